@@ -0,0 +1,121 @@
+// Package domain holds the errors returned by the services, transports translate them by Kind
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Kind classify a domain error
+type Kind uint8
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindForbidden
+	KindValidation
+	KindTimeout
+	KindUnavailable
+	KindUnauthorized
+)
+
+// server error numbers of the mysql errors which are not Internal
+const (
+	mysqlDuplicateEntry   = 1062
+	mysqlQueryInterrupted = 1317
+	mysqlRowIsReferenced  = 1451
+	mysqlQueryTimeout     = 3024
+)
+
+// Error is the error returned by the services, Message is safe to show to the client
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(resource string) *Error {
+	return &Error{Kind: KindNotFound, Message: resource + " not found"}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func Forbidden(resource string) *Error {
+	return &Error{Kind: KindForbidden, Message: "access to " + resource + " forbidden"}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+func Validation(message string) *Error {
+	return &Error{Kind: KindValidation, Message: message}
+}
+
+func Timeout(err error) *Error {
+	return &Error{Kind: KindTimeout, Message: "request timed out", Err: err}
+}
+
+func Unavailable(err error) *Error {
+	return &Error{Kind: KindUnavailable, Message: "service unavailable", Err: err}
+}
+
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Message: "internal error", Err: err}
+}
+
+// Wrap classify a repository error about resource: missing rows become NotFound, unique key
+// violations and deletes of rows other rows still reference Conflict, exceeded deadlines Timeout, cancelled queries and lost connections
+// Unavailable and anything else Internal. Domain errors are returned untouched.
+func Wrap(resource string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var de *Error
+	if errors.As(err, &de) {
+		return err
+	}
+	var me *mysql.MySQLError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &Error{Kind: KindNotFound, Message: resource + " not found", Err: err}
+	case errors.As(err, &me) && me.Number == mysqlDuplicateEntry:
+		return &Error{Kind: KindConflict, Message: resource + " already exists", Err: err}
+	case me != nil && me.Number == mysqlRowIsReferenced:
+		return &Error{Kind: KindConflict, Message: resource + " is still in use", Err: err}
+	case errors.Is(err, context.DeadlineExceeded), me != nil && me.Number == mysqlQueryTimeout:
+		return Timeout(err)
+	case errors.Is(err, context.Canceled), errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn),
+		me != nil && me.Number == mysqlQueryInterrupted:
+		return Unavailable(err)
+	default:
+		return Internal(err)
+	}
+}
+
+// KindOf return the Kind of err, KindInternal when err is not a domain error
+func KindOf(err error) Kind {
+	var de *Error
+	if errors.As(err, &de) {
+		return de.Kind
+	}
+	return KindInternal
+}
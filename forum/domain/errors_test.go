@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	t.Run("when error is nil", func(t *testing.T) {
+		assert.NoError(t, Wrap("article", nil))
+	})
+	t.Run("when no rows", func(t *testing.T) {
+		err := Wrap("article", fmt.Errorf("find: %w", sql.ErrNoRows))
+		assert.Equal(t, KindNotFound, KindOf(err))
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.Equal(t, "article not found", err.(*Error).Message)
+	})
+	t.Run("when duplicate entry", func(t *testing.T) {
+		err := Wrap("user", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		assert.Equal(t, KindConflict, KindOf(err))
+		assert.Equal(t, "user already exists", err.(*Error).Message)
+	})
+	t.Run("when the row is still referenced", func(t *testing.T) {
+		err := Wrap("user", &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+		assert.Equal(t, KindConflict, KindOf(err))
+		assert.Equal(t, "user is still in use", err.(*Error).Message)
+	})
+	t.Run("when deadline exceeded", func(t *testing.T) {
+		err := Wrap("article", fmt.Errorf("find: %w", context.DeadlineExceeded))
+		assert.Equal(t, KindTimeout, KindOf(err))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("when query timeout", func(t *testing.T) {
+		err := Wrap("article", &mysql.MySQLError{Number: 3024, Message: "Query execution was interrupted, maximum statement execution time exceeded"})
+		assert.Equal(t, KindTimeout, KindOf(err))
+	})
+	t.Run("when cancelled", func(t *testing.T) {
+		assert.Equal(t, KindUnavailable, KindOf(Wrap("article", fmt.Errorf("find: %w", context.Canceled))))
+		assert.Equal(t, KindUnavailable, KindOf(Wrap("article", driver.ErrBadConn)))
+		assert.Equal(t, KindUnavailable, KindOf(Wrap("article", &mysql.MySQLError{Number: 1317, Message: "Query execution was interrupted"})))
+	})
+	t.Run("when domain error", func(t *testing.T) {
+		err := Forbidden("comment")
+		assert.Same(t, err, Wrap("article", err))
+	})
+	t.Run("when other error", func(t *testing.T) {
+		err := Wrap("article", fmt.Errorf("connection refused"))
+		assert.Equal(t, KindInternal, KindOf(err))
+		assert.Equal(t, "internal error: connection refused", err.Error())
+	})
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, KindValidation, KindOf(fmt.Errorf("bind: %w", Validation("title is required"))))
+	assert.Equal(t, KindInternal, KindOf(fmt.Errorf("boom")))
+}
@@ -1,11 +1,15 @@
 package article
 
 import (
+	"net/http"
 	"schema/entity"
+	"time"
 
+	"forum/handler"
 	"forum/model"
 	"forum/service"
 
+	"github.com/labstack/echo/v4"
 	"github.com/volatiletech/null/v8"
 )
 
@@ -22,8 +26,115 @@ func NewArticleHandler(as service.IServiceArticle) *Handler {
 func populateSimpleArticle(s *model.SimpleArticle) *entity.Article {
 	var a entity.Article
 	a.Title = s.Title
-	a.Slug = s.Slug
 	a.Description = null.StringFrom(s.Description)
 	a.Body = null.StringFrom(s.Body)
 	return &a
 }
+
+// populateUpdateArticle only mark the fields sent by the client as valid, so the others keep their value
+func populateUpdateArticle(s *model.UpdateArticle) *entity.Article {
+	var a entity.Article
+	a.Title = s.Title
+	a.Description = null.NewString(s.Description, s.Description != "")
+	a.Body = null.NewString(s.Body, s.Body != "")
+	return &a
+}
+
+// bindTagParam read the tag path parameter, rejecting the names a tag cannot have
+func bindTagParam(c echo.Context) (string, error) {
+	var p struct {
+		Tag string `json:"tag" validate:"required,max=64"`
+	}
+	p.Tag = c.Param("tag")
+	if err := handler.Validate(c, &p); err != nil {
+		return "", err
+	}
+	return p.Tag, nil
+}
+
+// bindPage read the page of a listing from the offset, limit, after and before query parameters,
+// after and before hold the cursors of a previous response
+func bindPage(c echo.Context) (model.Page, error) {
+	var page model.Page
+	var err error
+	if page.Offset, page.Limit, err = handler.BindOffsetLimit(c); err != nil {
+		return page, err
+	}
+	if after := c.QueryParam("after"); after != "" {
+		if page.After, err = model.ParseCursor(after); err != nil {
+			return page, echo.NewHTTPError(http.StatusBadRequest, "invalid after cursor")
+		}
+	}
+	if before := c.QueryParam("before"); before != "" {
+		if page.Before, err = model.ParseCursor(before); err != nil {
+			return page, echo.NewHTTPError(http.StatusBadRequest, "invalid before cursor")
+		}
+	}
+	if page.After != nil && page.Before != nil {
+		return page, echo.NewHTTPError(http.StatusBadRequest, "after and before cannot be combined")
+	}
+	return page, nil
+}
+
+// bindArticleFilter read the criteria of an article listing from the tag, author, favorited, since,
+// until and sort query parameters, since and until being RFC 3339 times
+func bindArticleFilter(c echo.Context) (model.ArticleFilter, error) {
+	f := model.ArticleFilter{
+		Tag:       c.QueryParam("tag"),
+		Author:    c.QueryParam("author"),
+		Favorited: c.QueryParam("favorited"),
+		Sort:      model.SortNewest,
+	}
+	var err error
+	if since := c.QueryParam("since"); since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return f, echo.NewHTTPError(http.StatusBadRequest, "invalid since, expected an RFC 3339 time")
+		}
+	}
+	if until := c.QueryParam("until"); until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return f, echo.NewHTTPError(http.StatusBadRequest, "invalid until, expected an RFC 3339 time")
+		}
+	}
+	switch sort := c.QueryParam("sort"); sort {
+	case "":
+	case model.SortNewest, model.SortOldest:
+		f.Sort = sort
+	default:
+		return f, echo.NewHTTPError(http.StatusBadRequest, "invalid sort, expected newest or oldest")
+	}
+	return f, nil
+}
+
+// bindCommentView read the view of the comments from the view query parameter, flat by default
+func bindCommentView(c echo.Context) (string, error) {
+	switch view := c.QueryParam("view"); view {
+	case "":
+		return model.CommentViewFlat, nil
+	case model.CommentViewFlat, model.CommentViewTree:
+		return view, nil
+	default:
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid view, expected flat or tree")
+	}
+}
+
+// topLevelComments keep the comments which start a thread, the ones the comment listings are paged by
+func topLevelComments(comments []*entity.Comment) []*entity.Comment {
+	var roots []*entity.Comment
+	for _, cm := range comments {
+		if !cm.ParentID.Valid {
+			roots = append(roots, cm)
+		}
+	}
+	return roots
+}
+
+// articleCursor is the position of a in the article listings
+func articleCursor(a *entity.Article) model.Cursor {
+	return model.Cursor{CreatedAt: a.CreatedAt.Time, ID: a.ID}
+}
+
+// commentCursor is the position of cm in the comment listings
+func commentCursor(cm *entity.Comment) model.Cursor {
+	return model.Cursor{CreatedAt: cm.CreatedAt.Time, ID: cm.ID}
+}
@@ -65,3 +65,15 @@ var (
 		Tag: null.StringFrom("bar"),
 	}
 )
+
+func Test_populateUpdateArticle(t *testing.T) {
+	got := populateUpdateArticle(&model.UpdateArticle{Title: "foo", Body: "foo body"})
+	want := &entity.Article{
+		Title:       "foo",
+		Description: null.NewString("", false),
+		Body:        null.StringFrom("foo body"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("populateUpdateArticle() = %v, want %v", got, want)
+	}
+}
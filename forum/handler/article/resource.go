@@ -1,11 +1,12 @@
 package article
 
 import (
-	http_error "http/error"
 	"net/http"
+	"path"
 	"schema/entity"
 	"strconv"
 
+	"forum/domain"
 	"forum/handler"
 	"forum/model"
 	"forum/service/article"
@@ -24,22 +25,34 @@ import (
 // @Produce  json
 // @Param slug path string true "Slug of the article to get"
 // @Success 200 {object} singleArticleResponse
+// @Success 301 "The article was renamed, Location holds its current path"
 // @Failure 400 {object} utils.Error
+// @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Router /articles/{slug} [get]
 func (h *Handler) GetArticle(c echo.Context) error {
 	slug := c.Param("slug")
-	a, u, t, err := h.Service.FindArticle(slug)
+	a, err := h.Service.FindArticleBySlug(c.Request().Context(), slug)
+	if domain.KindOf(err) == domain.KindNotFound {
+		moved, rerr := h.Service.FindArticleBySlugRedirect(c.Request().Context(), slug)
+		if rerr == nil {
+			return c.Redirect(http.StatusMovedPermanently, path.Join(path.Dir(c.Request().URL.Path), moved.Slug))
+		}
+		if domain.KindOf(rerr) != domain.KindNotFound {
+			log.Error().Err(rerr).Msg("Failed to get article redirect")
+			return rerr
+		}
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get article")
-		return c.JSON(http.StatusNotFound, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusOK, article.SingleArticleResponseMapper(a, u, t))
+	return h.article(c, http.StatusOK, handler.UserIDFromToken(c), a)
 }
 
 // Articles godoc
 // @Summary Get recent articles globally
-// @Description Get most recent articles globally. Use query parameters to filter results. Auth is optional
+// @Description Get most recent articles globally. Use query parameters to filter results, the filters combine. Auth is optional
 // @ID get-articles
 // @Tags article
 // @Accept  json
@@ -47,33 +60,88 @@ func (h *Handler) GetArticle(c echo.Context) error {
 // @Param tag query string false "Filter by tag"
 // @Param author query string false "Filter by author (username)"
 // @Param favorited query string false "Filter by favorites of a user (username)"
+// @Param since query string false "Filter by creation time, at or after this RFC 3339 time"
+// @Param until query string false "Filter by creation time, before this RFC 3339 time"
+// @Param sort query string false "newest (default) or oldest first" Enums(newest, oldest)
 // @Param limit query integer false "Limit number of articles returned (default is 20)"
 // @Param offset query integer false "Offset/skip number of articles (default is 0)"
+// @Param after query string false "List the articles following this cursor, a nextCursor of a previous response"
+// @Param before query string false "List the articles preceding this cursor, a prevCursor of a previous response"
 // @Success 200 {object} articleListResponse
+// @Failure 400 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Router /articles [get]
 func (h *Handler) Articles(c echo.Context) error {
-	tag := c.QueryParam("tag")
-	author := c.QueryParam("author")
-
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	filter, err := bindArticleFilter(c)
 	if err != nil {
-		log.Error().Err(err).Msg("error parsing offset,set to 0")
-		offset = 0
+		return err
 	}
 
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	page, err := bindPage(c)
 	if err != nil {
-		log.Error().Err(err).Msg("error parsing limit,set to 20")
-		limit = 20
+		return err
 	}
 
-	articles, count, err := h.Service.FindArticles(tag, author, offset, limit)
+	articles, count, err := h.Service.FindArticles(c.Request().Context(), filter, page)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get articles")
-		return c.JSON(http.StatusNotFound, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusOK, article.SimpleArticleListMapper(articles, count))
+	return h.articlePage(c, articles, count, &page)
+}
+
+// SearchArticles godoc
+// @Summary Search articles
+// @Description Full-text search of the articles by title, description and body, the most relevant first. Auth is optional
+// @ID search-articles
+// @Tags article
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Words to search for"
+// @Param tag query string false "Filter by tag"
+// @Param author query string false "Filter by author (username)"
+// @Param favorited query string false "Filter by favorites of a user (username)"
+// @Param since query string false "Filter by creation time, at or after this RFC 3339 time"
+// @Param until query string false "Filter by creation time, before this RFC 3339 time"
+// @Param limit query integer false "Limit number of articles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of articles (default is 0)"
+// @Success 200 {object} articleSearchResponse
+// @Failure 400 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /articles/search [get]
+func (h *Handler) SearchArticles(c echo.Context) error {
+	filter, err := bindArticleFilter(c)
+	if err != nil {
+		return err
+	}
+	page, err := bindPage(c)
+	if err != nil {
+		return err
+	}
+	if page.After != nil || page.Before != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "search results are paged by offset")
+	}
+
+	hits, count, err := h.Service.SearchArticles(c.Request().Context(), c.QueryParam("q"), filter, page)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to search articles")
+		return err
+	}
+	articles := make([]*entity.Article, 0, len(hits))
+	for _, hit := range hits {
+		articles = append(articles, hit.Article)
+	}
+	l, err := h.Service.ArticleListResponse(c.Request().Context(), handler.UserIDFromToken(c), articles, count)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build article list response")
+		return err
+	}
+	r := model.ArticleSearchResponse{Articles: make([]*model.ArticleHit, 0, len(hits)), ArticlesCount: l.ArticlesCount}
+	for i, a := range l.Articles {
+		r.Articles = append(r.Articles, &model.ArticleHit{ArticleResponse: a, Snippet: hits[i].Snippet})
+	}
+	return c.JSON(http.StatusOK, r)
 }
 
 // Feed godoc
@@ -85,33 +153,40 @@ func (h *Handler) Articles(c echo.Context) error {
 // @Produce  json
 // @Param limit query integer false "Limit number of articles returned (default is 20)"
 // @Param offset query integer false "Offset/skip number of articles (default is 0)"
+// @Param after query string false "List the articles following this cursor, a nextCursor of a previous response"
+// @Param before query string false "List the articles preceding this cursor, a prevCursor of a previous response"
 // @Success 200 {object} articleListResponse
+// @Failure 400 {object} utils.Error
 // @Failure 401 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Security ApiKeyAuth
 // @Router /articles/feed [get]
-/* func (h *Handler) Feed(c echo.Context) error {
-	var (
-		articles []*entity.Article
-		count    int64
-	)
-
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
+func (h *Handler) Feed(c echo.Context) error {
+	page, err := bindPage(c)
 	if err != nil {
-		offset = 0
+		return err
 	}
 
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	articles, count, err := h.Service.FindFeed(c.Request().Context(), handler.UserIDFromToken(c), page)
 	if err != nil {
-		limit = 20
+		log.Error().Err(err).Msg("Failed to get feed")
+		return err
 	}
+	return h.articlePage(c, articles, count, &page)
+}
 
-	articles, count, err = h.article.ListFeed(handler.UserIDFromToken(c), offset, limit)
+// articlePage answer with the articles and, when they were read with page, the cursors of the pages around them
+func (h *Handler) articlePage(c echo.Context, articles []*entity.Article, count int64, page *model.Page) error {
+	r, err := h.Service.ArticleListResponse(c.Request().Context(), handler.UserIDFromToken(c), articles, count)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, nil)
+		log.Error().Err(err).Msg("Failed to build article list response")
+		return err
+	}
+	if n := len(articles); page != nil && n > 0 {
+		r.PrevCursor, r.NextCursor = page.Cursors(n, articleCursor(articles[0]), articleCursor(articles[n-1]))
 	}
-	return c.JSON(http.StatusOK, article.ArticleListResponseMapper(handler.UserIDFromToken(c), articles, count))
-} */
+	return c.JSON(http.StatusOK, r)
+}
 
 // CreateArticle godoc
 // @Summary Create an article
@@ -131,17 +206,20 @@ func (h *Handler) CreateArticle(c echo.Context) error {
 	var s model.SimpleArticle
 	if err := c.Bind(&s); err != nil {
 		log.Error().Err(err).Msg("error binding article")
-		return c.JSON(http.StatusBadRequest, http_error.NewError(err))
+		return err
+	}
+	if err := handler.Validate(c, &s); err != nil {
+		return err
 	}
 	a := populateSimpleArticle(&s)
 	x := handler.UserIDFromToken(c)
 	a.AuthorID = null.Uint64From(uint64(x))
 
-	if err := h.Service.CreateArticle(a); err != nil {
+	if err := h.Service.CreateArticle(c.Request().Context(), a, s.TagList); err != nil {
 		log.Error().Err(err).Msg("error inserting article")
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusCreated, handler.ResultOK())
+	return h.article(c, http.StatusCreated, x, a)
 }
 
 // UpdateArticle godoc
@@ -156,31 +234,34 @@ func (h *Handler) CreateArticle(c echo.Context) error {
 // @Success 200 {object} singleArticleResponse
 // @Failure 400 {object} utils.Error
 // @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
 // @Failure 422 {object} utils.Error
 // @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Security ApiKeyAuth
 // @Router /articles/{slug} [put]
 func (h *Handler) UpdateArticle(c echo.Context) error {
-	var s model.SimpleArticle
+	var s model.UpdateArticle
 	slug := c.Param("slug")
 	if err := c.Bind(&s); err != nil {
 		log.Error().Err(err).Msg("error binding article")
-		return c.JSON(http.StatusBadRequest, http_error.NotFound())
+		return err
 	}
-	a := populateSimpleArticle(&s)
-	x := handler.UserIDFromToken(c)
-	a.AuthorID = null.Uint64From(uint64(x))
-	if err := h.Service.UpdateArticle(slug, a); err != nil {
+	if err := handler.Validate(c, &s); err != nil {
+		return err
+	}
+	uid := handler.UserIDFromToken(c)
+	a, err := h.Service.UpdateArticle(c.Request().Context(), uid, slug, populateUpdateArticle(&s), s.TagList)
+	if err != nil {
 		log.Error().Err(err).Msg("error updating article")
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
+	return h.article(c, http.StatusOK, uid, a)
 }
 
 // DeleteArticle godoc
 // @Summary Delete an article
-// @Description Delete an article. Auth is required
+// @Description Move an article and its comments to the trash, its favorites and tags are kept until it is purged. Only its author or a moderator may. Auth is required
 // @ID delete-article
 // @Tags article
 // @Accept  json
@@ -188,16 +269,17 @@ func (h *Handler) UpdateArticle(c echo.Context) error {
 // @Param slug path string true "Slug of the article to delete"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
 // @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Security ApiKeyAuth
 // @Router /articles/{slug} [delete]
 func (h *Handler) DeleteArticle(c echo.Context) error {
 	slug := c.Param("slug")
-	err := h.Service.DeleteArticle(slug)
+	err := h.Service.DeleteArticle(c.Request().Context(), handler.UserIDFromToken(c), slug)
 	if err != nil {
 		log.Error().Err(err).Msg("error deleting article")
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
@@ -205,7 +287,7 @@ func (h *Handler) DeleteArticle(c echo.Context) error {
 
 // AddComment godoc
 // @Summary CreateUser a comment for an article
-// @Description CreateUser a comment for an article. Auth is required
+// @Description CreateUser a comment for an article, or a reply to one of its comments when parentId is set. Replies nest at most 5 levels deep. Auth is required
 // @ID add-comment
 // @Tags comment
 // @Accept  json
@@ -222,53 +304,71 @@ func (h *Handler) DeleteArticle(c echo.Context) error {
 // @Router /articles/{slug}/comments [post]
 func (h *Handler) AddComment(c echo.Context) error {
 	slug := c.Param("slug")
-	var cm entity.Comment
-	if err := c.Bind(&cm); err != nil {
-		return c.JSON(http.StatusBadRequest, http_error.NewError(err))
+	var req model.CommentRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := handler.Validate(c, &req); err != nil {
+		return err
+	}
+	cm := entity.Comment{
+		Body:     null.StringFrom(req.Body),
+		UserID:   null.Uint64From(uint64(handler.UserIDFromToken(c))),
+		ParentID: null.Uint64FromPtr(req.ParentID),
 	}
-	if err := h.Service.AddCommentToArticle(slug, &cm); err != nil {
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+	if err := h.Service.AddCommentToArticle(c.Request().Context(), slug, &cm); err != nil {
+		return err
 	}
-	return c.JSON(http.StatusCreated, map[string]interface{}{"result": "ok"})
+	return c.JSON(http.StatusCreated, article.SingleCommentResponseMapper(&cm))
 }
 
 // GetComments godoc
 // @Summary Get the comments for an article
-// @Description Get the comments for an article. Auth is optional
+// @Description Get the threads of comments for an article, oldest first. The page and commentsCount count the top-level comments, each comes with all its replies. Auth is optional
 // @ID get-comments
 // @Tags comment
 // @Accept  json
 // @Produce  json
 // @Param slug path string true "Slug of the article that you want to get comments for"
+// @Param view query string false "flat (default) lists every comment followed by its replies with its depth, tree nests the replies in their comment"
+// @Param limit query integer false "Limit number of threads returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of threads (default is 0)"
+// @Param after query string false "List the threads following this cursor, a nextCursor of a previous response"
+// @Param before query string false "List the threads preceding this cursor, a prevCursor of a previous response"
 // @Success 200 {object} commentListResponse
+// @Failure 400 {object} utils.Error
 // @Failure 422 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Router /articles/{slug}/comments [get]
 func (h *Handler) GetComments(c echo.Context) error {
 	slug := c.Param("slug")
 
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	page, err := bindPage(c)
 	if err != nil {
-		log.Error().Err(err).Msg("error parsing offset,set to 0")
-		offset = 0
+		return err
 	}
-
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	view, err := bindCommentView(c)
 	if err != nil {
-		log.Error().Err(err).Msg("error parsing limit,set to 20")
-		limit = 20
+		return err
 	}
-	cms, err := h.Service.FindCommentsBySlug(slug, offset, limit)
+	cms, n, err := h.Service.FindCommentsBySlug(c.Request().Context(), slug, page)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
 
-	return c.JSON(http.StatusOK, article.CommentListResponseMapper(cms))
+	r := article.CommentListResponseMapper(cms, n)
+	if view == model.CommentViewTree {
+		r = article.CommentTreeResponseMapper(cms, n)
+	}
+	if roots := topLevelComments(cms); len(roots) > 0 {
+		r.PrevCursor, r.NextCursor = page.Cursors(len(roots), commentCursor(roots[0]), commentCursor(roots[len(roots)-1]))
+	}
+	return c.JSON(http.StatusOK, r)
 }
 
 // DeleteComment godoc
 // @Summary Delete a comment for an article
-// @Description Delete a comment for an article. Auth is required
+// @Description Delete a comment for an article. A comment which has replies is kept in its thread as a "[deleted]" placeholder, the others go to the trash. Only its author or a moderator may. Auth is required
 // @ID delete-comments
 // @Tags comment
 // @Accept  json
@@ -278,6 +378,7 @@ func (h *Handler) GetComments(c echo.Context) error {
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} utils.Error
 // @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
 // @Failure 422 {object} utils.Error
 // @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
@@ -286,22 +387,201 @@ func (h *Handler) GetComments(c echo.Context) error {
 func (h *Handler) DeleteComment(c echo.Context) error {
 	x := c.Param("id")
 	slug := c.Param("slug")
-	id64, err := strconv.ParseUint(x, 10, 32)
+	id64, err := strconv.ParseUint(x, 10, 64)
 	if err != nil {
 		log.Error().Err(err).Msg("error parsing id")
-		return c.JSON(http.StatusBadRequest, http_error.NewError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
 	}
 
-	if err := h.Service.DeleteCommentFromArticle(slug, id64); err != nil {
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+	if err := h.Service.DeleteCommentFromArticle(c.Request().Context(), handler.UserIDFromToken(c), slug, id64); err != nil {
+		log.Error().Err(err).Msg("error deleting comment")
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
 }
 
+// UpdateComment godoc
+// @Summary Update a comment for an article
+// @Description Replace the body of a comment for an article, the comment is marked as edited and its former body kept in its history. Auth is required
+// @ID update-comment
+// @Tags comment
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Slug of the article of the comment"
+// @Param id path integer true "ID of the comment you want to update"
+// @Param comment body model.UpdateComment true "New body of the comment"
+// @Success 200 {object} singleCommentResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /articles/{slug}/comments/{id} [put]
+func (h *Handler) UpdateComment(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
+	}
+	var req model.UpdateComment
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := handler.Validate(c, &req); err != nil {
+		return err
+	}
+	cm, err := h.Service.UpdateCommentOfArticle(c.Request().Context(), handler.UserIDFromToken(c), c.Param("slug"), id, req.Body)
+	if err != nil {
+		log.Error().Err(err).Msg("error updating comment")
+		return err
+	}
+	return c.JSON(http.StatusOK, article.SingleCommentResponseMapper(cm))
+}
+
+// CommentHistory godoc
+// @Summary Get the history of a comment
+// @Description Get a comment for an article along with its former bodies, the oldest first. Moderators and admins only
+// @ID comment-history
+// @Tags comment
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Slug of the article of the comment"
+// @Param id path integer true "ID of the comment"
+// @Success 200 {object} model.CommentHistoryResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /articles/{slug}/comments/{id}/history [get]
+func (h *Handler) CommentHistory(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
+	}
+	cm, revisions, err := h.Service.FindCommentHistory(c.Request().Context(), handler.UserIDFromToken(c), c.Param("slug"), id)
+	if err != nil {
+		log.Error().Err(err).Msg("error getting comment history")
+		return err
+	}
+	return c.JSON(http.StatusOK, article.CommentHistoryResponseMapper(cm, revisions))
+}
+
+// TrashedArticles godoc
+// @Summary List the trashed articles
+// @Description List the articles of the current user in the trash, the last deleted first. Auth is required
+// @ID trashed-articles
+// @Tags trash
+// @Accept  json
+// @Produce  json
+// @Param limit query integer false "Limit number of articles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of articles (default is 0)"
+// @Success 200 {object} articleListResponse
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/articles [get]
+func (h *Handler) TrashedArticles(c echo.Context) error {
+	offset, limit, err := handler.BindOffsetLimit(c)
+	if err != nil {
+		return err
+	}
+	articles, count, err := h.Service.FindTrashedArticles(c.Request().Context(), handler.UserIDFromToken(c), offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get trashed articles")
+		return err
+	}
+	return h.articlePage(c, articles, count, nil)
+}
+
+// TrashedComments godoc
+// @Summary List the trashed comments
+// @Description List the comments of the current user in the trash, the last deleted first. Auth is required
+// @ID trashed-comments
+// @Tags trash
+// @Accept  json
+// @Produce  json
+// @Param limit query integer false "Limit number of comments returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of comments (default is 0)"
+// @Success 200 {object} commentListResponse
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/comments [get]
+func (h *Handler) TrashedComments(c echo.Context) error {
+	offset, limit, err := handler.BindOffsetLimit(c)
+	if err != nil {
+		return err
+	}
+	cms, n, err := h.Service.FindTrashedComments(c.Request().Context(), handler.UserIDFromToken(c), offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get trashed comments")
+		return err
+	}
+	return c.JSON(http.StatusOK, article.CommentListResponseMapper(cms, n))
+}
+
+// RestoreArticle godoc
+// @Summary Restore an article
+// @Description Take an article of the current user out of the trash, along with the comments deleted with it. Auth is required
+// @ID restore-article
+// @Tags trash
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Slug of the trashed article"
+// @Success 200 {object} singleArticleResponse
+// @Failure 401 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/articles/{slug}/restore [post]
+func (h *Handler) RestoreArticle(c echo.Context) error {
+	uid := handler.UserIDFromToken(c)
+	a, err := h.Service.RestoreArticle(c.Request().Context(), uid, c.Param("slug"))
+	if err != nil {
+		log.Error().Err(err).Msg("error restoring article")
+		return err
+	}
+	return h.article(c, http.StatusOK, uid, a)
+}
+
+// RestoreComment godoc
+// @Summary Restore a comment
+// @Description Take a comment of the current user out of the trash. Auth is required
+// @ID restore-comment
+// @Tags trash
+// @Accept  json
+// @Produce  json
+// @Param id path integer true "ID of the trashed comment"
+// @Success 200 {object} singleCommentResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/comments/{id}/restore [post]
+func (h *Handler) RestoreComment(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
+	}
+	cm, err := h.Service.RestoreComment(c.Request().Context(), handler.UserIDFromToken(c), id)
+	if err != nil {
+		log.Error().Err(err).Msg("error restoring comment")
+		return err
+	}
+	return c.JSON(http.StatusOK, article.SingleCommentResponseMapper(cm))
+}
+
 // Favorite godoc
 // @Summary Favorite an article
-// @Description Favorite an article. Auth is required
+// @Description Favorite an article, favoriting it again changes nothing. Auth is required
 // @ID favorite
 // @Tags favorite
 // @Accept  json
@@ -318,16 +598,16 @@ func (h *Handler) DeleteComment(c echo.Context) error {
 func (h *Handler) Favorite(c echo.Context) error {
 	slug := c.Param("slug")
 	x := handler.UserIDFromToken(c)
-	err := h.Service.AddFavoriteArticleBySlug(slug, x)
+	a, err := h.Service.AddFavoriteArticleBySlug(c.Request().Context(), slug, x)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
+	return h.article(c, http.StatusOK, x, a)
 }
 
 // Unfavorite godoc
 // @Summary Unfavorite an article
-// @Description Unfavorite an article. Auth is required
+// @Description Unfavorite an article, unfavoriting it again changes nothing. Auth is required
 // @ID unfavorite
 // @Tags favorite
 // @Accept  json
@@ -344,61 +624,210 @@ func (h *Handler) Favorite(c echo.Context) error {
 func (h *Handler) Unfavorite(c echo.Context) error {
 	slug := c.Param("slug")
 	x := handler.UserIDFromToken(c)
-	err := h.Service.RemoveFavoriteArticleBySlug(slug, x)
+	a, err := h.Service.RemoveFavoriteArticleBySlug(c.Request().Context(), slug, x)
 	if err != nil {
 		log.Logger.Error().Err(err).Msg("error removing favorite")
-		return c.JSON(http.StatusInternalServerError, http_error.NewError(err))
+		return err
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
+	return h.article(c, http.StatusOK, x, a)
+}
+
+// FavoriteArticles godoc
+// @Summary Get the favorite articles of a user
+// @Description Get the articles favorited by a user, newest first. Auth is optional
+// @ID favorite-articles
+// @Tags favorite
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile whose favorites you want"
+// @Param limit query integer false "Limit number of articles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of articles (default is 0)"
+// @Param after query string false "List the articles following this cursor, a nextCursor of a previous response"
+// @Param before query string false "List the articles preceding this cursor, a prevCursor of a previous response"
+// @Success 200 {object} articleListResponse
+// @Failure 400 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /profiles/{username}/favorites [get]
+func (h *Handler) FavoriteArticles(c echo.Context) error {
+	page, err := bindPage(c)
+	if err != nil {
+		return err
+	}
+	articles, count, err := h.Service.FindFavoriteArticles(c.Request().Context(), c.Param("username"), page)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get favorite articles")
+		return err
+	}
+	return h.articlePage(c, articles, count, &page)
 }
 
 // Tags godoc
 // @Summary Get tags
-// @Description Get tags
+// @Description Get tags, sort=popular lists the used tags with their usage counts, the most used first
 // @ID tags
 // @Tags tag
 // @Accept  json
 // @Produce  json
-// @Success 201 {object} tagListResponse
+// @Param sort query string false "popular to sort by usage"
+// @Success 200 {object} tagListResponse
 // @Failure 400 {object} utils.Error
-// @Failure 422 {object} utils.Error
-// @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
-// @Security ApiKeyAuth
 // @Router /tags [get]
 func (h *Handler) Tags(c echo.Context) error {
-	tags, err := h.Service.GetAllTags()
-	if err != nil {
-		return c.JSON(http.StatusNotFound, err)
+	switch c.QueryParam("sort") {
+	case "":
+		tags, err := h.Service.GetAllTags(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, article.TagListResponseMapper(tags))
+	case "popular":
+		tags, counts, err := h.Service.GetPopularTags(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, article.TagCountListResponseMapper(tags, counts))
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid sort")
 	}
-
-	return c.JSON(http.StatusOK, article.TagListResponseMapper(tags))
 }
 
 // AddTagToArticle godoc
 // @Summary tag an article
-// @Description tag an article. Auth is required
-// @ID tags
+// @Description tag an article, an unknown tag is created. Auth is required
+// @ID add-article-tag
 // @Tags tag
 // @Accept  json
 // @Produce  json
 // @Param slug path string true "Slug of the article to tag"
-// @Param tag  path string true "existing tag to apply"
-// @Param article body UpdateArticleRequest true "Article to update"
+// @Param tag  path string true "tag to apply"
 // @Success 200 {object} singleArticleResponse
-// @Failure 400 {object} utils.Error
 // @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
 // @Failure 422 {object} utils.Error
 // @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Security ApiKeyAuth
-// @Router /articles/{slug}/{tag} [put]
+// @Router /articles/{slug}/tags/{tag} [put]
 func (h *Handler) AddTagToArticle(c echo.Context) error {
-	slug := c.Param("slug")
-	tag := c.Param("tag")
-	if err := h.Service.AddTagToArticle(slug, []string{tag}); err != nil {
-		return c.JSON(http.StatusBadRequest, http_error.NewError(err))
+	tag, err := bindTagParam(c)
+	if err != nil {
+		return err
+	}
+	uid := handler.UserIDFromToken(c)
+	a, err := h.Service.AddTagToArticle(c.Request().Context(), uid, c.Param("slug"), []string{tag})
+	if err != nil {
+		log.Error().Err(err).Msg("error tagging article")
+		return err
+	}
+	return h.article(c, http.StatusOK, uid, a)
+}
+
+// RemoveTagFromArticle godoc
+// @Summary untag an article
+// @Description remove a tag from an article. Auth is required
+// @ID remove-article-tag
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Slug of the article to untag"
+// @Param tag  path string true "tag to remove"
+// @Success 200 {object} singleArticleResponse
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /articles/{slug}/tags/{tag} [delete]
+func (h *Handler) RemoveTagFromArticle(c echo.Context) error {
+	tag, err := bindTagParam(c)
+	if err != nil {
+		return err
+	}
+	uid := handler.UserIDFromToken(c)
+	a, err := h.Service.RemoveTagFromArticle(c.Request().Context(), uid, c.Param("slug"), tag)
+	if err != nil {
+		log.Error().Err(err).Msg("error untagging article")
+		return err
+	}
+	return h.article(c, http.StatusOK, uid, a)
+}
+
+// RenameTag godoc
+// @Summary rename a tag
+// @Description rename a tag on every article, merge it into the tag already named so. Admin only
+// @ID rename-tag
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Param tag  path string true "tag to rename"
+// @Param rename body model.RenameTag true "new name of the tag"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 409 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /tags/{tag} [put]
+func (h *Handler) RenameTag(c echo.Context) error {
+	var s model.RenameTag
+	if err := c.Bind(&s); err != nil {
+		log.Error().Err(err).Msg("error binding tag")
+		return err
+	}
+	if err := handler.Validate(c, &s); err != nil {
+		return err
+	}
+	if err := h.Service.RenameTag(c.Request().Context(), handler.UserIDFromToken(c), c.Param("tag"), s.Name); err != nil {
+		log.Error().Err(err).Msg("error renaming tag")
+		return err
 	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
+}
 
+// MergeTags godoc
+// @Summary merge a tag into another
+// @Description retag the articles of a tag with another one, then delete it. Admin only
+// @ID merge-tags
+// @Tags tag
+// @Accept  json
+// @Produce  json
+// @Param tag  path string true "tag to merge"
+// @Param merge body model.MergeTags true "tag absorbing the merged one"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /tags/{tag}/merge [post]
+func (h *Handler) MergeTags(c echo.Context) error {
+	var s model.MergeTags
+	if err := c.Bind(&s); err != nil {
+		log.Error().Err(err).Msg("error binding tag")
+		return err
+	}
+	if err := handler.Validate(c, &s); err != nil {
+		return err
+	}
+	if err := h.Service.MergeTags(c.Request().Context(), handler.UserIDFromToken(c), c.Param("tag"), s.Into); err != nil {
+		log.Error().Err(err).Msg("error merging tags")
+		return err
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{"result": "ok"})
 }
+
+// article answer with a as seen by the viewer uid, with the status code
+func (h *Handler) article(c echo.Context, code int, uid uint, a *entity.Article) error {
+	r, err := h.Service.ArticleResponse(c.Request().Context(), uid, a)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build article response")
+		return err
+	}
+	return c.JSON(code, r)
+}
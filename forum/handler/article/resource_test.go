@@ -10,42 +10,83 @@ import (
 	"schema/entity"
 	"strings"
 	"testing"
+	"time"
 
+	"forum/domain"
+	forumHandler "forum/handler"
 	"forum/mock/service"
 	"forum/model"
+	"forum/repository"
 	"http/utils"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	mock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/null/v8"
 )
 
 func TestArticleResource_GetArticle(t *testing.T) {
 	t.Run("When return Not-Found", func(t *testing.T) {
 		rec, c := echoFindArticleSetup()
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindArticle", mock.Anything).Return(nil, nil, nil, fmt.Errorf("error"))
+		serviceArticleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, domain.NotFound("article"))
+		serviceArticleMock.On("FindArticleBySlugRedirect", mock.Anything, "test-slug").Return(nil, domain.NotFound("article"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.GetArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 	})
+	t.Run("When slug was renamed return Moved-Permanently", func(t *testing.T) {
+		rec, c := echoFindArticleSetup()
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(nil, domain.NotFound("article"))
+		serviceArticleMock.On("FindArticleBySlugRedirect", mock.Anything, "test-slug").Return(&entity.Article{Slug: "new-slug"}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.GetArticle(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+		assert.Equal(t, "/api/v1/articles/new-slug", rec.Header().Get(echo.HeaderLocation))
+	})
+	t.Run("When redirect lookup return error", func(t *testing.T) {
+		rec, c := echoFindArticleSetup()
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(nil, domain.NotFound("article"))
+		serviceArticleMock.On("FindArticleBySlugRedirect", mock.Anything, "test-slug").Return(nil, domain.Internal(fmt.Errorf("error")))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.GetArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
 	t.Run("When return OK", func(t *testing.T) {
 		rec, c := echoFindArticleSetup()
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindArticle", mock.Anything).Return(articleFoo, userFoo, []*entity.Tag{tagFoo}, nil)
+		serviceArticleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(0), articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "foo-slug"}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.GetArticle(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
 	})
+	t.Run("When build response return error", func(t *testing.T) {
+		rec, c := echoFindArticleSetup()
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.GetArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
 }
 
 func echoFindArticleSetup() (*httptest.ResponseRecorder, echo.Context) {
 	e := echo.New()
 	e.Validator = utils.NewValidator()
-	req := httptest.NewRequest(echo.GET, "/api/v1/articles", nil)
+	req := httptest.NewRequest(echo.GET, "/api/v1/articles/test-slug", nil)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -71,7 +112,8 @@ func TestArticleResource_GetArticles(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{articleFoo}, int64(1)).Return(&model.ArticleListResponse{ArticlesCount: 1}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Articles(c)
 		require.NoError(t, err)
@@ -92,12 +134,29 @@ func TestArticleResource_GetArticles(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{articleFoo}, int64(1)).Return(&model.ArticleListResponse{ArticlesCount: 1}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Articles(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
 	})
+	t.Run("when build list response return error", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticles", mock.Anything, model.ArticleFilter{Sort: model.SortNewest}, model.Page{Limit: 20}).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Articles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
 	t.Run("when find articles return error", func(t *testing.T) {
 		// Setup
 		e := echo.New()
@@ -113,11 +172,236 @@ func TestArticleResource_GetArticles(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("error"))
+		serviceArticleMock.On("FindArticles", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), domain.NotFound("user"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Articles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestArticleResource_ArticlesFilter(t *testing.T) {
+	t.Run("when every filter is set", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		q := make(url.Values)
+		q.Set("tag", "go")
+		q.Set("author", "foo")
+		q.Set("favorited", "bar")
+		q.Set("since", "2023-01-01T00:00:00Z")
+		q.Set("until", "2023-02-01T00:00:00+01:00")
+		q.Set("sort", "oldest")
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles?"+q.Encode(), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticles", mock.Anything, mock.MatchedBy(func(f model.ArticleFilter) bool {
+			return f.Tag == "go" && f.Author == "foo" && f.Favorited == "bar" && f.Sort == model.SortOldest &&
+				f.Since.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+				f.Until.Equal(time.Date(2023, 1, 31, 23, 0, 0, 0, time.UTC))
+		}), model.Page{Limit: 20}).Return([]*entity.Article{}, int64(0), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{}, int64(0)).Return(&model.ArticleListResponse{}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Articles(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	for _, query := range []string{"since=yesterday", "until=2023-01-01", "sort=popular"} {
+		t.Run("when "+query, func(t *testing.T) {
+			// Setup
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/api/v1/articles?"+query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			handler := NewArticleHandler(service.NewIServiceArticle(t))
+			err := handler.Articles(c)
+			require.Error(t, err)
+			forumHandler.HTTPErrorHandler(err, c)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestArticleResource_ArticlesCursor(t *testing.T) {
+	t.Run("when the page follows a cursor", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		after := model.Cursor{CreatedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), ID: 9}
+		q := make(url.Values)
+		q.Set("after", after.String())
+		q.Set("limit", "1")
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles?"+q.Encode(), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		listed := &entity.Article{ID: 8, CreatedAt: null.TimeFrom(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))}
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticles", mock.Anything, model.ArticleFilter{Sort: model.SortNewest}, mock.MatchedBy(func(p model.Page) bool {
+			return p.Limit == 1 && p.Before == nil && p.After != nil && p.After.ID == 9 && p.After.CreatedAt.Equal(after.CreatedAt)
+		})).Return([]*entity.Article{listed}, int64(3), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{listed}, int64(3)).Return(&model.ArticleListResponse{ArticlesCount: 3}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Articles(c)
 		require.NoError(t, err)
-		assert.Error(t, echo.NewHTTPError(http.StatusNotFound, "error"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var r model.ArticleListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		cursor := model.Cursor{CreatedAt: listed.CreatedAt.Time, ID: 8}.String()
+		assert.Equal(t, cursor, r.NextCursor)
+		assert.Equal(t, cursor, r.PrevCursor)
+	})
+	t.Run("when the articles favorited by a user are listed", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles?favorited=fan", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindArticles", mock.Anything, model.ArticleFilter{Favorited: "fan", Sort: model.SortNewest}, model.Page{Limit: 20}).Return([]*entity.Article{}, int64(0), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{}, int64(0)).Return(&model.ArticleListResponse{}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Articles(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "Cursor")
+	})
+	t.Run("when the cursor is invalid", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles?after=garbage!", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := handler.Articles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("when after and before are combined", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		cursor := model.Cursor{CreatedAt: time.Now(), ID: 1}.String()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles?after="+cursor+"&before="+cursor, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := handler.Articles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestArticleResource_SearchArticles(t *testing.T) {
+	t.Run("when the search return OK", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/search?q=foo&tag=go&limit=5", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		hits := []*repository.SearchHit{{Article: articleFoo, Snippet: "<em>foo</em> body"}}
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("SearchArticles", mock.Anything, "foo", model.ArticleFilter{Tag: "go", Sort: model.SortNewest}, model.Page{Limit: 5}).
+			Return(hits, int64(7), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(0), []*entity.Article{articleFoo}, int64(7)).
+			Return(&model.ArticleListResponse{Articles: []*model.ArticleResponse{{Slug: "foo"}}, ArticlesCount: 7}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.SearchArticles(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var r model.ArticleSearchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		assert.Equal(t, int64(7), r.ArticlesCount)
+		require.Len(t, r.Articles, 1)
+		assert.Equal(t, "foo", r.Articles[0].Slug)
+		assert.Equal(t, "<em>foo</em> body", r.Articles[0].Snippet)
+	})
+	t.Run("when the query is missing", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/search", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("SearchArticles", mock.Anything, "", mock.Anything, mock.Anything).
+			Return(nil, int64(0), domain.Validation("search query is required"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.SearchArticles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+	t.Run("when a cursor is given", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		cursor := model.Cursor{CreatedAt: time.Now(), ID: 1}.String()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/search?q=foo&after="+cursor, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := handler.SearchArticles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestArticleResource_Feed(t *testing.T) {
+	t.Run("When return OK", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		q := make(url.Values)
+		q.Set("offset", "0")
+		q.Set("limit", "10")
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/feed?"+q.Encode(), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(1))
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindFeed", mock.Anything, uint(1), model.Page{Limit: 10}).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(1), []*entity.Article{articleFoo}, int64(1)).Return(&model.ArticleListResponse{ArticlesCount: 1}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Feed(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("When offset/limit is nil, use default value", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/feed", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(1))
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindFeed", mock.Anything, uint(1), model.Page{Limit: 20}).Return([]*entity.Article{}, int64(0), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(1), []*entity.Article{}, int64(0)).Return(&model.ArticleListResponse{}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Feed(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("When find feed return error", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/feed", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(1))
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindFeed", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("error"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Feed(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
 
@@ -126,9 +410,9 @@ func TestArticleResource_CreateArticle(t *testing.T) {
 		// When
 		simple := &model.SimpleArticle{
 			Title:       "test",
-			Slug:        "test",
 			Description: "test",
 			Body:        "test",
+			TagList:     []string{"go"},
 		}
 		data, err := json.Marshal(simple)
 		reader := bytes.NewReader(data)
@@ -141,11 +425,13 @@ func TestArticleResource_CreateArticle(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("CreateArticle", mock.Anything).Return(nil)
+		serviceArticleMock.On("CreateArticle", mock.Anything, mock.Anything, []string{"go"}).Return(nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(0), mock.Anything).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "test"}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err = handler.CreateArticle(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"slug":"test"`)
 	})
 	t.Run("when bind error", func(t *testing.T) {
 		// Setup
@@ -158,14 +444,14 @@ func TestArticleResource_CreateArticle(t *testing.T) {
 		serviceArticleMock := service.NewIServiceArticle(t)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.CreateArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 	t.Run("when create article return error", func(t *testing.T) {
 		// When
 		simple := &model.SimpleArticle{
 			Title:       "test",
-			Slug:        "test",
 			Description: "test",
 			Body:        "test",
 		}
@@ -180,12 +466,29 @@ func TestArticleResource_CreateArticle(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("CreateArticle", mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("CreateArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err = handler.CreateArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
+	t.Run("when article is invalid", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.POST, "/api/v1/", strings.NewReader(`{"title":"foo","tagList":[""]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.CreateArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"errors":{"description":["can't be blank"],"body":["can't be blank"],"tagList[0]":["can't be blank"]}}`, rec.Body.String())
+	})
 }
 
 func TestArticleResource_UpdateArticle(t *testing.T) {
@@ -193,7 +496,6 @@ func TestArticleResource_UpdateArticle(t *testing.T) {
 		// When
 		simple := &model.SimpleArticle{
 			Title:       "test",
-			Slug:        "test",
 			Description: "test",
 			Body:        "test",
 		}
@@ -209,11 +511,13 @@ func TestArticleResource_UpdateArticle(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("UpdateArticle", mock.Anything, mock.Anything).Return(nil)
+		serviceArticleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(0), articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "foo-slug"}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err = handler.UpdateArticle(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"slug":"foo-slug"`)
 	})
 	t.Run("when bind error", func(t *testing.T) {
 		// Setup
@@ -226,14 +530,14 @@ func TestArticleResource_UpdateArticle(t *testing.T) {
 		serviceArticleMock := service.NewIServiceArticle(t)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.UpdateArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 	t.Run("when update return error", func(t *testing.T) {
 		// When
 		simple := &model.SimpleArticle{
 			Title:       "test",
-			Slug:        "test",
 			Description: "test",
 			Body:        "test",
 		}
@@ -249,10 +553,11 @@ func TestArticleResource_UpdateArticle(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("UpdateArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err = handler.UpdateArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
@@ -265,7 +570,7 @@ func TestArticleResource_DeleteArticle(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("DeleteArticle", mock.Anything).Return(nil)
+		serviceArticleMock.On("DeleteArticle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.DeleteArticle(c)
 		require.NoError(t, err)
@@ -278,39 +583,101 @@ func TestArticleResource_DeleteArticle(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("DeleteArticle", mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("DeleteArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.DeleteArticle(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
+	t.Run("when caller is not the author", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(2))
+		c.SetParamNames("slug")
+		c.SetParamValues("test-slug")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("DeleteArticle", mock.Anything, uint(2), "test-slug").Return(domain.Forbidden("article"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.DeleteArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.JSONEq(t, `{"errors":{"body":["access to article forbidden"]}}`, rec.Body.String())
+	})
+	t.Run("when article is not found", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("DeleteArticle", mock.Anything, mock.Anything, mock.Anything).Return(domain.NotFound("article"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.DeleteArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
 }
 
 func TestArticleResource_AddComment(t *testing.T) {
 	t.Run("when add comment return OK", func(t *testing.T) {
 		// Setup
 		e := echo.New()
-		req := httptest.NewRequest(echo.POST, "/api/v1/", nil)
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.POST, "/api/v1/", strings.NewReader(`{"body":"foo comment"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddCommentToArticle", mock.Anything, mock.Anything).Return(nil)
+		c.Set("user", uint(1))
+		serviceArticleMock.On("AddCommentToArticle", mock.Anything, "", &entity.Comment{Body: null.StringFrom("foo comment"), UserID: null.Uint64From(1)}).
+			Run(func(args mock.Arguments) { args.Get(2).(*entity.Comment).ID = 5 }).Return(nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.AddComment(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusCreated, rec.Code)
+		var r model.SingleCommentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		assert.Equal(t, uint(5), r.Comment.ID)
+		assert.Equal(t, "foo comment", r.Comment.Body)
 	})
-	t.Run("when add comment return error", func(t *testing.T) {
+	t.Run("when add a reply return OK", func(t *testing.T) {
 		// Setup
 		e := echo.New()
-		req := httptest.NewRequest(echo.POST, "/api/v1/", nil)
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.POST, "/api/v1/", strings.NewReader(`{"body":"foo reply","parentId":3}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddCommentToArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		c.Set("user", uint(1))
+		serviceArticleMock.On("AddCommentToArticle", mock.Anything, "", &entity.Comment{
+			Body: null.StringFrom("foo reply"), UserID: null.Uint64From(1), ParentID: null.Uint64From(3)}).Return(nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.AddComment(c)
 		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"parentId":3`)
+	})
+	t.Run("when add comment return error", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.POST, "/api/v1/", strings.NewReader(`{"body":"foo comment"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("AddCommentToArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.AddComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 	t.Run("when bind error", func(t *testing.T) {
@@ -324,9 +691,26 @@ func TestArticleResource_AddComment(t *testing.T) {
 		serviceArticleMock := service.NewIServiceArticle(t)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.AddComment(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
+	t.Run("when comment body is blank", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.POST, "/api/v1/", strings.NewReader(`{"body":""}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.AddComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"errors":{"body":["can't be blank"]}}`, rec.Body.String())
+	})
 }
 
 func TestArticleResource_GetComments(t *testing.T) {
@@ -338,11 +722,49 @@ func TestArticleResource_GetComments(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindCommentsBySlug", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Comment{commentFoo}, nil)
+		serviceArticleMock.On("FindCommentsBySlug", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Comment{commentFoo}, int64(8), nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.GetComments(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"commentsCount":8`)
+	})
+	t.Run("when get comments as a tree", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/?view=tree&limit=1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		root := &entity.Comment{ID: 1, Body: null.StringFrom("root")}
+		reply := &entity.Comment{ID: 2, Body: null.StringFrom("reply"), ParentID: null.Uint64From(1), Depth: 1}
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindCommentsBySlug", mock.Anything, mock.Anything, model.Page{Limit: 1}).
+			Return([]*entity.Comment{root, reply}, int64(2), nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.GetComments(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		var r model.CommentListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		require.Len(t, r.Comments, 1)
+		require.Len(t, r.Comments[0].Replies, 1)
+		assert.Equal(t, "reply", r.Comments[0].Replies[0].Body)
+		assert.Equal(t, commentCursor(root).String(), r.NextCursor)
+	})
+	t.Run("when the view is unknown", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/?view=list", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.GetComments(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 	t.Run("when FindCommentsBySlug return error", func(t *testing.T) {
 		// Setup
@@ -352,10 +774,11 @@ func TestArticleResource_GetComments(t *testing.T) {
 		c := e.NewContext(req, rec)
 
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("FindCommentsBySlug", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
+		serviceArticleMock.On("FindCommentsBySlug", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.GetComments(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
@@ -371,7 +794,24 @@ func TestArticleResource_DeleteComent(t *testing.T) {
 		c.SetParamNames("slug", "id")
 		c.SetParamValues("test-slug", "1")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, mock.Anything).Return(nil)
+		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.DeleteComment(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when comments id is past 32 bits", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(1))
+		c.SetPath("/articles/:slug/comments/:id")
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "4294967296")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, uint(1), "test-slug", uint64(4294967296)).Return(nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.DeleteComment(c)
 		require.NoError(t, err)
@@ -389,7 +829,8 @@ func TestArticleResource_DeleteComent(t *testing.T) {
 		serviceArticleMock := service.NewIServiceArticle(t)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.DeleteComment(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 	t.Run("when delete comment from article return error", func(t *testing.T) {
@@ -402,12 +843,31 @@ func TestArticleResource_DeleteComent(t *testing.T) {
 		c.SetParamNames("slug", "id")
 		c.SetParamValues("test-slug", "1")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.DeleteComment(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
+	t.Run("when caller is not the comment author", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", uint(2))
+		c.SetPath("/articles/:slug/comments/:id")
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "1")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("DeleteCommentFromArticle", mock.Anything, uint(2), "test-slug", uint64(1)).Return(domain.Forbidden("comment"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.DeleteComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
 }
 
 func TestArticleResource_Favorite(t *testing.T) {
@@ -421,11 +881,13 @@ func TestArticleResource_Favorite(t *testing.T) {
 		c.SetParamNames("slug")
 		c.SetParamValues("test-slug")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddFavoriteArticleBySlug", mock.Anything, mock.Anything).Return(nil)
+		serviceArticleMock.On("AddFavoriteArticleBySlug", mock.Anything, "test-slug", mock.Anything).Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, mock.Anything, articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "foo", Favorited: true}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Favorite(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"slug":"foo"`)
 	})
 	t.Run("when favorite article return error", func(t *testing.T) {
 		// Setup
@@ -437,10 +899,11 @@ func TestArticleResource_Favorite(t *testing.T) {
 		c.SetParamNames("slug")
 		c.SetParamValues("test-slug")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddFavoriteArticleBySlug", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("AddFavoriteArticleBySlug", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Favorite(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
@@ -456,11 +919,13 @@ func TestArticleResource_UnFavorite(t *testing.T) {
 		c.SetParamNames("slug")
 		c.SetParamValues("test-slug")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("RemoveFavoriteArticleBySlug", mock.Anything, mock.Anything).Return(nil)
+		serviceArticleMock.On("RemoveFavoriteArticleBySlug", mock.Anything, "test-slug", mock.Anything).Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, mock.Anything, articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "foo", Favorited: false}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Unfavorite(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"slug":"foo"`)
 	})
 	t.Run("when unFavorite article return error", func(t *testing.T) {
 		// Setup
@@ -472,14 +937,52 @@ func TestArticleResource_UnFavorite(t *testing.T) {
 		c.SetParamNames("slug")
 		c.SetParamValues("test-slug")
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("RemoveFavoriteArticleBySlug", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("RemoveFavoriteArticleBySlug", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Unfavorite(c)
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
 
+func TestArticleResource_FavoriteArticles(t *testing.T) {
+	t.Run("when favorite articles return ok", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/?limit=10", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/profiles/:username/favorites")
+		c.SetParamNames("username")
+		c.SetParamValues("bar")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindFavoriteArticles", mock.Anything, "bar", model.Page{Limit: 10}).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, mock.Anything, []*entity.Article{articleFoo}, int64(1)).Return(&model.ArticleListResponse{ArticlesCount: 1}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.FavoriteArticles(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when the user is unknown", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/profiles/:username/favorites")
+		c.SetParamNames("username")
+		c.SetParamValues("nobody")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindFavoriteArticles", mock.Anything, "nobody", model.Page{Limit: 20}).Return(nil, int64(0), domain.NotFound("user"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.FavoriteArticles(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
 func TestArticleResource_Tags(t *testing.T) {
 	t.Run("when get all tags return ok", func(t *testing.T) {
 		// Setup
@@ -488,7 +991,7 @@ func TestArticleResource_Tags(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("GetAllTags").Return([]*entity.Tag{tagBar, tagFoo}, nil)
+		serviceArticleMock.On("GetAllTags", mock.Anything, mock.Anything).Return([]*entity.Tag{tagBar, tagFoo}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Tags(c)
 		require.NoError(t, err)
@@ -501,45 +1004,369 @@ func TestArticleResource_Tags(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("GetAllTags").Return(nil, fmt.Errorf("error"))
+		serviceArticleMock.On("GetAllTags", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("error"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Tags(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestArticleResource_PopularTags(t *testing.T) {
+	t.Run("when get popular tags return ok", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/tags?sort=popular", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		tags := []*entity.Tag{{ID: 2, Tag: null.StringFrom("bar")}, {ID: 1, Tag: null.StringFrom("foo")}}
+		serviceArticleMock.On("GetPopularTags", mock.Anything, mock.Anything).Return(tags, map[uint64]int{1: 1, 2: 3}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
 		err := handler.Tags(c)
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"tags":[{"tag":"bar","count":3},{"tag":"foo","count":1}]}`, rec.Body.String())
 	})
+	t.Run("when sort is unknown", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/tags?sort=recent", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.Tags(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func echoArticleTagSetup(method, tag string) (*httptest.ResponseRecorder, echo.Context) {
+	e := echo.New()
+	e.Validator = utils.NewValidator()
+	req := httptest.NewRequest(method, "/api/v1/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/articles/:slug/tags/:tag")
+	c.SetParamNames("slug", "tag")
+	c.SetParamValues("test-slug", tag)
+	c.Set("user", uint(1))
+	return rec, c
 }
 
 func TestArticleResource_AddTagToArticle(t *testing.T) {
 	t.Run("when add tag to article return ok", func(t *testing.T) {
+		rec, c := echoArticleTagSetup(echo.PUT, "new-tag")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("AddTagToArticle", mock.Anything, uint(1), "test-slug", []string{"new-tag"}).Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(1), articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{TagList: []string{"new-tag"}}}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.AddTagToArticle(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"tagList":["new-tag"]`)
+	})
+	t.Run("when add tag to article return error", func(t *testing.T) {
+		rec, c := echoArticleTagSetup(echo.PUT, "new-tag")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("AddTagToArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, domain.Forbidden("article"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.AddTagToArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+	t.Run("when tag is too long", func(t *testing.T) {
+		rec, c := echoArticleTagSetup(echo.PUT, strings.Repeat("a", 65))
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.AddTagToArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
+
+func TestArticleResource_RemoveTagFromArticle(t *testing.T) {
+	t.Run("when remove tag from article return ok", func(t *testing.T) {
+		rec, c := echoArticleTagSetup(echo.DELETE, "old-tag")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RemoveTagFromArticle", mock.Anything, uint(1), "test-slug", "old-tag").Return(articleFoo, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(1), articleFoo).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{}}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RemoveTagFromArticle(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when remove tag from article return error", func(t *testing.T) {
+		rec, c := echoArticleTagSetup(echo.DELETE, "old-tag")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RemoveTagFromArticle", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, domain.NotFound("tag"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RemoveTagFromArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func echoTagAdminSetup(method, body string) (*httptest.ResponseRecorder, echo.Context) {
+	e := echo.New()
+	e.Validator = utils.NewValidator()
+	req := httptest.NewRequest(method, "/api/v1/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/tags/:tag")
+	c.SetParamNames("tag")
+	c.SetParamValues("golang")
+	c.Set("user", uint(1))
+	return rec, c
+}
+
+func TestArticleResource_RenameTag(t *testing.T) {
+	t.Run("when rename tag return ok", func(t *testing.T) {
+		rec, c := echoTagAdminSetup(echo.PUT, `{"name":"go"}`)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RenameTag", mock.Anything, uint(1), "golang", "go").Return(nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RenameTag(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when new name is taken", func(t *testing.T) {
+		rec, c := echoTagAdminSetup(echo.PUT, `{"name":"go"}`)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RenameTag", mock.Anything, uint(1), "golang", "go").Return(domain.Conflict("tag already exists"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RenameTag(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("when new name is blank", func(t *testing.T) {
+		rec, c := echoTagAdminSetup(echo.PUT, `{"name":""}`)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RenameTag(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
+
+func TestArticleResource_MergeTags(t *testing.T) {
+	t.Run("when merge tags return ok", func(t *testing.T) {
+		rec, c := echoTagAdminSetup(echo.POST, `{"into":"go"}`)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("MergeTags", mock.Anything, uint(1), "golang", "go").Return(nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.MergeTags(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when merge tags return error", func(t *testing.T) {
+		rec, c := echoTagAdminSetup(echo.POST, `{"into":"go"}`)
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("MergeTags", mock.Anything, uint(1), "golang", "go").Return(domain.NotFound("tag"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.MergeTags(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestArticleResource_TrashedArticles(t *testing.T) {
+	t.Run("when the trashed articles are listed", func(t *testing.T) {
 		// Setup
 		e := echo.New()
-		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		req := httptest.NewRequest(echo.GET, "/api/v1/trash/articles?limit=5", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
-		c.SetPath("/articles/:slug/:tag")
-		c.SetParamNames("slug", "tag")
-		c.SetParamValues("test-slug", "new-tag")
+		c.Set("user", uint(1))
+		articles := []*entity.Article{{ID: 1, Slug: "foo-slug"}}
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddTagToArticle", mock.Anything, mock.Anything).Return(nil)
+		serviceArticleMock.On("FindTrashedArticles", mock.Anything, uint(1), 0, 5).Return(articles, int64(1), nil)
+		serviceArticleMock.On("ArticleListResponse", mock.Anything, uint(1), articles, int64(1)).Return(&model.ArticleListResponse{ArticlesCount: 1}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
-		err := handler.AddTagToArticle(c)
+		err := handler.TrashedArticles(c)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"articlesCount":1`)
 	})
-	t.Run("when add tag to article return error", func(t *testing.T) {
+}
+
+func TestArticleResource_RestoreArticle(t *testing.T) {
+	t.Run("when the article is restored", func(t *testing.T) {
 		// Setup
 		e := echo.New()
-		req := httptest.NewRequest(echo.DELETE, "/api/v1/", nil)
+		req := httptest.NewRequest(echo.POST, "/api/v1/trash/articles/foo-slug/restore", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
-		c.SetPath("/articles/:slug/:tag")
-		c.SetParamNames("slug", "tag")
-		c.SetParamValues("test-slug", "new-tag")
+		c.SetParamNames("slug")
+		c.SetParamValues("foo-slug")
+		c.Set("user", uint(1))
+		restored := &entity.Article{ID: 1, Slug: "foo-slug"}
 		serviceArticleMock := service.NewIServiceArticle(t)
-		serviceArticleMock.On("AddTagToArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("error"))
+		serviceArticleMock.On("RestoreArticle", mock.Anything, uint(1), "foo-slug").Return(restored, nil)
+		serviceArticleMock.On("ArticleResponse", mock.Anything, uint(1), restored).Return(&model.SingleArticleResponse{Article: &model.ArticleResponse{Slug: "foo-slug"}}, nil)
 		handler := NewArticleHandler(serviceArticleMock)
-		err := handler.AddTagToArticle(c)
+		err := handler.RestoreArticle(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("when the article is not in the trash", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/api/v1/trash/articles/foo-slug/restore", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug")
+		c.SetParamValues("foo-slug")
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RestoreArticle", mock.Anything, mock.Anything, "foo-slug").Return(nil, domain.NotFound("article"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RestoreArticle(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestArticleResource_UpdateComment(t *testing.T) {
+	t.Run("when the comment is updated", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.PUT, "/api/v1/articles/test-slug/comments/2", strings.NewReader(`{"body":"new body"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "2")
+		c.Set("user", uint(1))
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("UpdateCommentOfArticle", mock.Anything, uint(1), "test-slug", uint64(2), "new body").
+			Return(&entity.Comment{ID: 2, Body: null.StringFrom("new body"), EditedAt: null.TimeFrom(time.Now())}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.UpdateComment(c)
 		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"edited":true`)
+	})
+	t.Run("when the body is empty", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.PUT, "/api/v1/articles/test-slug/comments/2", strings.NewReader(`{"body":""}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "2")
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := handler.UpdateComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+	t.Run("when the caller is not the author", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		e.Validator = utils.NewValidator()
+		req := httptest.NewRequest(echo.PUT, "/api/v1/articles/test-slug/comments/2", strings.NewReader(`{"body":"new body"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "2")
+		c.Set("user", uint(1))
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("UpdateCommentOfArticle", mock.Anything, uint(1), "test-slug", uint64(2), "new body").
+			Return(nil, domain.Forbidden("comment"))
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.UpdateComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestArticleResource_CommentHistory(t *testing.T) {
+	t.Run("when the history is listed", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/test-slug/comments/2/history", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "2")
+		c.Set("user", uint(1))
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("FindCommentHistory", mock.Anything, uint(1), "test-slug", uint64(2)).
+			Return(&entity.Comment{ID: 2, Body: null.StringFrom("second")},
+				[]*entity.CommentRevision{{ID: 1, CommentID: 2, Body: null.StringFrom("first")}}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.CommentHistory(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var r model.CommentHistoryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		assert.Equal(t, "second", r.Comment.Body)
+		require.Len(t, r.Revisions, 1)
+		assert.Equal(t, "first", r.Revisions[0].Body)
+	})
+	t.Run("when the caller is not a moderator", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/api/v1/articles/test-slug/comments/2/history", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("slug", "id")
+		c.SetParamValues("test-slug", "2")
+		c.Set("user", uint(2))
+		c.Set("role", entity.UsersRoleUser)
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := utils.RequireRole(entity.UsersRoleModerator, entity.UsersRoleAdmin)(handler.CommentHistory)(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestArticleResource_RestoreComment(t *testing.T) {
+	t.Run("when the comment is restored", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/api/v1/trash/comments/2/restore", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+		c.Set("user", uint(1))
+		serviceArticleMock := service.NewIServiceArticle(t)
+		serviceArticleMock.On("RestoreComment", mock.Anything, uint(1), uint64(2)).Return(&entity.Comment{ID: 2}, nil)
+		handler := NewArticleHandler(serviceArticleMock)
+		err := handler.RestoreComment(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"comment":{"id":2`)
+	})
+	t.Run("when the comment id is invalid", func(t *testing.T) {
+		// Setup
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/api/v1/trash/comments/foo/restore", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("foo")
+		handler := NewArticleHandler(service.NewIServiceArticle(t))
+		err := handler.RestoreComment(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 }
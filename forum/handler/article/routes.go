@@ -2,6 +2,9 @@ package article
 
 import (
 	"http/utils"
+	"net/http"
+	"schema/entity"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -10,26 +13,60 @@ func (h *Handler) Register(v *echo.Group) {
 	articles := v.Group("/articles", utils.JWTWithConfig(
 		utils.JWTConfig{
 			Skipper: func(c echo.Context) bool {
-				if c.Request().Method == "GET" && c.Path() != "/api/articles/feed" {
+				if c.Request().Method == "GET" && !strings.HasSuffix(c.Path(), "/articles/feed") &&
+					!strings.HasSuffix(c.Path(), "/history") {
 					return true
 				}
 				return false
 			},
-			SigningKey: utils.JWTSecret,
+			Keys: utils.JWTKeys,
 		},
 	))
 	articles.POST("", h.CreateArticle)
-	// articles.GET("/feed", h.Feed)
+	articles.GET("/feed", h.Feed)
+	articles.GET("/search", h.SearchArticles)
 	articles.PUT("/:slug", h.UpdateArticle)
 	articles.DELETE("/:slug", h.DeleteArticle)
 	articles.POST("/:slug/comments", h.AddComment)
+	articles.PUT("/:slug/comments/:id", h.UpdateComment)
 	articles.DELETE("/:slug/comments/:id", h.DeleteComment)
 	articles.POST("/:slug/favorite", h.Favorite)
 	articles.DELETE("/:slug/favorite", h.Unfavorite)
 	articles.GET("", h.Articles)
 	articles.GET("/:slug", h.GetArticle)
 	articles.GET("/:slug/comments", h.GetComments)
+	articles.GET("/:slug/comments/:id/history", h.CommentHistory,
+		utils.RequireRole(entity.UsersRoleModerator, entity.UsersRoleAdmin))
 
-	tags := v.Group("/tags")
+	articles.PUT("/:slug/tags/:tag", h.AddTagToArticle)
+	articles.DELETE("/:slug/tags/:tag", h.RemoveTagFromArticle)
+
+	profiles := v.Group("/profiles", utils.JWTWithConfig(
+		utils.JWTConfig{
+			Skipper: func(c echo.Context) bool {
+				return c.Request().Method == http.MethodGet
+			},
+			Keys: utils.JWTKeys,
+		},
+	))
+	profiles.GET("/:username/favorites", h.FavoriteArticles)
+
+	trash := v.Group("/trash", utils.JWT(utils.JWTKeys))
+	trash.GET("/articles", h.TrashedArticles)
+	trash.GET("/comments", h.TrashedComments)
+	trash.POST("/articles/:slug/restore", h.RestoreArticle)
+	trash.POST("/comments/:id/restore", h.RestoreComment)
+
+	tags := v.Group("/tags", utils.JWTWithConfig(
+		utils.JWTConfig{
+			Skipper: func(c echo.Context) bool {
+				return c.Request().Method == http.MethodGet
+			},
+			Keys: utils.JWTKeys,
+		},
+	))
 	tags.GET("", h.Tags)
+	admin := utils.RequireRole(entity.UsersRoleAdmin)
+	tags.PUT("/:tag", h.RenameTag, admin)
+	tags.POST("/:tag/merge", h.MergeTags, admin)
 }
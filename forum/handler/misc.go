@@ -1,9 +1,29 @@
 package handler
 
 import (
+	"errors"
+	http_error "http/error"
+	"http/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/domain"
+
 	"github.com/labstack/echo/v4"
 )
 
+// HTTPErrorHandler render the errors returned by the handlers in the RealWorld shape
+var HTTPErrorHandler = http_error.NewHTTPErrorHandler(MapError)
+
+// Validate check i against its validate tags, failures come back as a 422 listing the messages per field
+func Validate(c echo.Context, i interface{}) error {
+	if err := c.Validate(i); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, utils.NewValidatorError(err))
+	}
+	return nil
+}
+
 func UserIDFromToken(c echo.Context) uint {
 	id, ok := c.Get("user").(uint)
 	if !ok {
@@ -12,6 +32,84 @@ func UserIDFromToken(c echo.Context) uint {
 	return id
 }
 
+// TokenFromRequest return the access token the request was authenticated with
+func TokenFromRequest(c echo.Context) string {
+	token, _ := c.Get("token").(string)
+	return token
+}
+
+// TokenIDFromToken return the jti of the access token of the request and when that token expires
+func TokenIDFromToken(c echo.Context) (string, time.Time) {
+	jti, _ := c.Get("jti").(string)
+	exp, _ := c.Get("exp").(time.Time)
+	return jti, exp
+}
+
+// DefaultLimit is the size of the pages of the listings when no limit is asked, MaxLimit the largest one
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// BindOffsetLimit read the offset and limit query parameters of a listing, 0 and DefaultLimit when missing.
+// Values which are not numbers or are below 0 are answered with a 422, the limit is clamped to 1..MaxLimit
+func BindOffsetLimit(c echo.Context) (int, int, error) {
+	offset, limit := 0, DefaultLimit
+	errs := make(map[string]interface{})
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"offset", &offset}, {"limit", &limit}} {
+		raw := c.QueryParam(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		switch {
+		case err != nil:
+			errs[p.name] = []string{"is not a number"}
+		case n < 0:
+			errs[p.name] = []string{"must be at least 0"}
+		default:
+			*p.value = n
+		}
+	}
+	if len(errs) > 0 {
+		return 0, 0, echo.NewHTTPError(http.StatusUnprocessableEntity, http_error.JsonError{Errors: errs})
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return offset, limit, nil
+}
+
 func ResultOK() map[string]interface{} {
 	return map[string]interface{}{"status": "OK"}
 }
+
+// MapError translate a domain error into its http status and public message
+func MapError(err error) (int, string) {
+	var de *domain.Error
+	if !errors.As(err, &de) || de.Kind == domain.KindInternal {
+		return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+	switch de.Kind {
+	case domain.KindNotFound:
+		return http.StatusNotFound, de.Message
+	case domain.KindConflict:
+		return http.StatusConflict, de.Message
+	case domain.KindUnauthorized:
+		return http.StatusUnauthorized, de.Message
+	case domain.KindForbidden:
+		return http.StatusForbidden, de.Message
+	case domain.KindTimeout:
+		return http.StatusGatewayTimeout, de.Message
+	case domain.KindUnavailable:
+		return http.StatusServiceUnavailable, de.Message
+	default:
+		return http.StatusUnprocessableEntity, de.Message
+	}
+}
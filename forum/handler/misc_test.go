@@ -1 +1,73 @@
 package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"forum/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"not found", domain.NotFound("article"), http.StatusNotFound, "article not found"},
+		{"conflict", domain.Conflict("username already taken"), http.StatusConflict, "username already taken"},
+		{"unauthorized", domain.Unauthorized("invalid refresh token"), http.StatusUnauthorized, "invalid refresh token"},
+		{"forbidden", fmt.Errorf("delete: %w", domain.Forbidden("comment")), http.StatusForbidden, "access to comment forbidden"},
+		{"validation", domain.Validation("title is required"), http.StatusUnprocessableEntity, "title is required"},
+		{"timeout", domain.Timeout(context.DeadlineExceeded), http.StatusGatewayTimeout, "request timed out"},
+		{"unavailable", domain.Unavailable(context.Canceled), http.StatusServiceUnavailable, "service unavailable"},
+		{"internal", domain.Internal(fmt.Errorf("connection refused")), http.StatusInternalServerError, "Internal Server Error"},
+		{"unknown", fmt.Errorf("boom"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := MapError(tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.message, message)
+		})
+	}
+}
+
+func TestBindOffsetLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		offset int
+		limit  int
+		status int
+	}{
+		{"defaults", "", 0, DefaultLimit, 0},
+		{"given", "offset=40&limit=10", 40, 10, 0},
+		{"limit of 0", "limit=0", 0, 1, 0},
+		{"limit past the max", "limit=1000", 0, MaxLimit, 0},
+		{"negative offset", "offset=-5", 0, 0, http.StatusUnprocessableEntity},
+		{"negative limit", "limit=-1", 0, 0, http.StatusUnprocessableEntity},
+		{"not a number", "limit=ten", 0, 0, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil), httptest.NewRecorder())
+			offset, limit, err := BindOffsetLimit(c)
+			if tt.status != 0 {
+				var he *echo.HTTPError
+				assert.ErrorAs(t, err, &he)
+				assert.Equal(t, tt.status, he.Code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.offset, offset)
+			assert.Equal(t, tt.limit, limit)
+		})
+	}
+}
@@ -1,11 +1,12 @@
 package user
 
 import (
-	http_error "http/error"
 	"net/http"
+	"schema/entity"
 
 	"forum/handler"
 	"forum/model"
+	"forum/service/user"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
@@ -22,18 +23,23 @@ import (
 // @Success 201 {object} userResponse
 // @Failure 400 {object} utils.Error
 // @Failure 404 {object} utils.Error
+// @Failure 409 {object} utils.Error
 // @Failure 500 {object} utils.Error
 // @Router /users [post]
 func (h *Handler) SignUp(c echo.Context) error {
 	var reg model.RegisterUser
 	if err := c.Bind(&reg); err != nil {
 		log.Error().Err(err).Msg("Error binding request")
-		return c.JSON(http.StatusUnprocessableEntity, http_error.NewError(err))
+		return err
 	}
-	if err := h.Service.CreateUser(&reg); err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, http_error.NewError(err))
+	if err := handler.Validate(c, &reg); err != nil {
+		return err
 	}
-	return c.JSON(http.StatusCreated, handler.ResultOK())
+	u, err := h.Service.CreateUser(c.Request().Context(), &reg)
+	if err != nil {
+		return err
+	}
+	return h.openSession(c, http.StatusCreated, u)
 }
 
 // Login godoc
@@ -54,15 +60,222 @@ func (h *Handler) SignUp(c echo.Context) error {
 func (h *Handler) Login(c echo.Context) error {
 	var req model.LoginUser
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, http_error.NewError(err))
+		return err
+	}
+	if err := handler.Validate(c, &req); err != nil {
+		return err
 	}
-	err := h.Service.CheckUser(&req)
+	u, err := h.Service.CheckUser(c.Request().Context(), &req)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, http_error.NewError(err))
+		return err
+	}
+	return h.openSession(c, http.StatusOK, u)
+}
+
+// openSession answer with u and the tokens of a new session
+func (h *Handler) openSession(c echo.Context, code int, u *entity.User) error {
+	token, refreshToken, err := h.Service.IssueTokens(c.Request().Context(), u)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to issue tokens")
+		return err
+	}
+	return c.JSON(code, user.NewUserResponse(u, token, refreshToken))
+}
+
+// Refresh godoc
+// @Summary Refresh the tokens of a session
+// @Description Trade a refresh token for a new access token and a new refresh token, the refresh token traded can't be used again
+// @ID refresh
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param session body model.RefreshSession true "Refresh token of the session"
+// @Success 200 {object} userResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /users/refresh [post]
+func (h *Handler) Refresh(c echo.Context) error {
+	var req model.RefreshSession
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := handler.Validate(c, &req); err != nil {
+		return err
+	}
+	u, token, refreshToken, err := h.Service.RefreshSession(c.Request().Context(), req.RefreshToken)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to refresh session")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewUserResponse(u, token, refreshToken))
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke the access token of the request right away, and the refresh token given if any, an unknown one is ignored. Auth is required
+// @ID logout
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param session body model.Logout false "Refresh token of the session"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /users/logout [post]
+func (h *Handler) Logout(c echo.Context) error {
+	var req model.Logout
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	jti, exp := handler.TokenIDFromToken(c)
+	err := h.Service.Logout(c.Request().Context(), handler.UserIDFromToken(c), jti, exp, req.RefreshToken)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to logout")
+		return err
 	}
 	return c.JSON(http.StatusOK, handler.ResultOK())
 }
 
+// SetRole godoc
+// @Summary Change the role of a user
+// @Description Give a user the role user, moderator or admin. Admin only, an admin cannot change its own role
+// @ID set-role
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the user"
+// @Param role body model.UpdateRole true "New role of the user"
+// @Success 200 {object} profileResponse
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 409 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /users/{username}/role [put]
+func (h *Handler) SetRole(c echo.Context) error {
+	var req model.UpdateRole
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := handler.Validate(c, &req); err != nil {
+		return err
+	}
+	u, err := h.Service.SetUserRole(c.Request().Context(), handler.UserIDFromToken(c), c.Param("username"), req.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set role")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileResponse(u, false))
+}
+
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Move a user to the trash and end its sessions. Admin only, an admin cannot delete itself
+// @ID delete-user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the user"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 409 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /users/{username} [delete]
+func (h *Handler) DeleteUser(c echo.Context) error {
+	err := h.Service.DeleteUserByUserName(c.Request().Context(), handler.UserIDFromToken(c), c.Param("username"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete user")
+		return err
+	}
+	return c.JSON(http.StatusOK, handler.ResultOK())
+}
+
+// TrashedUsers godoc
+// @Summary List the trashed users
+// @Description List the users in the trash, the last deleted first. Admin only
+// @ID trashed-users
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param limit query integer false "Limit number of profiles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of profiles (default is 0)"
+// @Success 200 {object} profileListResponse
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/users [get]
+func (h *Handler) TrashedUsers(c echo.Context) error {
+	offset, limit, err := handler.BindOffsetLimit(c)
+	if err != nil {
+		return err
+	}
+	users, count, err := h.Service.FindTrashedUsers(c.Request().Context(), handler.UserIDFromToken(c), offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trashed users")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileListResponse(users, map[uint64]bool{}, count))
+}
+
+// RestoreUser godoc
+// @Summary Restore a user
+// @Description Take a user out of the trash. Admin only
+// @ID restore-user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the user"
+// @Success 200 {object} profileResponse
+// @Failure 401 {object} utils.Error
+// @Failure 403 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /trash/users/{username}/restore [post]
+func (h *Handler) RestoreUser(c echo.Context) error {
+	u, err := h.Service.RestoreUserByUserName(c.Request().Context(), handler.UserIDFromToken(c), c.Param("username"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to restore user")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileResponse(u, false))
+}
+
+// CurrentUser godoc
+// @Summary Get the current user
+// @Description Gets the currently logged-in user
+// @ID current-user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} userResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /user [get]
+func (h *Handler) CurrentUser(c echo.Context) error {
+	u, err := h.Service.GetUserByID(c.Request().Context(), handler.UserIDFromToken(c))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get current user")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewUserResponse(u, handler.TokenFromRequest(c), ""))
+}
+
 // UpdateUser godoc
 // @Summary UpdateUser current user
 // @Description UpdateUser user information for current user
@@ -74,6 +287,7 @@ func (h *Handler) Login(c echo.Context) error {
 // @Success 200 {object} userResponse
 // @Failure 400 {object} utils.Error
 // @Failure 401 {object} utils.Error
+// @Failure 409 {object} utils.Error
 // @Failure 422 {object} utils.Error
 // @Failure 404 {object} utils.Error
 // @Failure 500 {object} utils.Error
@@ -81,16 +295,159 @@ func (h *Handler) Login(c echo.Context) error {
 // @Router /user [put]
 func (h *Handler) UpdateUser(c echo.Context) error {
 	uid := handler.UserIDFromToken(c)
-	var s model.ProfileType
+	var s model.UpdateUser
 	if err := c.Bind(&s); err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, http_error.NewError(err))
+		return err
 	}
-	u, err := h.Service.GetUserByID(uid)
+	if err := handler.Validate(c, &s); err != nil {
+		return err
+	}
+	u, err := h.Service.UpdateUser(c.Request().Context(), uid, &s)
 	if err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, http_error.NewError(err))
+		log.Error().Err(err).Msg("Failed to update user")
+		return err
 	}
-	if err = h.Service.UpdateUser(u); err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, http_error.NewError(err))
+	return c.JSON(http.StatusOK, user.NewUserResponse(u, handler.TokenFromRequest(c), ""))
+}
+
+// GetProfile godoc
+// @Summary Get a profile
+// @Description Get a profile of a user of the system. Auth is optional
+// @ID get-profile
+// @Tags profile
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile to get"
+// @Success 200 {object} profileResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /profiles/{username} [get]
+func (h *Handler) GetProfile(c echo.Context) error {
+	username := c.Param("username")
+	u, following, err := h.Service.GetProfile(c.Request().Context(), handler.UserIDFromToken(c), username)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get profile")
+		return err
 	}
-	return c.JSON(http.StatusOK, handler.ResultOK())
+	return c.JSON(http.StatusOK, user.NewProfileResponse(u, following))
+}
+
+// Follow godoc
+// @Summary Follow a user
+// @Description Follow a user by username, following it again changes nothing. A user cannot follow itself
+// @ID follow
+// @Tags follow
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile you want to follow"
+// @Success 200 {object} profileResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /profiles/{username}/follow [post]
+func (h *Handler) Follow(c echo.Context) error {
+	username := c.Param("username")
+	u, err := h.Service.FollowUserByUserName(c.Request().Context(), handler.UserIDFromToken(c), username)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to follow user")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileResponse(u, true))
+}
+
+// Unfollow godoc
+// @Summary Unfollow a user
+// @Description Unfollow a user by username
+// @ID unfollow
+// @Tags follow
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile you want to unfollow"
+// @Success 200 {object} profileResponse
+// @Failure 400 {object} utils.Error
+// @Failure 401 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 404 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Security ApiKeyAuth
+// @Router /profiles/{username}/follow [delete]
+func (h *Handler) Unfollow(c echo.Context) error {
+	username := c.Param("username")
+	u, err := h.Service.UnFollowUserByUserName(c.Request().Context(), handler.UserIDFromToken(c), username)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to unfollow user")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileResponse(u, false))
+}
+
+// Followers godoc
+// @Summary List the followers of a user
+// @Description List the users following a user. Auth is optional
+// @ID followers
+// @Tags follow
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile"
+// @Param limit query integer false "Limit number of profiles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of profiles (default is 0)"
+// @Success 200 {object} profileListResponse
+// @Failure 404 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /profiles/{username}/followers [get]
+func (h *Handler) Followers(c echo.Context) error {
+	offset, limit, err := handler.BindOffsetLimit(c)
+	if err != nil {
+		return err
+	}
+	users, count, err := h.Service.GetFollowersByUserName(c.Request().Context(), c.Param("username"), offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get followers")
+		return err
+	}
+	return h.profileList(c, users, count)
+}
+
+// Following godoc
+// @Summary List the users followed by a user
+// @Description List the users followed by a user. Auth is optional
+// @ID following
+// @Tags follow
+// @Accept  json
+// @Produce  json
+// @Param username path string true "Username of the profile"
+// @Param limit query integer false "Limit number of profiles returned (default is 20)"
+// @Param offset query integer false "Offset/skip number of profiles (default is 0)"
+// @Success 200 {object} profileListResponse
+// @Failure 404 {object} utils.Error
+// @Failure 422 {object} utils.Error
+// @Failure 500 {object} utils.Error
+// @Router /profiles/{username}/following [get]
+func (h *Handler) Following(c echo.Context) error {
+	offset, limit, err := handler.BindOffsetLimit(c)
+	if err != nil {
+		return err
+	}
+	users, count, err := h.Service.GetFollowingByUserName(c.Request().Context(), c.Param("username"), offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get following")
+		return err
+	}
+	return h.profileList(c, users, count)
+}
+
+func (h *Handler) profileList(c echo.Context, users []*entity.User, count int64) error {
+	following, err := h.Service.GetFollowingFlags(c.Request().Context(), handler.UserIDFromToken(c), users)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get following flags")
+		return err
+	}
+	return c.JSON(http.StatusOK, user.NewProfileListResponse(users, following, count))
 }
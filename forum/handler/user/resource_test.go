@@ -1,13 +1,19 @@
 package user
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"schema/entity"
 	"strings"
 	"testing"
+	"time"
 
+	"forum/domain"
+	forumHandler "forum/handler"
 	"forum/mock/service"
+	"forum/model"
 	"http/utils"
 
 	"github.com/stretchr/testify/mock"
@@ -15,29 +21,48 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/volatiletech/null/v8"
 )
 
 func TestUserLogin_Bind(t *testing.T) {
-	jsonUser := `{"user":{"email":"alice@realworld.io","password":"secret"}}`
+	jsonUser := `{"email":"alice@realworld.io","password":"secret"}`
 	const ApiLogin = "/api/v1/login"
 	t.Run("When Bind return OK ", func(t *testing.T) {
 		rec, c := echoSetup(http.MethodPost, ApiLogin, jsonUser)
 		serviceUserMock := service.NewIServiceUser(t)
-		serviceUserMock.On("CheckUser", mock.Anything).Return(nil)
+		serviceUserMock.On("CheckUser", mock.Anything, mock.Anything).Return(&entity.User{ID: 1, Username: "alice", Email: "alice@realworld.io"}, nil)
+		serviceUserMock.On("IssueTokens", mock.Anything, mock.Anything).Return("access", "refresh", nil)
 		handler := NewUserHandler(serviceUserMock)
 		err := handler.Login(c)
 		require.NoError(t, err)
 		// Assertions
 		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp model.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "alice", resp.User.Username)
+		assert.Equal(t, "access", resp.User.Token)
+		assert.Equal(t, "refresh", resp.User.RefreshToken)
+	})
+	t.Run("When IssueTokens return Error", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, ApiLogin, jsonUser)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("CheckUser", mock.Anything, mock.Anything).Return(&entity.User{ID: 1, Username: "alice", Email: "alice@realworld.io"}, nil)
+		serviceUserMock.On("IssueTokens", mock.Anything, mock.Anything).Return("", "", domain.Internal(fmt.Errorf("some error")))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Login(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 	t.Run("When CheckUser return Error", func(t *testing.T) {
 		rec, c := echoSetup(http.MethodPost, ApiLogin, jsonUser)
 		serviceUserMock := service.NewIServiceUser(t)
-		serviceUserMock.On("CheckUser", mock.Anything).Return(fmt.Errorf("error"))
+		serviceUserMock.On("CheckUser", mock.Anything, mock.Anything).Return(nil, domain.NotFound("user"))
 		handler := NewUserHandler(serviceUserMock)
 		err := handler.Login(c)
 		// Assertions
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 	})
 	t.Run("When request json is invalid,Bind return Error ", func(t *testing.T) {
@@ -47,11 +72,402 @@ func TestUserLogin_Bind(t *testing.T) {
 		// Assertions
 		err := handler.Login(c)
 		// Assertions
-		require.NoError(t, err)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 }
 
+func TestUser_SignUp(t *testing.T) {
+	jsonUser := `{"username":"alice","email":"alice@realworld.io","password":"secret"}`
+	t.Run("When CreateUser return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users", jsonUser)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("CreateUser", mock.Anything, mock.Anything).Return(&entity.User{ID: 1, Username: "alice", Email: "alice@realworld.io"}, nil)
+		serviceUserMock.On("IssueTokens", mock.Anything, mock.Anything).Return("access", "refresh", nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.SignUp(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var resp model.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "alice@realworld.io", resp.User.Email)
+		assert.NotEmpty(t, resp.User.Token)
+	})
+	t.Run("When CreateUser return Error", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users", jsonUser)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("CreateUser", mock.Anything, mock.Anything).Return(nil, domain.Conflict("user already exists"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.SignUp(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("When request is invalid", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users", `{"username":"al","email":"alice","password":"secret"}`)
+		serviceUserMock := service.NewIServiceUser(t)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.SignUp(c)
+		// Assertions
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"errors":{"username":["is too short (minimum is 3 characters)"],"email":["is invalid"]}}`, rec.Body.String())
+	})
+}
+
+func TestUser_CurrentUser(t *testing.T) {
+	t.Run("When GetUserByID return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodGet, "/api/v1/user", "")
+		c.Set("user", uint(1))
+		c.Set("token", "access")
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetUserByID", mock.Anything, uint(1)).Return(&entity.User{ID: 1, Username: "alice", Email: "alice@realworld.io"}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.CurrentUser(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp model.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "alice", resp.User.Username)
+		assert.Equal(t, "access", resp.User.Token)
+	})
+	t.Run("When GetUserByID return Error", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodGet, "/api/v1/user", "")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetUserByID", mock.Anything, uint(1)).Return(nil, domain.NotFound("user"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.CurrentUser(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestUser_UpdateUser(t *testing.T) {
+	t.Run("When UpdateUser return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/user", `{"bio":"I like to skateboard","image":"https://i.stack.imgur.com/xHWG8.jpg"}`)
+		c.Set("user", uint(1))
+		c.Set("token", "access")
+		serviceUserMock := service.NewIServiceUser(t)
+		update := &model.UpdateUser{Bio: "I like to skateboard", Image: "https://i.stack.imgur.com/xHWG8.jpg"}
+		serviceUserMock.On("UpdateUser", mock.Anything, uint(1), update).
+			Return(&entity.User{ID: 1, Username: "alice", Bio: null.StringFrom("I like to skateboard")}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.UpdateUser(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp model.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "I like to skateboard", *resp.User.Bio)
+		assert.Equal(t, "access", resp.User.Token)
+	})
+	t.Run("When request is invalid", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/user", `{"email":"alice"}`)
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.UpdateUser(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+	t.Run("When the username is taken", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/user", `{"username":"bob"}`)
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("UpdateUser", mock.Anything, uint(1), &model.UpdateUser{Username: "bob"}).Return(nil, domain.Conflict("user already exists"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.UpdateUser(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestUser_Refresh(t *testing.T) {
+	t.Run("When RefreshSession return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users/refresh", `{"refreshToken":"old"}`)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("RefreshSession", mock.Anything, "old").Return(&entity.User{ID: 1, Username: "alice"}, "access", "new", nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Refresh(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp model.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "access", resp.User.Token)
+		assert.Equal(t, "new", resp.User.RefreshToken)
+	})
+	t.Run("When the refresh token is rejected", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users/refresh", `{"refreshToken":"old"}`)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("RefreshSession", mock.Anything, "old").Return(nil, "", "", domain.Unauthorized("invalid refresh token"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Refresh(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("When the refresh token is missing", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users/refresh", `{}`)
+		serviceUserMock := service.NewIServiceUser(t)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Refresh(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
+
+func TestUser_Logout(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	t.Run("When Logout return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users/logout", `{"refreshToken":"old"}`)
+		c.Set("user", uint(1))
+		c.Set("jti", "abc")
+		c.Set("exp", exp)
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("Logout", mock.Anything, uint(1), "abc", exp, "old").Return(nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Logout(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("When the tokens cannot be revoked", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPost, "/api/v1/users/logout", `{"refreshToken":"other"}`)
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("Logout", mock.Anything, uint(1), "", time.Time{}, "other").Return(domain.Internal(fmt.Errorf("some error")))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Logout(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestUser_SetRole(t *testing.T) {
+	t.Run("When SetUserRole return OK", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/users/bar/role", `{"role":"moderator"}`)
+		c.SetParamNames("username")
+		c.SetParamValues("bar")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("SetUserRole", mock.Anything, uint(1), "bar", entity.UsersRoleModerator).
+			Return(&entity.User{ID: 2, Username: "bar", Role: entity.UsersRoleModerator}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.SetRole(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var r model.ProfileResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+		assert.Equal(t, entity.UsersRoleModerator, r.Profile.Role)
+	})
+	t.Run("When the role is unknown", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/users/bar/role", `{"role":"root"}`)
+		c.Set("user", uint(1))
+		handler := NewUserHandler(service.NewIServiceUser(t))
+		err := handler.SetRole(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+	t.Run("When the caller is not an admin", func(t *testing.T) {
+		rec, c := echoSetup(http.MethodPut, "/api/v1/users/bar/role", `{"role":"admin"}`)
+		c.Set("user", uint(2))
+		c.Set("role", entity.UsersRoleModerator)
+		handler := NewUserHandler(service.NewIServiceUser(t))
+		err := utils.RequireRole(entity.UsersRoleAdmin)(handler.SetRole)(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestUser_DeleteUser(t *testing.T) {
+	t.Run("When DeleteUserByUserName return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodDelete, "/api/v1/users/bar")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("DeleteUserByUserName", mock.Anything, uint(1), "bar").Return(nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.DeleteUser(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("When the admin deletes itself", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodDelete, "/api/v1/users/bar")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("DeleteUserByUserName", mock.Anything, uint(1), "bar").Return(domain.Conflict("an admin cannot delete itself"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.DeleteUser(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestUser_TrashedUsers(t *testing.T) {
+	rec, c := echoSetup(http.MethodGet, "/api/v1/trash/users", "")
+	c.Set("user", uint(1))
+	serviceUserMock := service.NewIServiceUser(t)
+	serviceUserMock.On("FindTrashedUsers", mock.Anything, uint(1), 0, 20).
+		Return([]*entity.User{{ID: 2, Username: "bar"}}, int64(1), nil)
+	handler := NewUserHandler(serviceUserMock)
+	err := handler.TrashedUsers(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var r model.ProfileListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+	require.Len(t, r.Profiles, 1)
+	assert.Equal(t, "bar", r.Profiles[0].Username)
+	assert.Equal(t, int64(1), r.ProfilesCount)
+}
+
+func TestUserProfile_FollowersPage(t *testing.T) {
+	rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar/followers?offset=-5")
+	handler := NewUserHandler(service.NewIServiceUser(t))
+	err := handler.Followers(c)
+	require.Error(t, err)
+	forumHandler.HTTPErrorHandler(err, c)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "must be at least 0")
+}
+
+func TestUser_RestoreUser(t *testing.T) {
+	t.Run("When RestoreUserByUserName return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodPost, "/api/v1/trash/users/bar/restore")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("RestoreUserByUserName", mock.Anything, uint(1), "bar").Return(&entity.User{ID: 2, Username: "bar"}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.RestoreUser(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("When the user is not in the trash", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodPost, "/api/v1/trash/users/bar/restore")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("RestoreUserByUserName", mock.Anything, uint(1), "bar").Return(nil, domain.NotFound("user"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.RestoreUser(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestUserProfile_GetProfile(t *testing.T) {
+	t.Run("When GetProfile return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetProfile", mock.Anything, uint(1), "bar").Return(&entity.User{Username: "bar"}, true, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.GetProfile(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"following":true`)
+	})
+	t.Run("When GetProfile return Error", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar")
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetProfile", mock.Anything, uint(0), "bar").Return(nil, false, domain.NotFound("user"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.GetProfile(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestUserProfile_Follow(t *testing.T) {
+	t.Run("When follow return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodPost, "/api/v1/profiles/bar/follow")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("FollowUserByUserName", mock.Anything, uint(1), "bar").Return(&entity.User{Username: "bar"}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Follow(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"following":true`)
+	})
+	t.Run("When follow return Error", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodPost, "/api/v1/profiles/bar/follow")
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("FollowUserByUserName", mock.Anything, mock.Anything, mock.Anything).Return(nil, domain.NotFound("user"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Follow(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("When unfollow return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodDelete, "/api/v1/profiles/bar/follow")
+		c.Set("user", uint(1))
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("UnFollowUserByUserName", mock.Anything, uint(1), "bar").Return(&entity.User{Username: "bar"}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Unfollow(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"following":false`)
+	})
+}
+
+func TestUserProfile_Followers(t *testing.T) {
+	t.Run("When list followers return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar/followers?offset=0&limit=10")
+		c.Set("user", uint(1))
+		users := []*entity.User{{ID: 3, Username: "baz"}}
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetFollowersByUserName", mock.Anything, "bar", 0, 10).Return(users, int64(1), nil)
+		serviceUserMock.On("GetFollowingFlags", mock.Anything, uint(1), users).Return(map[uint64]bool{3: true}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Followers(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"profilesCount":1`)
+	})
+	t.Run("When list followers return Error", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar/followers")
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetFollowersByUserName", mock.Anything, "bar", 0, 20).Return(nil, int64(0), domain.NotFound("user"))
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Followers(c)
+		require.Error(t, err)
+		forumHandler.HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("When list following return OK", func(t *testing.T) {
+		rec, c := echoProfileSetup(http.MethodGet, "/api/v1/profiles/bar/following")
+		users := []*entity.User{{ID: 3, Username: "baz"}}
+		serviceUserMock := service.NewIServiceUser(t)
+		serviceUserMock.On("GetFollowingByUserName", mock.Anything, "bar", 0, 20).Return(users, int64(1), nil)
+		serviceUserMock.On("GetFollowingFlags", mock.Anything, uint(0), users).Return(map[uint64]bool{}, nil)
+		handler := NewUserHandler(serviceUserMock)
+		err := handler.Following(c)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func echoProfileSetup(method string, url string) (*httptest.ResponseRecorder, echo.Context) {
+	rec, c := echoSetup(method, url, "")
+	c.SetParamNames("username")
+	c.SetParamValues("bar")
+	return rec, c
+}
+
+func init() {
+	utils.JWTKeys, _ = utils.NewKeySet(utils.HMACKey("test", []byte("secret")))
+}
+
 func echoSetup(method string, url string, jsonUser string) (*httptest.ResponseRecorder, echo.Context) {
 	e := echo.New()
 	e.Validator = utils.NewValidator()
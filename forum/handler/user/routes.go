@@ -2,16 +2,42 @@ package user
 
 import (
 	"http/utils"
+	"net/http"
+	"schema/entity"
 
 	"github.com/labstack/echo/v4"
 )
 
 func (h *Handler) Register(v *echo.Group) {
-	jwtMiddleware := utils.JWT(utils.JWTSecret)
+	jwtMiddleware := utils.JWT(utils.JWTKeys)
 	guestUsers := v.Group("/users")
 	guestUsers.POST("", h.SignUp)
 	guestUsers.POST("/login", h.Login)
+	guestUsers.POST("/refresh", h.Refresh)
+	guestUsers.POST("/logout", h.Logout, jwtMiddleware)
+	admin := utils.RequireRole(entity.UsersRoleAdmin)
+	guestUsers.PUT("/:username/role", h.SetRole, jwtMiddleware, admin)
+	guestUsers.DELETE("/:username", h.DeleteUser, jwtMiddleware, admin)
+
+	trash := v.Group("/trash")
+	trash.GET("/users", h.TrashedUsers, jwtMiddleware, admin)
+	trash.POST("/users/:username/restore", h.RestoreUser, jwtMiddleware, admin)
 
 	user := v.Group("/user", jwtMiddleware)
+	user.GET("", h.CurrentUser)
 	user.PUT("", h.UpdateUser)
+
+	profiles := v.Group("/profiles", utils.JWTWithConfig(
+		utils.JWTConfig{
+			Skipper: func(c echo.Context) bool {
+				return c.Request().Method == http.MethodGet
+			},
+			Keys: utils.JWTKeys,
+		},
+	))
+	profiles.GET("/:username", h.GetProfile)
+	profiles.POST("/:username/follow", h.Follow)
+	profiles.DELETE("/:username/follow", h.Unfollow)
+	profiles.GET("/:username/followers", h.Followers)
+	profiles.GET("/:username/following", h.Following)
 }
@@ -0,0 +1,50 @@
+// Package job holds the tasks the forum runs in the background
+package job
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultTrashRetention keep the trashed rows for 30 days when TRASH_RETENTION is not set
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	// DefaultPurgeInterval empty the trash hourly when TRASH_PURGE_INTERVAL is not set
+	DefaultPurgeInterval = time.Hour
+)
+
+// TrashPurger remove for good what was trashed before a given time
+type TrashPurger interface {
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PurgeTrash purge, right away then every interval, the rows trashed for longer than retention until ctx is done
+func PurgeTrash(ctx context.Context, p TrashPurger, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := p.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge trash")
+		} else if n > 0 {
+			log.Info().Int64("rows", n).Msg("trash purged")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DurationFromEnv read the duration (e.g. "720h") of the environment variable key, def when it is unset or invalid
+func DurationFromEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type purgerFunc func(ctx context.Context, before time.Time) (int64, error)
+
+func (f purgerFunc) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	return f(ctx, before)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var cutoffs []time.Time
+	p := purgerFunc(func(_ context.Context, before time.Time) (int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		cutoffs = append(cutoffs, before)
+		if len(cutoffs) == 3 {
+			cancel()
+		}
+		if len(cutoffs) == 1 {
+			return 0, fmt.Errorf("purge error")
+		}
+		return 2, nil
+	})
+	start := time.Now()
+	PurgeTrash(ctx, p, time.Hour, time.Millisecond)
+	assert.Len(t, cutoffs, 3)
+	for _, c := range cutoffs {
+		assert.WithinDuration(t, start.Add(-time.Hour), c, time.Second)
+	}
+}
+
+func TestDurationFromEnv(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "48h")
+	assert.Equal(t, 48*time.Hour, DurationFromEnv("TRASH_RETENTION", DefaultTrashRetention))
+	t.Setenv("TRASH_RETENTION", "-1h")
+	assert.Equal(t, DefaultTrashRetention, DurationFromEnv("TRASH_RETENTION", DefaultTrashRetention))
+	t.Setenv("TRASH_RETENTION", "a month")
+	assert.Equal(t, DefaultTrashRetention, DurationFromEnv("TRASH_RETENTION", DefaultTrashRetention))
+}
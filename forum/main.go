@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"db"
 	"http/middleware"
 	"http/utils"
 
+	"forum/handler"
 	"forum/handler/article"
 	"forum/handler/user"
+	"forum/job"
 	"forum/repository/mysql"
 	articleService "forum/service/article"
 	userService "forum/service/user"
@@ -35,15 +38,23 @@ import (
 
 func main() {
 	r := echo.New()
+	keys, err := utils.KeySetFromEnv()
+	if err != nil {
+		r.Logger.Fatal(err)
+	}
+	utils.JWTKeys = keys
 	middleware.ConfigMiddleware(r)
 	middleware.SetupZeroLog(r)
+	middleware.SetupRequestTimeout(r, middleware.RequestTimeoutFromEnv())
 	setupRouter(r)
 	r.Validator = utils.NewValidator()
+	r.HTTPErrorHandler = handler.HTTPErrorHandler
 	r.Logger.Fatal(r.Start("8585"))
 }
 
 func setupRouter(r *echo.Echo) {
 	r.GET("/swagger/*", webSwagger.WrapHandler)
+	r.GET("/.well-known/jwks.json", utils.JWKSHandler(utils.JWTKeys))
 
 	v1 := r.Group("/api/v1")
 
@@ -52,13 +63,15 @@ func setupRouter(r *echo.Echo) {
 	userRepo := mysql.NewUserRepo(d)
 	articleRepo := mysql.NewArticleRepo(d)
 	us := userService.NewUserService(userRepo)
-	as := articleService.NewServiceArticle(articleRepo, userRepo)
+	as := articleService.NewServiceArticle(articleRepo, userRepo, mysql.NewUnitOfWork(d), mysql.NewArticleSearch(d))
+	utils.JWTDenylist = us.IsTokenRevoked
 	uh := user.NewUserHandler(us)
 	ah := article.NewArticleHandler(as)
 
-	userRouter := v1.Group("/users")
-	uh.Register(userRouter)
+	go job.PurgeTrash(context.Background(), as,
+		job.DurationFromEnv("TRASH_RETENTION", job.DefaultTrashRetention),
+		job.DurationFromEnv("TRASH_PURGE_INTERVAL", job.DefaultPurgeInterval))
 
-	articleRouter := v1.Group("/articles")
-	ah.Register(articleRouter)
+	uh.Register(v1)
+	ah.Register(v1)
 }
@@ -27,38 +27,143 @@ type CommentResponse struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	Author    Author    `json:"author"`
+	// Edited tells whether the body was changed since the comment was posted
+	Edited bool `json:"edited"`
+	// ParentID is the id of the comment replied to, nil for a top-level comment
+	ParentID *uint `json:"parentId"`
+	// Depth is the number of comments above it in its thread
+	Depth int `json:"depth"`
+	// Replies are the replies to the comment in the tree view
+	Replies []CommentResponse `json:"replies,omitempty"`
 }
 
+// DeletedCommentBody is the body of the placeholder left in its thread by a deleted comment which has
+// replies
+const DeletedCommentBody = "[deleted]"
+
+// the views of the comments of an article: the threads flattened in reading order, every comment
+// carrying its depth, or nested in a tree
+const (
+	CommentViewFlat = "flat"
+	CommentViewTree = "tree"
+)
+
 type SimpleArticle struct {
-	Slug        string   `json:"slug"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
+	Title       string   `json:"title" validate:"required,max=255"`
+	Description string   `json:"description" validate:"required,max=255"`
+	Body        string   `json:"body" validate:"required"`
+	TagList     []string `json:"tagList,omitempty" validate:"omitempty,dive,required,max=64"`
+}
+
+// UpdateArticle carry the fields of an article to change, empty fields are left untouched.
+// A tagList, even empty, replaces the tags of the article
+type UpdateArticle struct {
+	Title       string   `json:"title" validate:"max=255"`
+	Description string   `json:"description" validate:"max=255"`
 	Body        string   `json:"body"`
-	TagList     []string `json:"tagList,omitempty"`
+	TagList     []string `json:"tagList" validate:"omitempty,dive,required,max=64"`
 }
 type SingleArticle struct {
 	Article *ArticleResponse `json:"article"`
 }
 
-type SimpleArticleList struct {
-	Articles      []*SimpleArticle `json:"articles"`
-	ArticlesCount int64            `json:"articlesCount"`
+// the orders of the article listings
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// ArticleFilter narrow an article listing to the articles matching all the criteria set, the zero
+// value matches every article, newest first
+type ArticleFilter struct {
+	// Tag is the name of a tag of the articles
+	Tag string
+	// Author is the username of their author
+	Author string
+	// Favorited is the username of a user who favorited them
+	Favorited string
+	// Since and Until bound their creation time, Until excluded
+	Since time.Time
+	Until time.Time
+	// Sort is SortNewest or SortOldest
+	Sort string
+}
+
+type ArticleListResponse struct {
+	Articles      []*ArticleResponse `json:"articles"`
+	ArticlesCount int64              `json:"articlesCount"`
+	PrevCursor    string             `json:"prevCursor,omitempty"`
+	NextCursor    string             `json:"nextCursor,omitempty"`
+}
+
+// ArticleHit is an article matching a search, Snippet quotes it around the terms searched for
+type ArticleHit struct {
+	*ArticleResponse
+	Snippet string `json:"snippet"`
+}
+
+type ArticleSearchResponse struct {
+	Articles      []*ArticleHit `json:"articles"`
+	ArticlesCount int64         `json:"articlesCount"`
 }
 
 type SingleArticleResponse struct {
 	Article *ArticleResponse `json:"article"`
 }
 
+type SingleCommentResponse struct {
+	Comment *CommentResponse `json:"comment"`
+}
+
+// CommentRevision is a former body of a comment, replaced by an edit at ReplacedAt
+type CommentRevision struct {
+	Body       string    `json:"body"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+// CommentHistoryResponse is a comment along with its former bodies, the oldest first
+type CommentHistoryResponse struct {
+	Comment   *CommentResponse  `json:"comment"`
+	Revisions []CommentRevision `json:"revisions"`
+}
+
 type CommentListResponse struct {
-	Comments []CommentResponse `json:"comments"`
+	Comments      []CommentResponse `json:"comments"`
+	CommentsCount int64             `json:"commentsCount"`
+	PrevCursor    string            `json:"prevCursor,omitempty"`
+	NextCursor    string            `json:"nextCursor,omitempty"`
 }
 
 type TagListResponse struct {
 	Tags []string `json:"tags"`
 }
 
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type TagCountListResponse struct {
+	Tags []TagCount `json:"tags"`
+}
+
+// RenameTag carry the new name of a tag
+type RenameTag struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// MergeTags carry the tag which absorbs the merged one
+type MergeTags struct {
+	Into string `json:"into" validate:"required,max=64"`
+}
+
 type CommentRequest struct {
-	Comment struct {
-		Body string `json:"body" validate:"required"`
-	} `json:"comment"`
+	Body string `json:"body" validate:"required,max=4096"`
+	// ParentID is the id of the comment replied to, on the same article
+	ParentID *uint64 `json:"parentId"`
+}
+
+// UpdateComment carry the new body of a comment
+type UpdateComment struct {
+	Body string `json:"body" validate:"required,max=4096"`
 }
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a row in a listing ordered by (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+// String encode the cursor as the opaque token the clients send back
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + strconv.FormatUint(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decode a token made by Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := Cursor{CreatedAt: time.Unix(0, nanos).UTC()}
+	c.ID, err = strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Page select Limit rows of a listing: the rows following After or preceding Before when one of
+// them is set, the rows past the first Offset ones otherwise
+type Page struct {
+	Offset int
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+}
+
+// Cursors return the cursors of the pages around the n rows read with p, first and last being the
+// positions of the first and last of them. A full page is taken as having more rows past it
+func (p Page) Cursors(n int, first, last Cursor) (prev, next string) {
+	if n == 0 {
+		return "", ""
+	}
+	full := n >= p.Limit
+	if p.Before != nil || full {
+		next = last.String()
+	}
+	if p.After != nil || p.Offset > 0 || (p.Before != nil && full) {
+		prev = first.String()
+	}
+	return prev, next
+}
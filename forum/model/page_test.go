@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCursor(t *testing.T) {
+	t.Run("when the cursor was made by String", func(t *testing.T) {
+		c := Cursor{CreatedAt: time.Date(2023, 5, 1, 10, 20, 30, 123000000, time.UTC), ID: 42}
+		parsed, err := ParseCursor(c.String())
+		require.NoError(t, err)
+		assert.True(t, c.CreatedAt.Equal(parsed.CreatedAt))
+		assert.Equal(t, uint64(42), parsed.ID)
+	})
+	t.Run("when the cursor is not base64", func(t *testing.T) {
+		_, err := ParseCursor("not a cursor!")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+	t.Run("when the cursor lacks the id", func(t *testing.T) {
+		_, err := ParseCursor("MTIzNDU")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+	t.Run("when the cursor holds no numbers", func(t *testing.T) {
+		_, err := ParseCursor("Zm9vOmJhcg")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestPage_Cursors(t *testing.T) {
+	first := Cursor{CreatedAt: time.Unix(2, 0), ID: 2}
+	last := Cursor{CreatedAt: time.Unix(1, 0), ID: 1}
+	t.Run("when the first page is full", func(t *testing.T) {
+		prev, next := Page{Limit: 2}.Cursors(2, first, last)
+		assert.Empty(t, prev)
+		assert.Equal(t, last.String(), next)
+	})
+	t.Run("when the last page is read by offset", func(t *testing.T) {
+		prev, next := Page{Offset: 4, Limit: 5}.Cursors(2, first, last)
+		assert.Equal(t, first.String(), prev)
+		assert.Empty(t, next)
+	})
+	t.Run("when the page follows a cursor", func(t *testing.T) {
+		prev, next := Page{Limit: 5, After: &first}.Cursors(2, first, last)
+		assert.Equal(t, first.String(), prev)
+		assert.Empty(t, next)
+	})
+	t.Run("when a full page precedes a cursor", func(t *testing.T) {
+		prev, next := Page{Limit: 2, Before: &last}.Cursors(2, first, last)
+		assert.Equal(t, first.String(), prev)
+		assert.Equal(t, last.String(), next)
+	})
+	t.Run("when the first page precedes a cursor", func(t *testing.T) {
+		prev, next := Page{Limit: 5, Before: &last}.Cursors(2, first, last)
+		assert.Empty(t, prev)
+		assert.Equal(t, last.String(), next)
+	})
+	t.Run("when the page is empty", func(t *testing.T) {
+		prev, next := Page{Limit: 5, After: &first}.Cursors(0, Cursor{}, Cursor{})
+		assert.Empty(t, prev)
+		assert.Empty(t, next)
+	})
+}
@@ -6,20 +6,23 @@ type Response struct {
 	Bio      *string `json:"bio"`
 	Image    *string `json:"image"`
 	Token    string  `json:"token"`
+	Role     string  `json:"role"`
+	// RefreshToken is only handed out when a session opens or is refreshed
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
 
 type RegisterUser struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,min=3,max=32"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=6,max=72"`
 }
 
 type UpdateUser struct {
-	Username string `json:"username"`
-	Email    string `json:"email" validate:"email"`
-	Password string `json:"password"`
-	Bio      string `json:"bio"`
-	Image    string `json:"image"`
+	Username string `json:"username" validate:"omitempty,min=3,max=32"`
+	Email    string `json:"email" validate:"omitempty,email,max=255"`
+	Password string `json:"password" validate:"omitempty,min=6,max=72"`
+	Bio      string `json:"bio" validate:"max=1024"`
+	Image    string `json:"image" validate:"omitempty,url,max=1024"`
 }
 
 type LoginUser struct {
@@ -27,17 +30,38 @@ type LoginUser struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// RefreshSession trade a refresh token for the next access and refresh tokens
+type RefreshSession struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Logout end the session of the access token, and of RefreshToken when given
+type Logout struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// UpdateRole give a user another role, only admins may
+type UpdateRole struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
 type ProfileType struct {
 	Username  string  `json:"username"`
 	Email     string  `json:"email"`
 	Bio       *string `json:"bio"`
 	Image     *string `json:"image"`
 	Following bool    `json:"following"`
+	Role      string  `json:"role"`
 }
 
 type ProfileResponse struct {
 	Profile ProfileType `json:"profile"`
 }
+
+type ProfileListResponse struct {
+	Profiles      []ProfileType `json:"profiles"`
+	ProfilesCount int64         `json:"profilesCount"`
+}
 type UserResponse struct {
 	User Response `json:"user"`
 }
@@ -3,37 +3,105 @@
 package repository
 
 import (
+	"context"
+	"forum/model"
 	"schema/entity"
+	"time"
 )
 
 // IRepoArticle ...
 type IRepoArticle interface {
-	FindArticleBySlug(s string) (*entity.Article, error)
-	FindArticleByAuthorIDAndSlug(userID uint64, slug string) (*entity.Article, error)
-	CreateArticle(article *entity.Article) error
-	// UpdateArticle  update article
-	UpdateArticle(article *entity.Article) error
-	DeleteArticle(article *entity.Article) error
-	// FindArticles all the articles with pagination
-	FindArticles(offset, limit int) ([]*entity.Article, int64, error)
-	ListArticlesByTag(tagStr string, offset, limit int) ([]*entity.Article, int64, error)
-	ListArticlesByAuthor(user *entity.User, offset, limit int) ([]*entity.Article, int64, error)
-	FindAuthorByArticle(article *entity.Article) (*entity.User, error)
-	ListFeed(userID uint, offset, limit int) ([]*entity.Article, int64, error)
-	AddComment(article *entity.Article, comment *entity.Comment) error
-	FindCommentsByArticle(article *entity.Article, offset int, limit int) ([]*entity.Comment, error)
-	FindCommentByID(commentID uint64) (*entity.Comment, error)
-	DeleteComment(comment *entity.Comment) error
-	DeleteCommentByCommentID(commentID uint64) error
-	DeleteCommentByArticle(article *entity.Article, comment *entity.Comment) error
-	AddFavoriteArticle(article *entity.Article, user *entity.User) error
-	RemoveFavorite(article *entity.Article, user *entity.User) error
-	FindFavoriteArticlesByUser(user *entity.User, offset, limit int) ([]*entity.Article, int64, error)
-	CreateTag(tag *entity.Tag) error
-	AddTagToArticle(article *entity.Article, tag *entity.Tag) error
-	AddTagsToArticle(article *entity.Article, tag []*entity.Tag) error
-	RemoveTagFromArticle(article *entity.Article, tag *entity.Tag) error
-	RemoveTagsFromArticle(article *entity.Article, tags []*entity.Tag) error
-	FindTagsByArticle(article *entity.Article) ([]*entity.Tag, error)
-	ListTags() ([]*entity.Tag, error)
+	FindArticleBySlug(ctx context.Context, s string) (*entity.Article, error)
+	FindArticleByAuthorIDAndSlug(ctx context.Context, userID uint64, slug string) (*entity.Article, error)
+	// FindArticleBySlugRedirect find the article which used to be reachable by the old slug s
+	FindArticleBySlugRedirect(ctx context.Context, s string) (*entity.Article, error)
+	// FindTakenSlugs return the slugs equal to base or to base with a suffix, which are used by the
+	// articles other than articleID, trashed ones included, or by their redirects
+	FindTakenSlugs(ctx context.Context, base string, articleID uint64) (map[string]bool, error)
+	// RenameArticle update the article whose slug changed from oldSlug, and redirect oldSlug to it.
+	// The tags of the article are replaced unless tags is nil
+	RenameArticle(ctx context.Context, article *entity.Article, oldSlug string, tags []string) error
+	// CreateArticle insert the article tagged with tags, unknown tags are created on the way
+	CreateArticle(ctx context.Context, article *entity.Article, tags []string) error
+	// UpdateArticle  update article, its tags are replaced unless tags is nil
+	UpdateArticle(ctx context.Context, article *entity.Article, tags []string) error
+	// DeleteArticle move article and its comments to the trash in one transaction, its favorites and tags
+	// are kept so RestoreArticle brings it back whole
+	DeleteArticle(ctx context.Context, article *entity.Article) error
+	// FindDeletedArticleByAuthorIDAndSlug find the trashed article of userID identified by slug
+	FindDeletedArticleByAuthorIDAndSlug(ctx context.Context, userID uint64, slug string) (*entity.Article, error)
+	// ListDeletedArticlesByAuthor list the trashed articles of user, the last deleted first
+	ListDeletedArticlesByAuthor(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.Article, int64, error)
+	// RestoreArticle take article out of the trash, along with the comments trashed with it
+	RestoreArticle(ctx context.Context, article *entity.Article) error
+	// ListArticles list a page of the articles matching every criterion of filter, and count them all
+	ListArticles(ctx context.Context, filter model.ArticleFilter, page model.Page) ([]*entity.Article, int64, error)
+	// ListArticlesByAuthor list the articles written by user
+	ListArticlesByAuthor(ctx context.Context, user *entity.User, page model.Page) ([]*entity.Article, int64, error)
+	FindAuthorByArticle(ctx context.Context, article *entity.Article) (*entity.User, error)
+	// FindAuthorsByArticles load the authors of articles in one query, keyed by user id
+	FindAuthorsByArticles(ctx context.Context, articles []*entity.Article) (map[uint64]*entity.User, error)
+	// FindTagsByArticles load the tags of articles in one query, keyed by article id
+	FindTagsByArticles(ctx context.Context, articles []*entity.Article) (map[uint64][]*entity.Tag, error)
+	// FindFavoritedArticleIDs return the subset of articles favorited by userID, keyed by article id
+	FindFavoritedArticleIDs(ctx context.Context, userID uint64, articles []*entity.Article) (map[uint64]bool, error)
+	// ListFeed list the articles written by the users followed by userID, newest first
+	ListFeed(ctx context.Context, userID uint, page model.Page) ([]*entity.Article, int64, error)
+	AddComment(ctx context.Context, article *entity.Article, comment *entity.Comment) error
+	// FindCommentsByArticle list a page of the top-level comments of article, oldest first, and count them all.
+	// Their replies are loaded by FindRepliesByRoots
+	FindCommentsByArticle(ctx context.Context, article *entity.Article, page model.Page) ([]*entity.Comment, int64, error)
+	// FindRepliesByRoots load the replies, at any depth, to the top-level comments roots in one query,
+	// oldest first
+	FindRepliesByRoots(ctx context.Context, roots []*entity.Comment) ([]*entity.Comment, error)
+	FindCommentByID(ctx context.Context, commentID uint64) (*entity.Comment, error)
+	DeleteComment(ctx context.Context, comment *entity.Comment) error
+	DeleteCommentByCommentID(ctx context.Context, commentID uint64) error
+	// DeleteCommentByArticle move the comment of article to the trash. A comment which has replies stays
+	// in its thread as a placeholder instead: its body becomes model.DeletedCommentBody and it loses its author
+	DeleteCommentByArticle(ctx context.Context, article *entity.Article, comment *entity.Comment) error
+	// FindDeletedCommentByID find the trashed comment commentID
+	FindDeletedCommentByID(ctx context.Context, commentID uint64) (*entity.Comment, error)
+	// ListDeletedCommentsByAuthor list the trashed comments of user on articles which are not trashed,
+	// the last deleted first
+	ListDeletedCommentsByAuthor(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.Comment, int64, error)
+	// RestoreComment take comment out of the trash
+	RestoreComment(ctx context.Context, comment *entity.Comment) error
+	// EditComment keep the current body of comment as a revision, then replace it by body and mark comment as edited
+	EditComment(ctx context.Context, comment *entity.Comment, body string) error
+	// ListCommentRevisions list the former bodies of comment, the oldest first
+	ListCommentRevisions(ctx context.Context, comment *entity.Comment) ([]*entity.CommentRevision, error)
+	// AddFavoriteArticle make article a favorite of user and count it in article.FavoritesCount, which is
+	// reloaded. Favoriting an article twice changes nothing
+	AddFavoriteArticle(ctx context.Context, article *entity.Article, user *entity.User) error
+	// RemoveFavorite take article out of the favorites of user and uncount it from article.FavoritesCount,
+	// which is reloaded. Removing a favorite which does not exist changes nothing
+	RemoveFavorite(ctx context.Context, article *entity.Article, user *entity.User) error
+	// FindFavoriteArticlesByUser list a page of the articles favorited by user, newest first, and count them all
+	FindFavoriteArticlesByUser(ctx context.Context, user *entity.User, page model.Page) ([]*entity.Article, int64, error)
+	CreateTag(ctx context.Context, tag *entity.Tag) error
+	AddTagToArticle(ctx context.Context, article *entity.Article, tag *entity.Tag) error
+	AddTagsToArticle(ctx context.Context, article *entity.Article, tag []*entity.Tag) error
+	// TagArticle add the tags named by tags to article, unknown tags are created and the ones
+	// already on the article are skipped
+	TagArticle(ctx context.Context, article *entity.Article, tags []string) error
+	RemoveTagFromArticle(ctx context.Context, article *entity.Article, tag *entity.Tag) error
+	RemoveTagsFromArticle(ctx context.Context, article *entity.Article, tags []*entity.Tag) error
+	FindTagsByArticle(ctx context.Context, article *entity.Article) ([]*entity.Tag, error)
+	// FindTagByName find the tag named name
+	FindTagByName(ctx context.Context, name string) (*entity.Tag, error)
+	// RestoreTagByName find the tag named name, trashed ones included, taking it out of the trash
+	RestoreTagByName(ctx context.Context, name string) (*entity.Tag, error)
+	// CountArticlesByTags count the articles, trashed ones excluded, of every used tag in one query,
+	// keyed by tag id
+	CountArticlesByTags(ctx context.Context) (map[uint64]int, error)
+	// RenameTag rename tag to name, on every article it is applied to
+	RenameTag(ctx context.Context, tag *entity.Tag, name string) error
+	// MergeTags move the articles tagged with from to into, then delete from
+	MergeTags(ctx context.Context, from, into *entity.Tag) error
+	ListTags(ctx context.Context) ([]*entity.Tag, error)
+	// PurgeDeleted remove for good the comments, articles and tags trashed before before. The comments,
+	// favorites and tag links of the articles and tags removed go with them through ON DELETE CASCADE.
+	// It returns the number of comments, articles and tags removed
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"schema/entity"
+
+	"forum/model"
+)
+
+// ArticleSearch find the articles matching a full-text query, the most relevant first
+type ArticleSearch interface {
+	SearchArticles(ctx context.Context, query string, filter model.ArticleFilter, page model.Page) ([]*SearchHit, int64, error)
+}
+
+// SearchHit is an article matching a search, Snippet quotes the text around the terms searched
+// for, wrapped in <em> tags
+type SearchHit struct {
+	Article *entity.Article
+	Snippet string
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"schema/entity"
+	"strings"
+	"time"
 
+	"forum/model"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 
 	"github.com/rs/zerolog/log"
@@ -20,21 +25,21 @@ func NewArticleRepo(db *sql.DB) *ArticleRepo {
 	return &ArticleRepo{Db: db}
 }
 
-func (a *ArticleRepo) FindArticleBySlug(s string) (*entity.Article, error) {
-	article, err := entity.Articles(entity.ArticleWhere.Slug.EQ(s)).One(context.Background(), a.Db)
+func (a *ArticleRepo) FindArticleBySlug(ctx context.Context, s string) (*entity.Article, error) {
+	article, err := entity.Articles(entity.ArticleWhere.Slug.EQ(s)).One(ctx, executor(ctx, a.Db))
 	if err != nil {
 		return nil, err
 	}
 	return article, nil
 }
 
-func (a *ArticleRepo) FindArticleByAuthorIDAndSlug(userID uint64, slug string) (*entity.Article, error) {
+func (a *ArticleRepo) FindArticleByAuthorIDAndSlug(ctx context.Context, userID uint64, slug string) (*entity.Article, error) {
 	criteriaUserid := entity.ArticleWhere.AuthorID.EQ(null.NewUint64(userID, true))
 	criteriaSlug := entity.ArticleWhere.Slug.EQ(slug)
 
 	article, err := entity.Articles(
 		criteriaSlug,
-		criteriaUserid).One(context.Background(), a.Db)
+		criteriaUserid).One(ctx, executor(ctx, a.Db))
 	if err != nil {
 		log.Error().Err(err).Msg("error while finding article")
 		return nil, err
@@ -42,320 +47,877 @@ func (a *ArticleRepo) FindArticleByAuthorIDAndSlug(userID uint64, slug string) (
 	return article, nil
 }
 
-func (a *ArticleRepo) CreateArticle(article *entity.Article) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// FindArticleBySlugRedirect find the article which used to be reachable by the old slug s
+func (a *ArticleRepo) FindArticleBySlugRedirect(ctx context.Context, s string) (*entity.Article, error) {
+	article, err := entity.Articles(
+		qm.Select("articles.*"),
+		qm.InnerJoin("article_slug_redirects ON article_slug_redirects.article_id = articles.id"),
+		qm.Where("article_slug_redirects.old_slug = ?", s)).One(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
-	err = article.Insert(ctx, a.Db, boil.Infer())
+	return article, nil
+}
+
+// FindTakenSlugs return the slugs equal to base or to base with a suffix, which are used by the
+// articles other than articleID, trashed ones included, or by their redirects
+func (a *ArticleRepo) FindTakenSlugs(ctx context.Context, base string, articleID uint64) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	articles, err := entity.Articles(
+		qm.WithDeleted(),
+		qm.Select("slug"),
+		qm.Where("(slug = ? OR slug LIKE ?)", base, base+"-%"),
+		entity.ArticleWhere.ID.NEQ(articleID)).All(ctx, executor(ctx, a.Db))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find article slugs")
+		return nil, err
+	}
+	for _, article := range articles {
+		taken[article.Slug] = true
+	}
+	redirects, err := entity.ArticleSlugRedirects(
+		qm.Select("old_slug"),
+		qm.Where("(old_slug = ? OR old_slug LIKE ?)", base, base+"-%"),
+		entity.ArticleSlugRedirectWhere.ArticleID.NEQ(articleID)).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to insert article")
-		return err
+		log.Error().Err(err).Msg("failed to find redirected slugs")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	for _, redirect := range redirects {
+		taken[redirect.OldSlug] = true
+	}
+	return taken, nil
 }
 
-// UpdateArticle  update article
-func (a *ArticleRepo) UpdateArticle(article *entity.Article) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// RenameArticle update the article whose slug changed from oldSlug, and redirect oldSlug to it.
+// The tags of the article are replaced unless tags is nil
+func (a *ArticleRepo) RenameArticle(ctx context.Context, article *entity.Article, oldSlug string, tags []string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := article.Update(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to update article")
+			return err
+		}
+		err = setArticleTags(ctx, tx, article, tags)
+		if err != nil {
+			return err
+		}
+		// the article may take back one of its former slugs
+		_, err = entity.ArticleSlugRedirects(
+			entity.ArticleSlugRedirectWhere.OldSlug.IN([]string{oldSlug, article.Slug})).DeleteAll(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete slug redirects")
+			return err
+		}
+		redirect := &entity.ArticleSlugRedirect{OldSlug: oldSlug, ArticleID: article.ID}
+		err = redirect.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to insert slug redirect")
+			return err
+		}
+		return nil
+	})
+}
+
+// CreateArticle insert the article tagged with tags, unknown tags are created on the way
+func (a *ArticleRepo) CreateArticle(ctx context.Context, article *entity.Article, tags []string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to insert article")
+			return err
+		}
+		return setArticleTags(ctx, tx, article, tags)
+	})
+}
+
+// UpdateArticle  update article, its tags are replaced unless tags is nil
+func (a *ArticleRepo) UpdateArticle(ctx context.Context, article *entity.Article, tags []string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := article.Update(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to update article")
+			return err
+		}
+		return setArticleTags(ctx, tx, article, tags)
+	})
+}
+
+// setArticleTags replace the tags of article by tags, nil leaves them untouched
+func setArticleTags(ctx context.Context, tx boil.ContextExecutor, article *entity.Article, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+	t, err := upsertTags(ctx, tx, tags)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
 		return err
 	}
-	defer tx.Rollback()
-	_, err = article.Update(ctx, a.Db, boil.Infer())
+	err = article.SetTags(ctx, tx, false, t...)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to update article")
+		log.Error().Err(err).Msg("failed to set tags")
 		return err
 	}
-	tx.Commit()
 	return nil
 }
 
-func (a *ArticleRepo) DeleteArticle(article *entity.Article) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+// upsertTags find the tags named by names, creating the missing ones and restoring the trashed ones. Names
+// are matched regardless of case as the tags collation does, names differing by case only give one tag
+func upsertTags(ctx context.Context, tx boil.ContextExecutor, names []string) ([]*entity.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
 	}
-	defer tx.Rollback()
-	_, err = article.Delete(ctx, a.Db)
+	existing, err := entity.Tags(qm.WithDeleted(), qm.WhereIn("tag IN ?", stringsToInterfaces(names)...)).All(ctx, tx)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to delete article")
-		return err
+		log.Error().Err(err).Msg("failed to find tags")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	byName := make(map[string]*entity.Tag, len(existing))
+	for _, t := range existing {
+		if t.DeletedAt.Valid {
+			t.DeletedAt = null.Time{}
+			_, err = t.Update(ctx, tx, boil.Whitelist(entity.TagColumns.DeletedAt))
+			if err != nil {
+				log.Error().Err(err).Msg("failed to restore tag")
+				return nil, err
+			}
+		}
+		byName[strings.ToLower(t.Tag.String)] = t
+	}
+	tags := make([]*entity.Tag, 0, len(names))
+	added := make(map[uint64]bool, len(names))
+	for _, name := range names {
+		t, ok := byName[strings.ToLower(name)]
+		if !ok {
+			t = &entity.Tag{Tag: null.StringFrom(name)}
+			err = t.Insert(ctx, tx, boil.Infer())
+			if err != nil {
+				log.Error().Err(err).Msg("failed to create tag")
+				return nil, err
+			}
+			byName[strings.ToLower(name)] = t
+		}
+		if !added[t.ID] {
+			added[t.ID] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
 }
 
-// FindArticles all the articles with pagination
-func (a *ArticleRepo) FindArticles(offset, limit int) ([]*entity.Article, int64, error) {
-	articles, err := entity.Articles(qm.Limit(limit), qm.Offset(offset)).All(context.Background(), a.Db)
+func stringsToInterfaces(s []string) []interface{} {
+	r := make([]interface{}, 0, len(s))
+	for _, v := range s {
+		r = append(r, v)
+	}
+	return r
+}
+
+// DeleteArticle move article and its comments to the trash in one transaction, its favorites and tags
+// are kept so RestoreArticle brings it back whole
+func (a *ArticleRepo) DeleteArticle(ctx context.Context, article *entity.Article) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := article.Delete(ctx, tx, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete article")
+			return err
+		}
+		_, err = entity.Comments(entity.CommentWhere.ArticleID.EQ(null.Uint64From(article.ID))).
+			UpdateAll(ctx, tx, entity.M{entity.CommentColumns.DeletedAt: article.DeletedAt})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete comments of article")
+			return err
+		}
+		return nil
+	})
+}
+
+// FindDeletedArticleByAuthorIDAndSlug find the trashed article of userID identified by slug
+func (a *ArticleRepo) FindDeletedArticleByAuthorIDAndSlug(ctx context.Context, userID uint64, slug string) (*entity.Article, error) {
+	article, err := entity.Articles(
+		qm.WithDeleted(),
+		entity.ArticleWhere.DeletedAt.IsNotNull(),
+		entity.ArticleWhere.AuthorID.EQ(null.Uint64From(userID)),
+		entity.ArticleWhere.Slug.EQ(slug)).One(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to list articles")
-		return nil, 0, err
+		log.Error().Err(err).Msg("failed to find deleted article")
+		return nil, err
 	}
-	return articles, int64(len(articles)), nil
+	return article, nil
 }
 
-func (a *ArticleRepo) ListArticlesByTag(tagStr string, offset, limit int) ([]*entity.Article, int64, error) {
-	criteriaTags := entity.TagWhere.Tag.EQ(null.NewString(tagStr, true))
-	ctx := context.Background()
-	tag, err := entity.Tags(criteriaTags).One(ctx, a.Db)
+// ListDeletedArticlesByAuthor list the trashed articles of user, the last deleted first
+func (a *ArticleRepo) ListDeletedArticlesByAuthor(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.Article, int64, error) {
+	criteria := []qm.QueryMod{
+		qm.WithDeleted(),
+		entity.ArticleWhere.DeletedAt.IsNotNull(),
+		entity.ArticleWhere.AuthorID.EQ(null.Uint64From(user.ID)),
+	}
+	count, err := entity.Articles(criteria...).Count(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to find tag")
+		log.Error().Err(err).Msg("failed to count deleted articles")
 		return nil, 0, err
 	}
-	articles, err := tag.Articles(qm.Limit(limit), qm.Offset(offset)).All(ctx, a.Db)
+	articles, err := entity.Articles(append(criteria,
+		qm.OrderBy("deleted_at DESC, id DESC"),
+		qm.Limit(limit),
+		qm.Offset(offset))...).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to list articles by tag")
+		log.Error().Err(err).Msg("failed to list deleted articles")
 		return nil, 0, err
 	}
-	return articles, int64(len(articles)), nil
+	return articles, count, nil
+}
+
+// RestoreArticle take article out of the trash, along with the comments trashed with it
+func (a *ArticleRepo) RestoreArticle(ctx context.Context, article *entity.Article) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		deletedAt := article.DeletedAt
+		article.DeletedAt = null.Time{}
+		_, err := article.Update(ctx, tx, boil.Whitelist(entity.ArticleColumns.DeletedAt))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore article")
+			return err
+		}
+		_, err = entity.Comments(
+			qm.WithDeleted(),
+			entity.CommentWhere.ArticleID.EQ(null.Uint64From(article.ID)),
+			entity.CommentWhere.DeletedAt.EQ(deletedAt)).
+			UpdateAll(ctx, tx, entity.M{entity.CommentColumns.DeletedAt: nil})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore comments of article")
+			return err
+		}
+		return nil
+	})
 }
 
-func (a *ArticleRepo) ListArticlesByAuthor(user *entity.User, offset, limit int) ([]*entity.Article, int64, error) {
-	articles, err := user.AuthorArticles(qm.Limit(limit), qm.Offset(offset)).All(context.Background(), a.Db)
+// listArticles count the articles matching criteria and list a page of them, the newest first when desc
+func (a *ArticleRepo) listArticles(ctx context.Context, page model.Page, desc bool, criteria ...qm.QueryMod) ([]*entity.Article, int64, error) {
+	count, err := entity.Articles(criteria...).Count(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get articles")
+		log.Error().Err(err).Msg("failed to count articles")
 		return nil, 0, err
 	}
-	return articles, int64(len(articles)), nil
-}
-
-func (a *ArticleRepo) FindAuthorByArticle(article *entity.Article) (*entity.User, error) {
-	return article.Author().One(context.Background(), a.Db)
+	articles, err := entity.Articles(append(criteria, pageMods(page, desc)...)...).All(ctx, executor(ctx, a.Db))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list articles")
+		return nil, 0, err
+	}
+	return inListingOrder(page, articles), count, nil
 }
 
-func (a *ArticleRepo) ListFeed(userID uint, offset, limit int) ([]*entity.Article, int64, error) {
-	// TODO implement me
-	panic("implement me")
+// ListArticles list a page of the articles matching every criterion of filter, and count them all
+func (a *ArticleRepo) ListArticles(ctx context.Context, filter model.ArticleFilter, page model.Page) ([]*entity.Article, int64, error) {
+	return a.listArticles(ctx, page, filter.Sort != model.SortOldest, articleFilterMods(filter)...)
 }
 
-func (a *ArticleRepo) AddComment(article *entity.Article, comment *entity.Comment) error {
-	tx, err := a.Db.BeginTx(context.Background(), nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+// articleFilterMods turn the criteria set in filter into where clauses
+func articleFilterMods(filter model.ArticleFilter) []qm.QueryMod {
+	var mods []qm.QueryMod
+	if filter.Tag != "" {
+		mods = append(mods, qm.Where("articles.id IN (SELECT article_tags.article_id FROM article_tags"+
+			" JOIN tags ON tags.id = article_tags.tag_id WHERE tags.tag = ? AND tags.deleted_at IS NULL)", filter.Tag))
 	}
-	defer tx.Rollback()
-	ctx := context.Background()
-	err = article.AddComments(ctx, a.Db, true, comment)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to add comment")
-		return err
+	if filter.Author != "" {
+		mods = append(mods, qm.Where("articles.author_id IN (SELECT users.id FROM users"+
+			" WHERE users.username = ? AND users.deleted_at IS NULL)", filter.Author))
 	}
-	tx.Commit()
-	return nil
+	if filter.Favorited != "" {
+		mods = append(mods, qm.Where("articles.id IN (SELECT favorites.article_id FROM favorites"+
+			" JOIN users ON users.id = favorites.user_id WHERE users.username = ? AND users.deleted_at IS NULL)", filter.Favorited))
+	}
+	if !filter.Since.IsZero() {
+		mods = append(mods, entity.ArticleWhere.CreatedAt.GTE(null.TimeFrom(filter.Since)))
+	}
+	if !filter.Until.IsZero() {
+		mods = append(mods, entity.ArticleWhere.CreatedAt.LT(null.TimeFrom(filter.Until)))
+	}
+	return mods
+}
+
+// ListArticlesByAuthor list the articles written by user
+func (a *ArticleRepo) ListArticlesByAuthor(ctx context.Context, user *entity.User, page model.Page) ([]*entity.Article, int64, error) {
+	return a.listArticles(ctx, page, true, entity.ArticleWhere.AuthorID.EQ(null.Uint64From(user.ID)))
 }
 
-func (a *ArticleRepo) FindCommentsByArticle(article *entity.Article, offset int, limit int) ([]*entity.Comment, error) {
-	return article.Comments(qm.Limit(limit), qm.Offset(offset)).All(context.Background(), a.Db)
+func (a *ArticleRepo) FindAuthorByArticle(ctx context.Context, article *entity.Article) (*entity.User, error) {
+	return article.Author().One(ctx, executor(ctx, a.Db))
 }
 
-func (a *ArticleRepo) FindCommentByID(commentID uint64) (*entity.Comment, error) {
-	ctx := context.Background()
-	comment, err := entity.Comments(entity.CommentWhere.ID.EQ(commentID)).One(ctx, a.Db)
+// FindAuthorsByArticles load the authors of articles in one query, keyed by user id
+func (a *ArticleRepo) FindAuthorsByArticles(ctx context.Context, articles []*entity.Article) (map[uint64]*entity.User, error) {
+	authors := make(map[uint64]*entity.User)
+	ids := make([]interface{}, 0, len(articles))
+	for _, article := range articles {
+		if article.AuthorID.Valid {
+			ids = append(ids, article.AuthorID.Uint64)
+		}
+	}
+	if len(ids) == 0 {
+		return authors, nil
+	}
+	users, err := entity.Users(qm.WhereIn("id IN ?", ids...)).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to find comment")
+		log.Error().Err(err).Msg("failed to find authors")
 		return nil, err
 	}
-	return comment, nil
+	for _, u := range users {
+		authors[u.ID] = u
+	}
+	return authors, nil
 }
 
-func (a *ArticleRepo) DeleteComment(comment *entity.Comment) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+// FindTagsByArticles load the tags of articles in one query, keyed by article id
+func (a *ArticleRepo) FindTagsByArticles(ctx context.Context, articles []*entity.Article) (map[uint64][]*entity.Tag, error) {
+	tags := make(map[uint64][]*entity.Tag)
+	if len(articles) == 0 {
+		return tags, nil
+	}
+	var rows []*struct {
+		ArticleID  uint64 `boil:"article_id"`
+		entity.Tag `boil:",bind"`
+	}
+	err := entity.NewQuery(
+		qm.Select("article_tags.article_id AS article_id", "tags.*"),
+		qm.From("tags"),
+		qm.InnerJoin("article_tags ON tags.id = article_tags.tag_id"),
+		qm.WhereIn("article_tags.article_id IN ?", articleIDs(articles)...),
+		qm.Where("tags.deleted_at IS NULL"),
+		qm.OrderBy("tags.tag"),
+	).Bind(ctx, executor(ctx, a.Db), &rows)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find tags of articles")
+		return nil, err
 	}
-	defer tx.Rollback()
-	_, err = comment.Delete(ctx, a.Db)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to delete comment")
-		return err
+	for _, row := range rows {
+		tag := row.Tag
+		tags[row.ArticleID] = append(tags[row.ArticleID], &tag)
 	}
-	tx.Commit()
-	return nil
+	return tags, nil
 }
 
-func (a *ArticleRepo) DeleteCommentByCommentID(commentID uint64) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+// FindFavoritedArticleIDs return the subset of articles favorited by userID, keyed by article id
+func (a *ArticleRepo) FindFavoritedArticleIDs(ctx context.Context, userID uint64, articles []*entity.Article) (map[uint64]bool, error) {
+	favorited := make(map[uint64]bool)
+	if len(articles) == 0 {
+		return favorited, nil
 	}
-	defer tx.Rollback()
-	_, err = entity.Comments(
-		entity.CommentWhere.ID.EQ(commentID)).DeleteAll(ctx, a.Db)
+	var rows []*struct {
+		ArticleID uint64 `boil:"article_id"`
+	}
+	err := entity.NewQuery(
+		qm.Select("article_id"),
+		qm.From("favorites"),
+		qm.Where("user_id = ?", userID),
+		qm.WhereIn("article_id IN ?", articleIDs(articles)...),
+	).Bind(ctx, executor(ctx, a.Db), &rows)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to delete comment")
-		return err
+		log.Error().Err(err).Msg("failed to find favorited articles")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	for _, row := range rows {
+		favorited[row.ArticleID] = true
+	}
+	return favorited, nil
 }
 
-func (a *ArticleRepo) DeleteCommentByArticle(article *entity.Article, comment *entity.Comment) error {
-	tx, err := a.Db.BeginTx(context.Background(), nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+func articleIDs(articles []*entity.Article) []interface{} {
+	ids := make([]interface{}, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
 	}
-	defer tx.Rollback()
-	ctx := context.Background()
-	err = article.RemoveComments(ctx, a.Db, comment)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to add comment")
-		return err
-	}
-	tx.Commit()
-	return nil
+	return ids
 }
 
-func (a *ArticleRepo) AddFavoriteArticle(article *entity.Article, user *entity.User) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// ListFeed list the articles written by the users followed by userID, newest first
+func (a *ArticleRepo) ListFeed(ctx context.Context, userID uint, page model.Page) ([]*entity.Article, int64, error) {
+	return a.listArticles(ctx, page, true,
+		qm.Where("author_id IN (SELECT following_id FROM follows WHERE follower_id = ?)", userID))
+}
+
+func (a *ArticleRepo) AddComment(ctx context.Context, article *entity.Article, comment *entity.Comment) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.AddComments(ctx, tx, true, comment)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add comment")
+			return err
+		}
+		return nil
+	})
+}
+
+// FindCommentsByArticle list a page of the top-level comments of article, oldest first, and count them all.
+// Their replies are loaded by FindRepliesByRoots
+func (a *ArticleRepo) FindCommentsByArticle(ctx context.Context, article *entity.Article, page model.Page) ([]*entity.Comment, int64, error) {
+	count, err := article.Comments(entity.CommentWhere.ParentID.IsNull()).Count(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to count comments")
+		return nil, 0, err
 	}
-	defer tx.Rollback()
-	err = article.AddUsers(ctx, a.Db, false, user)
+	comments, err := article.Comments(append([]qm.QueryMod{entity.CommentWhere.ParentID.IsNull()},
+		pageMods(page, false)...)...).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to add favorite")
-		return err
+		log.Error().Err(err).Msg("failed to list comments")
+		return nil, 0, err
 	}
-	tx.Commit()
-	return nil
+	return inListingOrder(page, comments), count, nil
 }
 
-func (a *ArticleRepo) RemoveFavorite(article *entity.Article, user *entity.User) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// FindRepliesByRoots load the replies, at any depth, to the top-level comments roots in one query,
+// oldest first
+func (a *ArticleRepo) FindRepliesByRoots(ctx context.Context, roots []*entity.Comment) ([]*entity.Comment, error) {
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	ids := make([]interface{}, 0, len(roots))
+	for _, root := range roots {
+		ids = append(ids, root.ID)
+	}
+	replies, err := entity.Comments(
+		qm.WhereIn("root_id IN ?", ids...),
+		qm.OrderBy("created_at ASC, id ASC")).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to find replies")
+		return nil, err
 	}
-	defer tx.Rollback()
-	err = article.RemoveUsers(ctx, a.Db, user)
+	return replies, nil
+}
+
+func (a *ArticleRepo) FindCommentByID(ctx context.Context, commentID uint64) (*entity.Comment, error) {
+	comment, err := entity.Comments(entity.CommentWhere.ID.EQ(commentID)).One(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to remove favorite")
-		return err
+		log.Error().Err(err).Msg("failed to find comment")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	return comment, nil
+}
+
+func (a *ArticleRepo) DeleteComment(ctx context.Context, comment *entity.Comment) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := comment.Delete(ctx, tx, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete comment")
+			return err
+		}
+		return nil
+	})
 }
 
-func (a *ArticleRepo) FindFavoriteArticlesByUser(user *entity.User, offset, limit int) ([]*entity.Article, int64, error) {
-	articles, err := user.Articles(qm.Offset(offset), qm.Limit(limit)).All(context.Background(), a.Db)
+func (a *ArticleRepo) DeleteCommentByCommentID(ctx context.Context, commentID uint64) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := entity.Comments(
+			entity.CommentWhere.ID.EQ(commentID)).DeleteAll(ctx, tx, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete comment")
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteCommentByArticle move the comment of article to the trash. A comment which has replies stays
+// in its thread as a placeholder instead: its body becomes model.DeletedCommentBody and it loses its author
+func (a *ArticleRepo) DeleteCommentByArticle(ctx context.Context, article *entity.Article, comment *entity.Comment) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		replied, err := entity.Comments(entity.CommentWhere.ParentID.EQ(null.Uint64From(comment.ID))).Exists(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to find replies of comment")
+			return err
+		}
+		criteria := []qm.QueryMod{
+			entity.CommentWhere.ID.EQ(comment.ID),
+			entity.CommentWhere.ArticleID.EQ(null.Uint64From(article.ID)),
+		}
+		if replied {
+			_, err = entity.Comments(criteria...).UpdateAll(ctx, tx, entity.M{
+				entity.CommentColumns.Body:      model.DeletedCommentBody,
+				entity.CommentColumns.UserID:    nil,
+				entity.CommentColumns.UpdatedAt: time.Now(),
+			})
+		} else {
+			_, err = entity.Comments(criteria...).DeleteAll(ctx, tx, false)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete comment")
+			return err
+		}
+		return nil
+	})
+}
+
+// FindDeletedCommentByID find the trashed comment commentID
+func (a *ArticleRepo) FindDeletedCommentByID(ctx context.Context, commentID uint64) (*entity.Comment, error) {
+	comment, err := entity.Comments(
+		qm.WithDeleted(),
+		entity.CommentWhere.DeletedAt.IsNotNull(),
+		entity.CommentWhere.ID.EQ(commentID)).One(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to find articles")
-		return nil, 0, err
+		log.Error().Err(err).Msg("failed to find deleted comment")
+		return nil, err
 	}
-	return articles, int64(len(articles)), nil
+	return comment, nil
 }
 
-func (a *ArticleRepo) CreateTag(tag *entity.Tag) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// ListDeletedCommentsByAuthor list the trashed comments of user on articles which are not trashed,
+// the last deleted first
+func (a *ArticleRepo) ListDeletedCommentsByAuthor(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.Comment, int64, error) {
+	criteria := []qm.QueryMod{
+		qm.WithDeleted(),
+		entity.CommentWhere.DeletedAt.IsNotNull(),
+		entity.CommentWhere.UserID.EQ(null.Uint64From(user.ID)),
+		qm.Where("article_id IN (SELECT id FROM articles WHERE deleted_at IS NULL)"),
+	}
+	count, err := entity.Comments(criteria...).Count(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to count deleted comments")
+		return nil, 0, err
 	}
-	defer tx.Rollback()
-	err = tag.Insert(ctx, a.Db, boil.Infer())
+	comments, err := entity.Comments(append(criteria,
+		qm.OrderBy("deleted_at DESC, id DESC"),
+		qm.Limit(limit),
+		qm.Offset(offset))...).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create tag")
-		return err
+		log.Error().Err(err).Msg("failed to list deleted comments")
+		return nil, 0, err
 	}
-	tx.Commit()
-	return nil
+	return comments, count, nil
 }
 
-func (a *ArticleRepo) AddTagToArticle(article *entity.Article, tag *entity.Tag) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
-	}
-	defer tx.Rollback()
-	err = article.AddTags(ctx, a.Db, false, tag)
+// RestoreComment take comment out of the trash
+func (a *ArticleRepo) RestoreComment(ctx context.Context, comment *entity.Comment) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		comment.DeletedAt = null.Time{}
+		_, err := comment.Update(ctx, tx, boil.Whitelist(entity.CommentColumns.DeletedAt))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore comment")
+			return err
+		}
+		return nil
+	})
+}
+
+// EditComment keep the current body of comment as a revision, then replace it by body and mark comment as edited
+func (a *ArticleRepo) EditComment(ctx context.Context, comment *entity.Comment, body string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := comment.AddCommentRevisions(ctx, tx, true, &entity.CommentRevision{Body: comment.Body})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add comment revision")
+			return err
+		}
+		comment.Body = null.StringFrom(body)
+		comment.EditedAt = null.TimeFrom(time.Now())
+		_, err = comment.Update(ctx, tx, boil.Whitelist(
+			entity.CommentColumns.Body,
+			entity.CommentColumns.EditedAt,
+			entity.CommentColumns.UpdatedAt))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to edit comment")
+			return err
+		}
+		return nil
+	})
+}
+
+// ListCommentRevisions list the former bodies of comment, the oldest first
+func (a *ArticleRepo) ListCommentRevisions(ctx context.Context, comment *entity.Comment) ([]*entity.CommentRevision, error) {
+	revisions, err := comment.CommentRevisions(qm.OrderBy("created_at ASC, id ASC")).All(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to add tag")
-		return err
+		log.Error().Err(err).Msg("failed to list comment revisions")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	return revisions, nil
+}
+
+// AddFavoriteArticle make article a favorite of user and count it in article.FavoritesCount, which is
+// reloaded. Favoriting an article twice changes nothing
+func (a *ArticleRepo) AddFavoriteArticle(ctx context.Context, article *entity.Article, user *entity.User) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		res, err := queries.Raw("INSERT IGNORE INTO favorites (article_id, user_id) VALUES (?, ?)",
+			article.ID, user.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add favorite")
+			return err
+		}
+		return countFavorites(ctx, tx, article, res, 1)
+	})
+}
+
+// RemoveFavorite take article out of the favorites of user and uncount it from article.FavoritesCount,
+// which is reloaded. Removing a favorite which does not exist changes nothing
+func (a *ArticleRepo) RemoveFavorite(ctx context.Context, article *entity.Article, user *entity.User) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		res, err := queries.Raw("DELETE FROM favorites WHERE article_id = ? AND user_id = ?",
+			article.ID, user.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to remove favorite")
+			return err
+		}
+		return countFavorites(ctx, tx, article, res, -1)
+	})
 }
 
-func (a *ArticleRepo) AddTagsToArticle(article *entity.Article, tag []*entity.Tag) error {
-	ctx := context.Background()
-	tx, err := a.Db.BeginTx(ctx, nil)
+// countFavorites move the favorites counter of article by delta when res changed its favorites, then
+// reload article to pick the counter up
+func countFavorites(ctx context.Context, tx *sql.Tx, article *entity.Article, res sql.Result, delta int) error {
+	changed, err := res.RowsAffected()
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
 		return err
 	}
-	defer tx.Rollback()
-	err = article.AddTags(ctx, a.Db, false, tag...)
+	if changed > 0 {
+		_, err = queries.Raw("UPDATE articles SET favorites_count = favorites_count + ? WHERE id = ?",
+			delta, article.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to count favorites")
+			return err
+		}
+	}
+	err = article.Reload(ctx, tx)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to add tag")
+		log.Error().Err(err).Msg("failed to reload article")
 		return err
 	}
-	tx.Commit()
 	return nil
 }
 
-func (a *ArticleRepo) RemoveTagFromArticle(article *entity.Article, tag *entity.Tag) error {
-	tx, err := a.Db.BeginTx(context.Background(), nil)
+// FindFavoriteArticlesByUser list a page of the articles favorited by user, newest first, and count them all
+func (a *ArticleRepo) FindFavoriteArticlesByUser(ctx context.Context, user *entity.User, page model.Page) ([]*entity.Article, int64, error) {
+	return a.listArticles(ctx, page, true,
+		qm.Where("id IN (SELECT article_id FROM favorites WHERE user_id = ?)", user.ID))
+}
+
+func (a *ArticleRepo) CreateTag(ctx context.Context, tag *entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := tag.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create tag")
+			return err
+		}
+		return nil
+	})
+}
+
+func (a *ArticleRepo) AddTagToArticle(ctx context.Context, article *entity.Article, tag *entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.AddTags(ctx, tx, false, tag)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add tag")
+			return err
+		}
+		return nil
+	})
+}
+
+func (a *ArticleRepo) AddTagsToArticle(ctx context.Context, article *entity.Article, tag []*entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.AddTags(ctx, tx, false, tag...)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add tag")
+			return err
+		}
+		return nil
+	})
+}
+
+// TagArticle add the tags named by tags to article, unknown tags are created and the ones
+// already on the article are skipped
+func (a *ArticleRepo) TagArticle(ctx context.Context, article *entity.Article, tags []string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		t, err := upsertTags(ctx, tx, tags)
+		if err != nil {
+			return err
+		}
+		current, err := article.Tags().All(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to find tags of article")
+			return err
+		}
+		tagged := make(map[uint64]bool, len(current))
+		for _, c := range current {
+			tagged[c.ID] = true
+		}
+		var missing []*entity.Tag
+		for _, v := range t {
+			if !tagged[v.ID] {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) > 0 {
+			err = article.AddTags(ctx, tx, false, missing...)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to add tag")
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (a *ArticleRepo) RemoveTagFromArticle(ctx context.Context, article *entity.Article, tag *entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.RemoveTags(ctx, tx, tag)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to remove tag")
+			return err
+		}
+		return nil
+	})
+}
+
+func (a *ArticleRepo) RemoveTagsFromArticle(ctx context.Context, article *entity.Article, tags []*entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := article.RemoveTags(ctx, tx, tags...)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to remove tag")
+			return err
+		}
+		return nil
+	})
+}
+
+func (a *ArticleRepo) FindTagsByArticle(ctx context.Context, article *entity.Article) ([]*entity.Tag, error) {
+	return article.Tags().All(ctx, executor(ctx, a.Db))
+}
+
+// FindTagByName find the tag named name
+func (a *ArticleRepo) FindTagByName(ctx context.Context, name string) (*entity.Tag, error) {
+	tag, err := entity.Tags(entity.TagWhere.Tag.EQ(null.StringFrom(name))).One(ctx, executor(ctx, a.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to find tag")
+		return nil, err
 	}
-	defer tx.Rollback()
-	err = article.RemoveTags(context.Background(), a.Db, tag)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to remove tag")
+	return tag, nil
+}
+
+// RestoreTagByName find the tag named name, trashed ones included, taking it out of the trash
+func (a *ArticleRepo) RestoreTagByName(ctx context.Context, name string) (*entity.Tag, error) {
+	var tag *entity.Tag
+	err := inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		tag, err = entity.Tags(qm.WithDeleted(), entity.TagWhere.Tag.EQ(null.StringFrom(name))).One(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to find tag")
+			return err
+		}
+		if !tag.DeletedAt.Valid {
+			return nil
+		}
+		tag.DeletedAt = null.Time{}
+		_, err = tag.Update(ctx, tx, boil.Whitelist(entity.TagColumns.DeletedAt))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore tag")
+		}
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	return tag, nil
 }
 
-func (a *ArticleRepo) RemoveTagsFromArticle(article *entity.Article, tags []*entity.Tag) error {
-	tx, err := a.Db.BeginTx(context.Background(), nil)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+// CountArticlesByTags count the articles, trashed ones excluded, of every used tag in one query,
+// keyed by tag id
+func (a *ArticleRepo) CountArticlesByTags(ctx context.Context) (map[uint64]int, error) {
+	counts := make(map[uint64]int)
+	var rows []*struct {
+		TagID uint64 `boil:"tag_id"`
+		Count int    `boil:"articles_count"`
+	}
+	err := entity.NewQuery(
+		qm.Select("article_tags.tag_id AS tag_id", "COUNT(*) AS articles_count"),
+		qm.From("article_tags"),
+		qm.InnerJoin("articles ON articles.id = article_tags.article_id"),
+		qm.Where("articles.deleted_at IS NULL"),
+		qm.GroupBy("article_tags.tag_id"),
+	).Bind(ctx, executor(ctx, a.Db), &rows)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count articles by tags")
+		return nil, err
 	}
-	defer tx.Rollback()
-	err = article.RemoveTags(context.Background(), a.Db, tags...)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to remove tag")
-		return err
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
 	}
-	tx.Commit()
-	return nil
+	return counts, nil
+}
+
+// RenameTag rename tag to name, on every article it is applied to
+func (a *ArticleRepo) RenameTag(ctx context.Context, tag *entity.Tag, name string) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		tag.Tag = null.StringFrom(name)
+		_, err := tag.Update(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to rename tag")
+			return err
+		}
+		return nil
+	})
 }
 
-func (a *ArticleRepo) FindTagsByArticle(article *entity.Article) ([]*entity.Tag, error) {
-	return article.Tags().All(context.Background(), a.Db)
+// MergeTags move the articles tagged with from to into, then delete from
+func (a *ArticleRepo) MergeTags(ctx context.Context, from, into *entity.Tag) error {
+	return inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		// articles already tagged with both keep their link to into
+		_, err := queries.Raw(
+			"INSERT IGNORE INTO article_tags (tag_id, article_id) SELECT ?, article_id FROM article_tags WHERE tag_id = ?",
+			into.ID, from.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to move tagged articles")
+			return err
+		}
+		_, err = queries.Raw("DELETE FROM article_tags WHERE tag_id = ?", from.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to untag articles")
+			return err
+		}
+		_, err = from.Delete(ctx, tx, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete tag")
+			return err
+		}
+		return nil
+	})
 }
 
-func (a *ArticleRepo) ListTags() ([]*entity.Tag, error) {
-	tags, err := entity.Tags().All(context.Background(), a.Db)
+func (a *ArticleRepo) ListTags(ctx context.Context) ([]*entity.Tag, error) {
+	tags, err := entity.Tags().All(ctx, executor(ctx, a.Db))
 	if err != nil {
 		log.Error().Err(err).Msg("failed to find tags")
 		return nil, err
 	}
 	return tags, nil
 }
+
+// PurgeDeleted remove for good the comments, articles and tags trashed before before. The comments,
+// favorites and tag links of the articles and tags removed go with them through ON DELETE CASCADE.
+// It returns the number of comments, articles and tags removed
+func (a *ArticleRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := inTx(ctx, a.Db, func(ctx context.Context, tx *sql.Tx) error {
+		purged = 0
+		n, err := entity.Comments(
+			qm.WithDeleted(), entity.CommentWhere.DeletedAt.LT(null.TimeFrom(before))).DeleteAll(ctx, tx, true)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge comments")
+			return err
+		}
+		purged += n
+		n, err = entity.Articles(
+			qm.WithDeleted(), entity.ArticleWhere.DeletedAt.LT(null.TimeFrom(before))).DeleteAll(ctx, tx, true)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge articles")
+			return err
+		}
+		purged += n
+		n, err = entity.Tags(
+			qm.WithDeleted(), entity.TagWhere.DeletedAt.LT(null.TimeFrom(before))).DeleteAll(ctx, tx, true)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge tags")
+			return err
+		}
+		purged += n
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -1,12 +1,16 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"regexp"
 	"schema/entity"
 	"testing"
 	"time"
 
+	"forum/model"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,23 +26,25 @@ func TestArticle_Create(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.CreateArticle(articleFoo)
+		err = repo.CreateArticle(context.Background(), articleFoo, nil)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when create article success", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`favorites_count` FROM `articles` WHERE `id`=?")).
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 0))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.CreateArticle(articleFoo)
+		err = repo.CreateArticle(context.Background(), articleFoo, nil)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.CreateArticle(articleFoo)
+		err = repo.CreateArticle(context.Background(), articleFoo, nil)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -53,7 +59,7 @@ func TestArticle_Update(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("UPDATE")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.UpdateArticle(articleFoo)
+		err = repo.UpdateArticle(context.Background(), articleFoo, nil)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -62,14 +68,123 @@ func TestArticle_Update(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("UPDATE")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.UpdateArticle(articleFoo)
+		err = repo.UpdateArticle(context.Background(), articleFoo, nil)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.UpdateArticle(articleFoo)
+		err = repo.UpdateArticle(context.Background(), articleFoo, nil)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_CreateWithTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when tags are created and linked", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`favorites_count` FROM `articles` WHERE `id`=?")).
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tag` IN (?,?))")).
+			WithArgs("foo", "bar").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "foo"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tags`")).WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("(?i)delete from `article_tags`").WithArgs(uint64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("(?i)insert into `article_tags`").WithArgs(uint64(1), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("(?i)insert into `article_tags`").WithArgs(uint64(1), uint64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.CreateArticle(context.Background(), &entity.Article{Title: "foo", Slug: "foo"}, []string{"foo", "bar"})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("trashed tags are restored", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`favorites_count` FROM `articles` WHERE `id`=?")).
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tag` IN (?))")).
+			WithArgs("foo").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag", "deleted_at"}).AddRow(1, "foo", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `tags` SET `deleted_at`=?")).WithArgs(nil, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("(?i)delete from `article_tags`").WithArgs(uint64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("(?i)insert into `article_tags`").WithArgs(uint64(1), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.CreateArticle(context.Background(), &entity.Article{Title: "foo", Slug: "foo"}, []string{"foo"})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("tags differing by case only reuse the existing tag", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`favorites_count` FROM `articles` WHERE `id`=?")).
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tag` IN (?,?))")).
+			WithArgs("Go", "GO").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "go"))
+		mock.ExpectExec("(?i)delete from `article_tags`").WithArgs(uint64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("(?i)insert into `article_tags`").WithArgs(uint64(1), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.CreateArticle(context.Background(), &entity.Article{Title: "foo", Slug: "foo"}, []string{"Go", "GO"})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when a tag cannot be created", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`favorites_count` FROM `articles` WHERE `id`=?")).
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags`")).WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tags`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.CreateArticle(context.Background(), &entity.Article{Title: "foo", Slug: "foo"}, []string{"foo"})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when tags cannot be linked", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("(?i)delete from `article_tags`").WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.UpdateArticle(context.Background(), &entity.Article{ID: 1, Title: "foo", Slug: "foo"}, []string{})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_TagArticle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when only the missing tags are linked", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tag` IN (?,?))")).
+			WithArgs("foo", "bar").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "foo").AddRow(2, "bar"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` INNER JOIN `article_tags`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "foo"))
+		mock.ExpectExec("(?i)insert into `article_tags`").WithArgs(uint64(1), uint64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.TagArticle(context.Background(), &entity.Article{ID: 1}, []string{"foo", "bar"})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when tags cannot be found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.TagArticle(context.Background(), &entity.Article{ID: 1}, []string{"foo"})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -81,26 +196,38 @@ func TestArtcile_DeleteArticle(t *testing.T) {
 	defer db.Close()
 	t.Run("transaction rollback when delete article failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.DeleteArticle(articleFoo)
+		err = repo.DeleteArticle(context.Background(), articleFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when delete article success", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`article_id` = ?) AND (`comments`.`deleted_at` is null)")).
+			WithArgs(sqlmock.AnyArg(), articleFoo.ID).WillReturnResult(sqlmock.NewResult(0, 2))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.DeleteArticle(articleFoo)
+		err = repo.DeleteArticle(context.Background(), articleFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+	t.Run("transaction rollback when delete comments of article failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.DeleteArticle(context.Background(), articleFoo)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.DeleteArticle(articleFoo)
+		err = repo.DeleteArticle(context.Background(), articleFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -115,7 +242,7 @@ func TestArticle_AddComment(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.AddComment(articleFoo, commentFoo)
+		err = repo.AddComment(context.Background(), articleFoo, commentFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -124,14 +251,14 @@ func TestArticle_AddComment(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.AddComment(articleFoo, commentFoo)
+		err = repo.AddComment(context.Background(), articleFoo, commentFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.AddComment(articleFoo, commentFoo)
+		err = repo.AddComment(context.Background(), articleFoo, commentFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -143,26 +270,26 @@ func TestArticle_DeleteComment(t *testing.T) {
 	defer db.Close()
 	t.Run("transaction rollback when remove comment failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.DeleteComment(commentFoo)
+		err = repo.DeleteComment(context.Background(), commentFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when remove comment success", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.DeleteComment(commentFoo)
+		err = repo.DeleteComment(context.Background(), commentFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.DeleteComment(commentFoo)
+		err = repo.DeleteComment(context.Background(), commentFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -174,26 +301,26 @@ func TestArticle_DeleteCommentByArticle(t *testing.T) {
 	defer db.Close()
 	t.Run("when delete comment by comment id success", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when delete comment by comment id failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when delete comment by comment id transaction failed", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -208,7 +335,7 @@ func TestArticle_CreateTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.CreateTag(tagFoo)
+		err = repo.CreateTag(context.Background(), tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -217,14 +344,14 @@ func TestArticle_CreateTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.CreateTag(tagFoo)
+		err = repo.CreateTag(context.Background(), tagFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.CreateTag(tagFoo)
+		err = repo.CreateTag(context.Background(), tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -239,7 +366,7 @@ func TestArticle_AddTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.AddTagToArticle(articleFoo, tagFoo)
+		err = repo.AddTagToArticle(context.Background(), articleFoo, tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -248,21 +375,21 @@ func TestArticle_AddTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.AddTagToArticle(articleFoo, tagFoo)
+		err = repo.AddTagToArticle(context.Background(), articleFoo, tagFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.AddTagToArticle(articleFoo, tagFoo)
+		err = repo.AddTagToArticle(context.Background(), articleFoo, tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("find tag by article", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "tag1"))
 		repo := NewArticleRepo(db)
-		tags, err := repo.FindTagsByArticle(articleFoo)
+		tags, err := repo.FindTagsByArticle(context.Background(), articleFoo)
 		assert.NoError(t, err)
 		assert.Equal(t, 1, len(tags))
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -278,7 +405,7 @@ func TestArticle_AddTags(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.AddTagsToArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.AddTagsToArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -288,14 +415,14 @@ func TestArticle_AddTags(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.AddTagsToArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.AddTagsToArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.AddTagsToArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.AddTagsToArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -310,7 +437,7 @@ func TestArticle_RemoveTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagFromArticle(articleFoo, tagFoo)
+		err = repo.RemoveTagFromArticle(context.Background(), articleFoo, tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -319,14 +446,14 @@ func TestArticle_RemoveTag(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagFromArticle(articleFoo, tagFoo)
+		err = repo.RemoveTagFromArticle(context.Background(), articleFoo, tagFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagFromArticle(articleFoo, tagFoo)
+		err = repo.RemoveTagFromArticle(context.Background(), articleFoo, tagFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -341,7 +468,7 @@ func TestArticle_RemoveTags(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagsFromArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.RemoveTagsFromArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -350,14 +477,14 @@ func TestArticle_RemoveTags(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagsFromArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.RemoveTagsFromArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.RemoveTagsFromArticle(articleFoo, []*entity.Tag{tagFoo, tagBar})
+		err = repo.RemoveTagsFromArticle(context.Background(), articleFoo, []*entity.Tag{tagFoo, tagBar})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -370,7 +497,7 @@ func TestArticle_ListTags(t *testing.T) {
 	t.Run("when list tags return OK", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "foo").AddRow(2, "bar"))
 		repo := NewArticleRepo(db)
-		tags, err := repo.ListTags()
+		tags, err := repo.ListTags(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(tags))
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -378,7 +505,7 @@ func TestArticle_ListTags(t *testing.T) {
 	t.Run("when list tags return error", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		tags, err := repo.ListTags()
+		tags, err := repo.ListTags(context.Background())
 		assert.Errorf(t, err, "some error")
 		assert.Equal(t, 0, len(tags))
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -389,28 +516,49 @@ func TestArticle_AddFavorite(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
+	insert := regexp.QuoteMeta("INSERT IGNORE INTO favorites (article_id, user_id) VALUES (?, ?)")
+	reload := regexp.QuoteMeta("select * from `articles` where `id`=? and `deleted_at` is null")
 	t.Run("transaction rollback when add favorite failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(insert).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.AddFavoriteArticle(articleFoo, userFoo)
+		err = repo.AddFavoriteArticle(context.Background(), articleFoo, userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when add favorite success", func(t *testing.T) {
+		article := &entity.Article{ID: 1}
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(insert).WithArgs(uint64(1), userFoo.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE articles SET favorites_count = favorites_count + ? WHERE id = ?")).
+			WithArgs(1, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reload).WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 4))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.AddFavoriteArticle(articleFoo, userFoo)
+		err = repo.AddFavoriteArticle(context.Background(), article, userFoo)
 		assert.NoError(t, err)
+		assert.Equal(t, uint(4), article.FavoritesCount)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("favoriting again leaves the counter alone", func(t *testing.T) {
+		article := &entity.Article{ID: 1}
+		mock.ExpectBegin()
+		mock.ExpectExec(insert).WithArgs(uint64(1), userFoo.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(reload).WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 4))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.AddFavoriteArticle(context.Background(), article, userFoo)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), article.FavoritesCount)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.AddFavoriteArticle(articleFoo, userFoo)
+		err = repo.AddFavoriteArticle(context.Background(), articleFoo, userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -420,28 +568,46 @@ func TestArticle_RemoveFavorite(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
+	remove := regexp.QuoteMeta("DELETE FROM favorites WHERE article_id = ? AND user_id = ?")
+	reload := regexp.QuoteMeta("select * from `articles` where `id`=? and `deleted_at` is null")
 	t.Run("transaction rollback when remove favorite failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(remove).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveFavorite(articleFoo, userFoo)
+		err = repo.RemoveFavorite(context.Background(), articleFoo, userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when remove favorite success", func(t *testing.T) {
+		article := &entity.Article{ID: 1, FavoritesCount: 4}
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("delete from")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(remove).WithArgs(uint64(1), userFoo.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE articles SET favorites_count = favorites_count + ? WHERE id = ?")).
+			WithArgs(-1, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(reload).WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "favorites_count"}).AddRow(1, 3))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err = repo.RemoveFavorite(articleFoo, userFoo)
+		err = repo.RemoveFavorite(context.Background(), article, userFoo)
 		assert.NoError(t, err)
+		assert.Equal(t, uint(3), article.FavoritesCount)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when count favorites failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(remove).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE articles")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.RemoveFavorite(context.Background(), &entity.Article{ID: 1}, userFoo)
+		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err = repo.RemoveFavorite(articleFoo, userFoo)
+		err = repo.RemoveFavorite(context.Background(), articleFoo, userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -456,7 +622,7 @@ func TestArticle_FindArticleBySlug(t *testing.T) {
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1)
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(rows)
 		repo := NewArticleRepo(db)
-		article, err := repo.FindArticleBySlug(articleFoo.Slug)
+		article, err := repo.FindArticleBySlug(context.Background(), articleFoo.Slug)
 		assert.NoError(t, err)
 		assert.Equal(t, articleFoo.ID, article.ID)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -464,7 +630,7 @@ func TestArticle_FindArticleBySlug(t *testing.T) {
 	t.Run("when find article by slug failed", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, err = repo.FindArticleBySlug(articleFoo.Slug)
+		_, err = repo.FindArticleBySlug(context.Background(), articleFoo.Slug)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -479,7 +645,7 @@ func TestArticle_FindArticleByAuthorIDAndSlug(t *testing.T) {
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1)
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(rows)
 		repo := NewArticleRepo(db)
-		article, err := repo.FindArticleByAuthorIDAndSlug(1, articleFoo.Slug)
+		article, err := repo.FindArticleByAuthorIDAndSlug(context.Background(), 1, articleFoo.Slug)
 		assert.NoError(t, err)
 		assert.Equal(t, articleFoo.ID, article.ID)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -487,7 +653,97 @@ func TestArticle_FindArticleByAuthorIDAndSlug(t *testing.T) {
 	t.Run("when find article by author id and slug failed", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, err := repo.FindArticleByAuthorIDAndSlug(1, articleFoo.Slug)
+		_, err := repo.FindArticleByAuthorIDAndSlug(context.Background(), 1, articleFoo.Slug)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindArticleBySlugRedirect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when find article by slug redirect success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "title", "slug"}).AddRow(1, "foo Title", "foo-title")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT articles.* FROM `articles` INNER JOIN article_slug_redirects")).
+			WithArgs("foo-slug").WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		article, err := repo.FindArticleBySlugRedirect(context.Background(), "foo-slug")
+		assert.NoError(t, err)
+		assert.Equal(t, "foo-title", article.Slug)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find article by slug redirect failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindArticleBySlugRedirect(context.Background(), "foo-slug")
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindTakenSlugs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when find taken slugs success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `slug` FROM `articles`")).
+			WithArgs("foo", "foo-%", uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("foo").AddRow("foo-2"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `old_slug` FROM `article_slug_redirects`")).
+			WithArgs("foo", "foo-%", uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"old_slug"}).AddRow("foo-3"))
+		repo := NewArticleRepo(db)
+		taken, err := repo.FindTakenSlugs(context.Background(), "foo", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{"foo": true, "foo-2": true, "foo-3": true}, taken)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find taken slugs failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `slug` FROM `articles`")).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `old_slug` FROM `article_slug_redirects`")).
+			WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindTakenSlugs(context.Background(), "foo", 1)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_RenameArticle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	article := &entity.Article{ID: 1, Title: "foo", Slug: "foo"}
+	t.Run("transaction commit when rename article success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `article_slug_redirects`")).
+			WithArgs("foo-slug", "foo").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `article_slug_redirects`")).
+			WithArgs("foo-slug", uint64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.RenameArticle(context.Background(), article, "foo-slug", nil)
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when insert redirect failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `article_slug_redirects`")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `article_slug_redirects`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.RenameArticle(context.Background(), article, "foo-slug", nil)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction begin with error", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		err = repo.RenameArticle(context.Background(), article, "foo-slug", nil)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -501,59 +757,187 @@ func TestArticle_ListArticles(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1).
 			AddRow(articleBar.ID, articleBar.Title, articleBar.Slug, articleBar.Body, articleBar.Description, articleBar.CreatedAt, articleBar.UpdatedAt, articleBar.DeletedAt, 1)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (`articles`.`deleted_at` is null)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles` WHERE (`articles`.`deleted_at` is null) ORDER BY created_at DESC, id DESC LIMIT 2 OFFSET 4")).
+			WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		articles, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{}, model.Page{Offset: 4, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, articles, 2)
+		assert.Equal(t, int64(7), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when offset is beyond the last article", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "slug"}))
 		repo := NewArticleRepo(db)
-		_, n, err := repo.FindArticles(0, 1)
+		articles, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{}, model.Page{Offset: 10, Limit: 5})
 		assert.NoError(t, err)
+		assert.Empty(t, articles)
 		assert.Equal(t, int64(2), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+	t.Run("when count articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{}, model.Page{Offset: 0, Limit: 1})
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 	t.Run("when list articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{}, model.Page{Offset: 0, Limit: 1})
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_ListFeed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when list feed success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
+			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 2)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WithArgs(1).WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		articles, n, err := repo.ListFeed(context.Background(), 1, model.Page{Offset: 0, Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, articles, 1)
+		assert.Equal(t, int64(5), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list feed after a cursor", func(t *testing.T) {
+		cursor := &model.Cursor{CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ID: 7}
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(regexp.QuoteMeta("AND ((created_at < ? OR (created_at = ? AND id < ?))) AND (`articles`.`deleted_at` is null) ORDER BY created_at DESC, id DESC LIMIT 2;")).
+			WithArgs(1, cursor.CreatedAt, cursor.CreatedAt, cursor.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6).AddRow(5))
+		repo := NewArticleRepo(db)
+		articles, _, err := repo.ListFeed(context.Background(), 1, model.Page{Limit: 2, After: cursor})
+		assert.NoError(t, err)
+		require.Len(t, articles, 2)
+		assert.Equal(t, uint64(6), articles[0].ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list feed before a cursor", func(t *testing.T) {
+		cursor := &model.Cursor{CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ID: 7}
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(regexp.QuoteMeta("AND ((created_at > ? OR (created_at = ? AND id > ?))) AND (`articles`.`deleted_at` is null) ORDER BY created_at ASC, id ASC LIMIT 2;")).
+			WithArgs(1, cursor.CreatedAt, cursor.CreatedAt, cursor.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8).AddRow(9))
+		repo := NewArticleRepo(db)
+		articles, _, err := repo.ListFeed(context.Background(), 1, model.Page{Limit: 2, Before: cursor})
+		assert.NoError(t, err)
+		require.Len(t, articles, 2)
+		assert.Equal(t, uint64(9), articles[0].ID, "the rows read backwards are put back newest first")
+		assert.Equal(t, uint64(8), articles[1].ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count feed failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.ListFeed(context.Background(), 1, model.Page{Offset: 0, Limit: 1})
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list feed failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, n, err := repo.FindArticles(0, 1)
+		_, n, err := repo.ListFeed(context.Background(), 1, model.Page{Offset: 0, Limit: 1})
 		assert.Errorf(t, err, "some error")
 		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestArticle_ListArticlesByTag(t *testing.T) {
+func TestArticle_ListArticlesFiltered(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
+	byTag := "(articles.id IN (SELECT article_tags.article_id FROM article_tags JOIN tags ON tags.id = article_tags.tag_id WHERE tags.tag = ? AND tags.deleted_at IS NULL))"
 	t.Run("when list articles by tag success", func(t *testing.T) {
-		tagRows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "tag"}).
-			AddRow(tagFoo.ID, tagFoo.CreatedAt, tagFoo.UpdatedAt, tagFoo.DeletedAt, tagFoo.Tag).
-			AddRow(tagBar.ID, tagBar.CreatedAt, tagBar.UpdatedAt, tagBar.DeletedAt, tagBar.Tag)
 		rows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1).
 			AddRow(articleBar.ID, articleBar.Title, articleBar.Slug, articleBar.Body, articleBar.Description, articleBar.CreatedAt, articleBar.UpdatedAt, articleBar.DeletedAt, 1)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(tagRows)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(rows)
-
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE " + byTag)).WithArgs("tag").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles` WHERE " + byTag)).WithArgs("tag").
+			WillReturnRows(rows)
 		repo := NewArticleRepo(db)
-		_, n, err := repo.ListArticlesByTag("tag", 0, 1)
+		articles, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{Tag: "tag"}, model.Page{Offset: 0, Limit: 2})
 		assert.NoError(t, err)
-		assert.Equal(t, int64(2), n)
+		assert.Len(t, articles, 2)
+		assert.Equal(t, int64(3), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
-	t.Run("when list articles by tag find article failed", func(t *testing.T) {
-		tagRows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "tag"}).
-			AddRow(tagFoo.ID, tagFoo.CreatedAt, tagFoo.UpdatedAt, tagFoo.DeletedAt, tagFoo.Tag).
-			AddRow(tagBar.ID, tagBar.CreatedAt, tagBar.UpdatedAt, tagBar.DeletedAt, tagBar.Tag)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(tagRows)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+	t.Run("when no article has the tag", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "slug"}))
 		repo := NewArticleRepo(db)
-		_, n, err := repo.ListArticlesByTag("tag", 0, 1)
-		assert.Errorf(t, err, "some error")
+		articles, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{Tag: "unknown"}, model.Page{Offset: 0, Limit: 2})
+		assert.NoError(t, err)
+		assert.Empty(t, articles)
 		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
-	t.Run("when list articles by tag find tag failed", func(t *testing.T) {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+	t.Run("when every filter is combined", func(t *testing.T) {
+		since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+		where := "WHERE " + byTag +
+			" AND (articles.author_id IN (SELECT users.id FROM users WHERE users.username = ? AND users.deleted_at IS NULL))" +
+			" AND (articles.id IN (SELECT favorites.article_id FROM favorites JOIN users ON users.id = favorites.user_id WHERE users.username = ? AND users.deleted_at IS NULL))" +
+			" AND (`articles`.`created_at` >= ?) AND (`articles`.`created_at` < ?) AND (`articles`.`deleted_at` is null)"
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` "+where+";")).
+			WithArgs("tag", "author", "fan", since, until).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles` "+where+" ORDER BY created_at ASC, id ASC LIMIT 2;")).
+			WithArgs("tag", "author", "fan", since, until).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 		repo := NewArticleRepo(db)
-		_, n, err := repo.ListArticlesByTag("tag", 0, 1)
+		filter := model.ArticleFilter{Tag: "tag", Author: "author", Favorited: "fan", Since: since, Until: until, Sort: model.SortOldest}
+		articles, n, err := repo.ListArticles(context.Background(), filter, model.Page{Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, articles, 1)
+		assert.Equal(t, int64(1), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when the author alone is set", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (articles.author_id IN (SELECT users.id FROM users WHERE users.username = ? AND users.deleted_at IS NULL)) AND (`articles`.`deleted_at` is null);")).
+			WithArgs("author").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY created_at DESC, id DESC LIMIT 2;")).WithArgs("author").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		repo := NewArticleRepo(db)
+		articles, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{Author: "author"}, model.Page{Limit: 2})
+		assert.NoError(t, err)
+		assert.Empty(t, articles)
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list articles by tag failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.ListArticles(context.Background(), model.ArticleFilter{Tag: "tag"}, model.Page{Offset: 0, Limit: 1})
 		assert.Errorf(t, err, "some error")
 		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -565,21 +949,23 @@ func TestArticle_ListArticlesByAuthor(t *testing.T) {
 	require.NoError(t, err)
 	defer db.Close()
 	t.Run("when list articles by author success", func(t *testing.T) {
-		articleRows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
+		rows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1).
 			AddRow(articleBar.ID, articleBar.Title, articleBar.Slug, articleBar.Body, articleBar.Description, articleBar.CreatedAt, articleBar.UpdatedAt, articleBar.DeletedAt, 1)
-
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(articleRows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (`articles`.`author_id` = ?)")).WithArgs(userFoo.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles` WHERE (`articles`.`author_id` = ?)")).WithArgs(userFoo.ID).
+			WillReturnRows(rows)
 		repo := NewArticleRepo(db)
-		_, n, err := repo.ListArticlesByAuthor(userFoo, 0, 1)
+		_, n, err := repo.ListArticlesByAuthor(context.Background(), userFoo, model.Page{Offset: 0, Limit: 2})
 		assert.NoError(t, err)
-		assert.Equal(t, int64(2), n)
+		assert.Equal(t, int64(12), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
-	t.Run("when list articles by author find author  failed", func(t *testing.T) {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+	t.Run("when count articles by author failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, n, err := repo.ListArticlesByAuthor(userFoo, 0, 1)
+		_, n, err := repo.ListArticlesByAuthor(context.Background(), userFoo, model.Page{Offset: 0, Limit: 1})
 		assert.Errorf(t, err, "some error")
 		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -597,7 +983,7 @@ func TestArticle_FindAuthorBySlug(t *testing.T) {
 			WillReturnRows(users)
 
 		repo := NewArticleRepo(db)
-		_, err := repo.FindAuthorByArticle(articleFoo)
+		_, err := repo.FindAuthorByArticle(context.Background(), articleFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -610,10 +996,80 @@ func TestArticle_FindCommentsByArticle(t *testing.T) {
 	t.Run("when find comments  success", func(t *testing.T) {
 		commentRows := sqlmock.NewRows([]string{"id", "body", "created_at", "updated_at", "deleted_at", "author_id", "article_id"}).
 			AddRow(commentFoo.ID, commentFoo.Body, commentFoo.CreatedAt, commentFoo.UpdatedAt, commentFoo.DeletedAt, commentFoo.UserID, commentFoo.ArticleID)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(commentRows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `comments` WHERE (`comments`.`parent_id` is null) AND (`comments`.`article_id`=?)")).WithArgs(articleFoo.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.* FROM `comments` WHERE (`comments`.`parent_id` is null) AND (`comments`.`article_id`=?)")).WithArgs(articleFoo.ID).
+			WillReturnRows(commentRows)
 		repo := NewArticleRepo(db)
-		_, err := repo.FindCommentsByArticle(articleFoo, 0, 1)
+		comments, n, err := repo.FindCommentsByArticle(context.Background(), articleFoo, model.Page{Offset: 0, Limit: 1})
 		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+		assert.Equal(t, int64(4), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when offset is beyond the last comment", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.*")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "body"}))
+		repo := NewArticleRepo(db)
+		comments, n, err := repo.FindCommentsByArticle(context.Background(), articleFoo, model.Page{Offset: 20, Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+		assert.Equal(t, int64(4), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find comments after a cursor", func(t *testing.T) {
+		cursor := &model.Cursor{CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ID: 3}
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE (`comments`.`parent_id` is null) AND ((created_at > ? OR (created_at = ? AND id > ?))) AND (`comments`.`article_id`=?) AND (`comments`.`deleted_at` is null) ORDER BY created_at ASC, id ASC LIMIT 10;")).
+			WithArgs(cursor.CreatedAt, cursor.CreatedAt, cursor.ID, articleFoo.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		repo := NewArticleRepo(db)
+		comments, n, err := repo.FindCommentsByArticle(context.Background(), articleFoo, model.Page{Limit: 10, After: cursor})
+		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+		assert.Equal(t, int64(4), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count comments failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.FindCommentsByArticle(context.Background(), articleFoo, model.Page{Offset: 0, Limit: 1})
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindRepliesByRoots(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when find replies success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.* FROM `comments` WHERE (`root_id` IN (?,?)) AND (`comments`.`deleted_at` is null) ORDER BY created_at ASC, id ASC;")).
+			WithArgs(uint64(1), uint64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "root_id", "depth"}).AddRow(3, 1, 1, 1).AddRow(4, 3, 1, 2))
+		repo := NewArticleRepo(db)
+		replies, err := repo.FindRepliesByRoots(context.Background(), []*entity.Comment{{ID: 1}, {ID: 2}})
+		assert.NoError(t, err)
+		require.Len(t, replies, 2)
+		assert.Equal(t, uint(2), replies[1].Depth)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when there is no root", func(t *testing.T) {
+		repo := NewArticleRepo(db)
+		replies, err := repo.FindRepliesByRoots(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, replies)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find replies failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.*")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindRepliesByRoots(context.Background(), []*entity.Comment{{ID: 1}})
+		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -627,14 +1083,14 @@ func TestArticle_FindCommentByID(t *testing.T) {
 			AddRow(commentFoo.ID, commentFoo.Body, commentFoo.CreatedAt, commentFoo.UpdatedAt, commentFoo.DeletedAt, commentFoo.UserID, commentFoo.ArticleID)
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(commentRows)
 		repo := NewArticleRepo(db)
-		_, err := repo.FindCommentByID(commentFoo.ID)
+		_, err := repo.FindCommentByID(context.Background(), commentFoo.ID)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when find comment by id failed", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, err := repo.FindCommentByID(commentFoo.ID)
+		_, err := repo.FindCommentByID(context.Background(), commentFoo.ID)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -646,26 +1102,26 @@ func TestArticle_DeleteCommentByCommentID(t *testing.T) {
 	defer db.Close()
 	t.Run("when delete comment by comment id success", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when delete comment by comment id failed", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("DELETE")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when delete comment by comment id transaction failed", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		err := repo.DeleteCommentByCommentID(commentFoo.ID)
+		err := repo.DeleteCommentByCommentID(context.Background(), commentFoo.ID)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -679,18 +1135,512 @@ func TestArticle_FindFavoriteArticlesByUser(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "title", "slug", "body", "description", "created_at", "updated_at", "deleted_at", "author_id"}).
 			AddRow(articleFoo.ID, articleFoo.Title, articleFoo.Slug, articleFoo.Body, articleFoo.Description, articleFoo.CreatedAt, articleFoo.UpdatedAt, articleFoo.DeletedAt, 1).
 			AddRow(articleBar.ID, articleBar.Title, articleBar.Slug, articleBar.Body, articleBar.Description, articleBar.CreatedAt, articleBar.UpdatedAt, articleBar.DeletedAt, 1)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (id IN (SELECT article_id FROM favorites WHERE user_id = ?))")).
+			WithArgs(userFoo.ID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).WithArgs(userFoo.ID).WillReturnRows(rows)
 		repo := NewArticleRepo(db)
-		_, n, err := repo.FindFavoriteArticlesByUser(userFoo, 0, 1)
+		_, n, err := repo.FindFavoriteArticlesByUser(context.Background(), userFoo, model.Page{Offset: 0, Limit: 2})
 		assert.NoError(t, err)
-		assert.Equal(t, int64(2), n)
+		assert.Equal(t, int64(9), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when find favorite articles by user failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, _, err := repo.FindFavoriteArticlesByUser(context.Background(), userFoo, model.Page{Offset: 0, Limit: 1})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindAuthorsByArticles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when articles have no author", func(t *testing.T) {
+		repo := NewArticleRepo(db)
+		authors, err := repo.FindAuthorsByArticles(context.Background(), []*entity.Article{{ID: 1}})
+		assert.NoError(t, err)
+		assert.Empty(t, authors)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find authors success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "foo")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `users`.* FROM `users` WHERE (`id` IN (?))")).WithArgs(uint64(1)).WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		authors, err := repo.FindAuthorsByArticles(context.Background(), []*entity.Article{{ID: 1, AuthorID: null.Uint64From(1)}})
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", authors[1].Username)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find authors failed", func(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
 		repo := NewArticleRepo(db)
-		_, _, err := repo.FindFavoriteArticlesByUser(userFoo, 0, 1)
+		_, err := repo.FindAuthorsByArticles(context.Background(), []*entity.Article{{ID: 1, AuthorID: null.Uint64From(1)}})
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestArticle_FindTagsByArticles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when articles are empty", func(t *testing.T) {
+		repo := NewArticleRepo(db)
+		tags, err := repo.FindTagsByArticles(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find tags success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"article_id", "id", "tag"}).
+			AddRow(1, 1, "go").
+			AddRow(1, 2, "sql").
+			AddRow(2, 1, "go")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT article_tags.article_id AS article_id, tags.* FROM `tags` INNER JOIN article_tags")).WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		tags, err := repo.FindTagsByArticles(context.Background(), []*entity.Article{articleFoo, articleBar})
+		assert.NoError(t, err)
+		assert.Len(t, tags[1], 2)
+		assert.Len(t, tags[2], 1)
+		assert.Equal(t, "sql", tags[1][1].Tag.String)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find tags failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindTagsByArticles(context.Background(), []*entity.Article{articleFoo})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindFavoritedArticleIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when articles are empty", func(t *testing.T) {
+		repo := NewArticleRepo(db)
+		favorited, err := repo.FindFavoritedArticleIDs(context.Background(), userFoo.ID, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, favorited)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find favorited articles success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"article_id"}).AddRow(2)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `article_id` FROM `favorites` WHERE (user_id = ?)")).WillReturnRows(rows)
+		repo := NewArticleRepo(db)
+		favorited, err := repo.FindFavoritedArticleIDs(context.Background(), userFoo.ID, []*entity.Article{articleFoo, articleBar})
+		assert.NoError(t, err)
+		assert.False(t, favorited[1])
+		assert.True(t, favorited[2])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find favorited articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindFavoritedArticleIDs(context.Background(), userFoo.ID, []*entity.Article{articleFoo})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindTagByName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when find tag by name success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tags`.`tag` = ?)")).
+			WithArgs("go").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "go"))
+		repo := NewArticleRepo(db)
+		tag, err := repo.FindTagByName(context.Background(), "go")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), tag.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find tag by name failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindTagByName(context.Background(), "go")
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_RestoreTagByName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when the tag is not trashed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tags`.`tag` = ?)")).
+			WithArgs("go").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "go"))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		tag, err := repo.RestoreTagByName(context.Background(), "go")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), tag.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction commit when the tag is restored", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `tags`.* FROM `tags` WHERE (`tags`.`tag` = ?)")).
+			WithArgs("go").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tag", "deleted_at"}).AddRow(1, "go", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `tags` SET `deleted_at`=? WHERE `id`=?")).
+			WithArgs(nil, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		tag, err := repo.RestoreTagByName(context.Background(), "go")
+		assert.NoError(t, err)
+		assert.False(t, tag.DeletedAt.Valid)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when the tag does not exist", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		_, err := repo.RestoreTagByName(context.Background(), "go")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_CountArticlesByTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when count articles by tags success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("COUNT(*) AS articles_count FROM `article_tags` INNER JOIN articles ON articles.id = article_tags.article_id WHERE (articles.deleted_at IS NULL) GROUP BY article_tags.tag_id")).
+			WillReturnRows(sqlmock.NewRows([]string{"tag_id", "articles_count"}).AddRow(1, 3).AddRow(2, 1))
+		repo := NewArticleRepo(db)
+		counts, err := repo.CountArticlesByTags(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[uint64]int{1: 3, 2: 1}, counts)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count articles by tags failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.CountArticlesByTags(context.Background())
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_RenameTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when rename tag success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `tags`")).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("golang")}
+		err = repo.RenameTag(context.Background(), tag, "go")
+		assert.NoError(t, err)
+		assert.Equal(t, "go", tag.Tag.String)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when rename tag failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `tags`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.RenameTag(context.Background(), &entity.Tag{ID: 1}, "go")
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_MergeTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	from := &entity.Tag{ID: 1, Tag: null.StringFrom("golang")}
+	into := &entity.Tag{ID: 2, Tag: null.StringFrom("go")}
+	t.Run("transaction commit when merge tags success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO article_tags (tag_id, article_id) SELECT ?, article_id FROM article_tags WHERE tag_id = ?")).
+			WithArgs(uint64(2), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM article_tags WHERE tag_id = ?")).
+			WithArgs(uint64(1)).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `tags` SET `deleted_at`")).WithArgs(sqlmock.AnyArg(), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.MergeTags(context.Background(), from, into)
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when articles cannot be moved", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO article_tags")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.MergeTags(context.Background(), from, into)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_FindDeletedArticleByAuthorIDAndSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when the article is in the trash", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles` WHERE (`articles`.`deleted_at` is not null) AND (`articles`.`author_id` = ?) AND (`articles`.`slug` = ?)")).
+			WithArgs(uint64(1), "foo-slug").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "deleted_at"}).AddRow(1, "foo-slug", time.Now()))
+		repo := NewArticleRepo(db)
+		article, err := repo.FindDeletedArticleByAuthorIDAndSlug(context.Background(), 1, "foo-slug")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), article.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when the article is not in the trash", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.* FROM `articles`")).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		repo := NewArticleRepo(db)
+		_, err := repo.FindDeletedArticleByAuthorIDAndSlug(context.Background(), 1, "foo-slug")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_ListDeletedArticlesByAuthor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when list deleted articles success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (`articles`.`deleted_at` is not null) AND (`articles`.`author_id` = ?)")).
+			WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY deleted_at DESC, id DESC LIMIT 1")).
+			WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, "foo-slug"))
+		repo := NewArticleRepo(db)
+		articles, n, err := repo.ListDeletedArticlesByAuthor(context.Background(), &entity.User{ID: 1}, 0, 1)
+		assert.NoError(t, err)
+		assert.Len(t, articles, 1)
+		assert.Equal(t, int64(3), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count deleted articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, n, err := repo.ListDeletedArticlesByAuthor(context.Background(), &entity.User{ID: 1}, 0, 1)
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_RestoreArticle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	deletedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("transaction commit when restore article success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at`=? WHERE `id`=?")).
+			WithArgs(nil, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`article_id` = ?) AND (`comments`.`deleted_at` = ?)")).
+			WithArgs(nil, uint64(1), deletedAt).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		article := &entity.Article{ID: 1, DeletedAt: null.TimeFrom(deletedAt)}
+		err = repo.RestoreArticle(context.Background(), article)
+		assert.NoError(t, err)
+		assert.False(t, article.DeletedAt.Valid)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when restore article failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.RestoreArticle(context.Background(), &entity.Article{ID: 1})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_DeleteCommentOfArticle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	replies := regexp.QuoteMeta("SELECT COUNT(*) FROM `comments` WHERE (`comments`.`parent_id` = ?) AND (`comments`.`deleted_at` is null) LIMIT 1;")
+	t.Run("the comment of the article is trashed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(replies).WithArgs(uint64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`id` = ?) AND (`comments`.`article_id` = ?)")).
+			WithArgs(sqlmock.AnyArg(), uint64(2), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.DeleteCommentByArticle(context.Background(), &entity.Article{ID: 1}, &entity.Comment{ID: 2})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("the comment with replies is left as a placeholder", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(replies).WithArgs(uint64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `body` = ?, `updated_at` = ?, `user_id` = ? WHERE (`comments`.`id` = ?) AND (`comments`.`article_id` = ?) AND (`comments`.`deleted_at` is null)")).
+			WithArgs(model.DeletedCommentBody, sqlmock.AnyArg(), nil, uint64(2), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		err = repo.DeleteCommentByArticle(context.Background(), &entity.Article{ID: 1}, &entity.Comment{ID: 2})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when find replies failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(replies).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.DeleteCommentByArticle(context.Background(), &entity.Article{ID: 1}, &entity.Comment{ID: 2})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_ListDeletedCommentsByAuthor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when list deleted comments success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `comments` WHERE (`comments`.`deleted_at` is not null) AND (`comments`.`user_id` = ?) AND (article_id IN (SELECT id FROM articles WHERE deleted_at IS NULL))")).
+			WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY deleted_at DESC, id DESC LIMIT 20")).
+			WithArgs(uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "body"}).AddRow(2, "foo Body"))
+		repo := NewArticleRepo(db)
+		comments, n, err := repo.ListDeletedCommentsByAuthor(context.Background(), &entity.User{ID: 1}, 0, 20)
+		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+		assert.Equal(t, int64(1), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list deleted comments failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.*")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, _, err := repo.ListDeletedCommentsByAuthor(context.Background(), &entity.User{ID: 1}, 0, 20)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_RestoreComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when restore comment success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comments`.* FROM `comments` WHERE (`comments`.`deleted_at` is not null) AND (`comments`.`id` = ?)")).
+			WithArgs(uint64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at"}).AddRow(2, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at`=? WHERE `id`=?")).
+			WithArgs(nil, uint64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		comment, err := repo.FindDeletedCommentByID(context.Background(), 2)
+		require.NoError(t, err)
+		err = repo.RestoreComment(context.Background(), comment)
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_EditComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when edit comment success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `comment_revisions` (`created_at`,`comment_id`,`body`) VALUES (?,?,?)")).
+			WithArgs(sqlmock.AnyArg(), uint64(2), "old body").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `body`=?,`edited_at`=?,`updated_at`=? WHERE `id`=?")).
+			WithArgs("new body", sqlmock.AnyArg(), sqlmock.AnyArg(), uint64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		comment := &entity.Comment{ID: 2, Body: null.StringFrom("old body")}
+		err = repo.EditComment(context.Background(), comment, "new body")
+		assert.NoError(t, err)
+		assert.Equal(t, "new body", comment.Body.String)
+		assert.True(t, comment.EditedAt.Valid)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when add revision failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `comment_revisions`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		err = repo.EditComment(context.Background(), &entity.Comment{ID: 2}, "new body")
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_ListCommentRevisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when list revisions success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comment_revisions`.* FROM `comment_revisions` WHERE (`comment_revisions`.`comment_id`=?) ORDER BY created_at ASC, id ASC;")).
+			WithArgs(uint64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "comment_id", "body"}).AddRow(1, 2, "first").AddRow(2, 2, "second"))
+		repo := NewArticleRepo(db)
+		revisions, err := repo.ListCommentRevisions(context.Background(), &entity.Comment{ID: 2})
+		assert.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, "first", revisions[0].Body.String)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when list revisions failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `comment_revisions`.*")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewArticleRepo(db)
+		_, err := repo.ListCommentRevisions(context.Background(), &entity.Comment{ID: 2})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArticle_PurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("transaction commit when purge success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `comments` WHERE (`comments`.`deleted_at` < ?)")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `articles` WHERE (`articles`.`deleted_at` < ?)")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `tags` WHERE (`tags`.`deleted_at` < ?)")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewArticleRepo(db)
+		n, err := repo.PurgeDeleted(context.Background(), before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when purge failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `comments`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewArticleRepo(db)
+		n, err := repo.PurgeDeleted(context.Background(), before)
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
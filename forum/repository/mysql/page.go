@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"forum/model"
+
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// pageMods order a listing by (created_at, id), the newest rows first when desc, and select the
+// rows of page. The rows read before a cursor come in reverse order, see inListingOrder
+func pageMods(page model.Page, desc bool) []qm.QueryMod {
+	cmp, order := ">", "ASC"
+	if desc {
+		cmp, order = "<", "DESC"
+	}
+	var mods []qm.QueryMod
+	switch {
+	case page.After != nil:
+		mods = append(mods, keyset(cmp, page.After))
+	case page.Before != nil:
+		// walk the listing backwards from the cursor so the limit keeps the rows nearest to it
+		if desc {
+			cmp, order = ">", "ASC"
+		} else {
+			cmp, order = "<", "DESC"
+		}
+		mods = append(mods, keyset(cmp, page.Before))
+	default:
+		mods = append(mods, qm.Offset(page.Offset))
+	}
+	return append(mods, qm.OrderBy("created_at "+order+", id "+order), qm.Limit(page.Limit))
+}
+
+// keyset select the rows past c in the direction of cmp
+func keyset(cmp string, c *model.Cursor) qm.QueryMod {
+	return qm.Where("(created_at "+cmp+" ? OR (created_at = ? AND id "+cmp+" ?))", c.CreatedAt, c.CreatedAt, c.ID)
+}
+
+// inListingOrder put back in order the rows read backwards before a cursor
+func inListingOrder[T any](page model.Page, rows []T) []T {
+	if page.Before != nil {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows
+}
@@ -0,0 +1,140 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"schema/entity"
+	"strings"
+	"unicode"
+
+	"forum/model"
+	"forum/repository"
+
+	"github.com/rs/zerolog/log"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// matchArticles is the relevance of an article to the query bound to it, per the FULLTEXT index
+// idx_articles_search
+const matchArticles = "MATCH (articles.title, articles.description, articles.body) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// snippetRadius is the number of characters quoted on each side of the first term found
+const snippetRadius = 60
+
+// ArticleSearch search the articles through the FULLTEXT index on their title, description and body
+type ArticleSearch struct {
+	Db *sql.DB
+}
+
+func NewArticleSearch(db *sql.DB) *ArticleSearch {
+	return &ArticleSearch{Db: db}
+}
+
+// SearchArticles list a page of the articles matching query and filter, the most relevant first,
+// and count them all. The page is read by offset, the results being ordered by relevance
+func (s *ArticleSearch) SearchArticles(ctx context.Context, query string, filter model.ArticleFilter, page model.Page) ([]*repository.SearchHit, int64, error) {
+	criteria := append(articleFilterMods(filter), qm.Where(matchArticles, query))
+	count, err := entity.Articles(criteria...).Count(ctx, executor(ctx, s.Db))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count matching articles")
+		return nil, 0, err
+	}
+	articles, err := entity.Articles(append(criteria,
+		qm.OrderBy(matchArticles+" DESC, articles.id DESC", query),
+		qm.Limit(page.Limit),
+		qm.Offset(page.Offset))...).All(ctx, executor(ctx, s.Db))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to search articles")
+		return nil, 0, err
+	}
+	terms := searchTerms(query)
+	hits := make([]*repository.SearchHit, 0, len(articles))
+	for _, a := range articles {
+		hits = append(hits, &repository.SearchHit{
+			Article: a,
+			Snippet: snippet(terms, a.Body.String, a.Description.String, a.Title),
+		})
+	}
+	return hits, count, nil
+}
+
+// searchTerms split query into the lowercase words it searches for
+func searchTerms(query string) [][]rune {
+	var terms [][]rune
+	for _, w := range strings.FieldsFunc(query, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		terms = append(terms, lowerRunes(w))
+	}
+	return terms
+}
+
+// lowerRunes lowercase s rune by rune, so the indexes of the result are those of []rune(s)
+func lowerRunes(s string) []rune {
+	r := []rune(s)
+	for i, c := range r {
+		r[i] = unicode.ToLower(c)
+	}
+	return r
+}
+
+// snippet quote the first of texts containing one of terms around the first term found, with
+// every term highlighted. The full text index also matches other forms of a word, when none of
+// the texts contains a term the start of the first non empty one is quoted
+func snippet(terms [][]rune, texts ...string) string {
+	fallback := ""
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		runes, lower := []rune(text), lowerRunes(text)
+		for i := range lower {
+			if n := termAt(lower, i, terms); n > 0 {
+				return highlight(runes, lower, terms, i-snippetRadius, i+n+snippetRadius)
+			}
+		}
+		if fallback == "" {
+			fallback = highlight(runes, lower, nil, 0, 2*snippetRadius)
+		}
+	}
+	return fallback
+}
+
+// termAt return the length of the term found at lower[i:], 0 when there is none
+func termAt(lower []rune, i int, terms [][]rune) int {
+	for _, t := range terms {
+		if len(t) > 0 && i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == string(t) {
+			return len(t)
+		}
+	}
+	return 0
+}
+
+// highlight HTML escape runes[from:to], wrapping the terms in <em> tags and marking the cuts
+// with an ellipsis
+func highlight(runes, lower []rune, terms [][]rune, from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(runes) {
+		to = len(runes)
+	}
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	for i := from; i < to; {
+		if n := termAt(lower[:to], i, terms); n > 0 {
+			b.WriteString("<em>" + html.EscapeString(string(runes[i:i+n])) + "</em>")
+			i += n
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
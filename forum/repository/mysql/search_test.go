@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"forum/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleSearch_SearchArticles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	match := "MATCH (articles.title, articles.description, articles.body) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	t.Run("when search articles success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `articles` WHERE (articles.author_id IN (SELECT users.id FROM users WHERE users.username = ? AND users.deleted_at IS NULL)) AND ("+match+") AND (`articles`.`deleted_at` is null);")).
+			WithArgs("foo", "golang generics").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY "+match+" DESC, articles.id DESC LIMIT 2 OFFSET 1;")).
+			WithArgs("foo", "golang generics", "golang generics").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "body"}).
+				AddRow(2, "Generics", "Since 1.18 Golang has generics.").
+				AddRow(1, "Golang", ""))
+		s := NewArticleSearch(db)
+		hits, n, err := s.SearchArticles(context.Background(), "golang generics", model.ArticleFilter{Author: "foo"}, model.Page{Offset: 1, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+		require.Len(t, hits, 2)
+		assert.Equal(t, uint64(2), hits[0].Article.ID)
+		assert.Equal(t, "Since 1.18 <em>Golang</em> has <em>generics</em>.", hits[0].Snippet)
+		assert.Equal(t, "<em>Golang</em>", hits[1].Snippet)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count matching articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		s := NewArticleSearch(db)
+		hits, n, err := s.SearchArticles(context.Background(), "golang", model.ArticleFilter{}, model.Page{Limit: 2})
+		assert.Errorf(t, err, "some error")
+		assert.Nil(t, hits)
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when search articles failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `articles`.*")).WillReturnError(fmt.Errorf("some error"))
+		s := NewArticleSearch(db)
+		_, n, err := s.SearchArticles(context.Background(), "golang", model.ArticleFilter{}, model.Page{Limit: 2})
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSnippet(t *testing.T) {
+	long := "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. " +
+		"Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat."
+	tests := []struct {
+		name  string
+		query string
+		texts []string
+		want  string
+	}{
+		{"terms are highlighted whatever their case", "GO", []string{"Go and go"}, "<em>Go</em> and <em>go</em>"},
+		{"the text around the match is escaped", "b", []string{"<a> & b"}, "&lt;a&gt; &amp; <em>b</em>"},
+		{"long texts are cut around the first match", "minim", []string{long},
+			"…por incididunt ut labore et dolore magna aliqua. Ut enim ad <em>minim</em> veniam, quis nostrud exercitation ullamco laboris nisi ut a…"},
+		{"the first text with a match is quoted", "title", []string{"body", "", "the title"}, "the <em>title</em>"},
+		{"the first text is quoted when nothing matches literally", "running", []string{"", "runs fast"}, "runs fast"},
+		{"non ASCII text", "été", []string{"Un ÉTÉ à Paris"}, "Un <em>ÉTÉ</em> à Paris"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, snippet(searchTerms(tt.query), tt.texts...))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog/log"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+const (
+	// maxTxAttempts bound the runs of a transaction aborted by a deadlock or a lock wait timeout
+	maxTxAttempts = 3
+	// txRetryDelay is the pause before the second attempt, it grows with each attempt
+	txRetryDelay = 20 * time.Millisecond
+)
+
+// server error numbers of the aborted transactions worth running again
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// txKey is the context key of the transaction the repositories take part in
+type txKey struct{}
+
+// UnitOfWork run several repository calls in a single transaction
+type UnitOfWork struct {
+	Db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{Db: db}
+}
+
+// Do run fn in a transaction, every repository called with the ctx given to fn takes part in it.
+// The transaction commits when fn returns nil, and fn is run again from scratch when MySQL
+// aborted it on a deadlock. Do called within a unit of work joins the outer transaction.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, _ *sql.Tx) error {
+		return fn(ctx)
+	})
+}
+
+// executor return the transaction ctx carries, db when there is none
+func executor(ctx context.Context, db *sql.DB) boil.ContextExecutor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx run fn in the transaction ctx carries, or in a new one retried on deadlocks
+func inTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx, tx)
+	}
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, fn)
+		if !isRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+		log.Warn().Err(err).Int("attempt", attempt).Msg("transaction aborted, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start transaction")
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(context.WithValue(ctx, txKey{}, tx), tx)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// isRetryable tell whether err aborted the transaction on a lock conflict
+func isRetryable(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && (me.Number == mysqlDeadlock || me.Number == mysqlLockWaitTimeout)
+}
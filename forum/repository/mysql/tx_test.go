@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_Do(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	uow := NewUnitOfWork(db)
+	articleRepo := NewArticleRepo(db)
+	userRepo := NewUserRepo(db)
+	t.Run("repositories share the transaction which commits once", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT .*`favorites_count` FROM `articles`").
+			WillReturnRows(sqlmock.NewRows([]string{"favorites_count"}).AddRow(0))
+		mock.ExpectCommit()
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			if err := userRepo.CreateUser(ctx, userFoo); err != nil {
+				return err
+			}
+			return articleRepo.CreateArticle(ctx, articleFoo, nil)
+		})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("every call rolls back when one fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `articles`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			if err := userRepo.CreateUser(ctx, userFoo); err != nil {
+				return err
+			}
+			return articleRepo.CreateArticle(ctx, articleFoo, nil)
+		})
+		assert.EqualError(t, err, "entity: unable to insert into articles: some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("a deadlock runs the unit of work again", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"})
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		runs := 0
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			runs++
+			return userRepo.CreateUser(ctx, userFoo)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, runs)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("lock wait timeouts give up after the last attempt", func(t *testing.T) {
+		for i := 0; i < maxTxAttempts; i++ {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnError(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"})
+			mock.ExpectRollback()
+		}
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			return userRepo.CreateUser(ctx, userFoo)
+		})
+		assert.ErrorContains(t, err, "Lock wait timeout exceeded")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("other errors are not retried", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		mock.ExpectRollback()
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			return userRepo.CreateUser(ctx, userFoo)
+		})
+		assert.ErrorContains(t, err, "Duplicate entry")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("commit errors are returned", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit().WillReturnError(fmt.Errorf("commit error"))
+		err = uow.Do(context.Background(), func(ctx context.Context) error {
+			return userRepo.CreateUser(ctx, userFoo)
+		})
+		assert.EqualError(t, err, "commit error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
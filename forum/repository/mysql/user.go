@@ -3,11 +3,17 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"schema/entity"
+	"time"
 
+	"forum/model"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 
 	"github.com/rs/zerolog/log"
+	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 )
 
@@ -23,8 +29,8 @@ func NewUserRepo(db *sql.DB) *UserRepo {
 	}
 }
 
-func (u *UserRepo) FindUserByID(uid uint) (*entity.User, error) {
-	user, err := entity.Users(qm.Where("id = ?", uid)).One(context.Background(), u.Db)
+func (u *UserRepo) FindUserByID(ctx context.Context, uid uint) (*entity.User, error) {
+	user, err := entity.Users(qm.Where("id = ?", uid)).One(ctx, executor(ctx, u.Db))
 	if err != nil {
 		log.Error().Err(err).Msg("error in finding user by id")
 		return nil, err
@@ -32,8 +38,8 @@ func (u *UserRepo) FindUserByID(uid uint) (*entity.User, error) {
 	return user, nil
 }
 
-func (u *UserRepo) FindByEmail(s string) (*entity.User, error) {
-	user, err := entity.Users(qm.Where("email = ?", s)).One(context.Background(), u.Db)
+func (u *UserRepo) FindByEmail(ctx context.Context, s string) (*entity.User, error) {
+	user, err := entity.Users(qm.Where("email = ?", s)).One(ctx, executor(ctx, u.Db))
 	if err != nil {
 		log.Error().Err(err).Msg("error in finding user by email")
 		return nil, err
@@ -41,8 +47,8 @@ func (u *UserRepo) FindByEmail(s string) (*entity.User, error) {
 	return user, nil
 }
 
-func (u *UserRepo) FindUserByUserName(s string) (*entity.User, error) {
-	user, err := entity.Users(qm.Where("username = ?", s)).One(context.Background(), u.Db)
+func (u *UserRepo) FindUserByUserName(ctx context.Context, s string) (*entity.User, error) {
+	user, err := entity.Users(qm.Where("username = ?", s)).One(ctx, executor(ctx, u.Db))
 	if err != nil {
 		log.Error().Err(err).Msg("error in finding user by username")
 		return nil, err
@@ -50,93 +56,389 @@ func (u *UserRepo) FindUserByUserName(s string) (*entity.User, error) {
 	return user, nil
 }
 
-func (u *UserRepo) CreateUser(user *entity.User) error {
-	tx, err := u.Db.BeginTx(context.Background(), nil)
+func (u *UserRepo) CreateUser(ctx context.Context, user *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := user.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create user")
+			return err
+		}
+		return nil
+	})
+}
+
+func (u *UserRepo) UpdateUser(ctx context.Context, user *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := user.Update(ctx, tx, boil.Infer())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to update user")
+			return err
+		}
+		return nil
+	})
+}
+
+// AddFollower make follower follow user. Following a user twice changes nothing
+func (u *UserRepo) AddFollower(ctx context.Context, user *entity.User, follower *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := queries.Raw("INSERT IGNORE INTO follows (follower_id, following_id) VALUES (?, ?)",
+			follower.ID, user.ID).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to add follower")
+			return err
+		}
+		return nil
+	})
+}
+
+func (u *UserRepo) RemoveFollower(ctx context.Context, user *entity.User, follower *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		err := user.RemoveFollowerUsers(ctx, tx, follower)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to remove follower")
+			return err
+		}
+		return nil
+	})
+}
+
+// IsFollower tell whether follower follows user
+func (u *UserRepo) IsFollower(ctx context.Context, user, follower *entity.User) (bool, error) {
+	_, err := user.FollowerUsers(qm.Where("follower_id=?", follower.ID)).One(ctx, executor(ctx, u.Db))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to check follower")
+		return false, err
 	}
-	defer tx.Rollback()
-	err = user.Insert(context.Background(), u.Db, boil.Infer())
+	return true, nil
+}
+
+// GetFollowers list the users following user by username with pagination
+func (u *UserRepo) GetFollowers(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.User, int64, error) {
+	count, err := user.FollowerUsers().Count(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
-		return err
+		log.Error().Err(err).Msg("failed to count followers")
+		return nil, 0, err
 	}
-	tx.Commit()
-	return nil
+	followers, err := user.FollowerUsers(qm.OrderBy("users.username"), qm.Limit(limit), qm.Offset(offset)).All(ctx, executor(ctx, u.Db))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get followers")
+		return nil, 0, err
+	}
+	return followers, count, nil
 }
 
-func (u *UserRepo) UpdateUser(user *entity.User) error {
-	tx, err := u.Db.BeginTx(context.Background(), nil)
+// GetFollowingUsers list the users followed by user by username with pagination
+func (u *UserRepo) GetFollowingUsers(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.User, int64, error) {
+	count, err := user.FollowingUsers().Count(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to count following")
+		return nil, 0, err
 	}
-	defer tx.Rollback()
-	_, err = user.Update(context.Background(), u.Db, boil.Infer())
+	following, err := user.FollowingUsers(qm.OrderBy("users.username"), qm.Limit(limit), qm.Offset(offset)).All(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to update user")
-		return err
+		log.Error().Err(err).Msg("failed to get following")
+		return nil, 0, err
 	}
-	tx.Commit()
-	return nil
+	return following, count, nil
 }
 
-func (u *UserRepo) AddFollower(user *entity.User, follower *entity.User) error {
-	tx, err := u.Db.BeginTx(context.Background(), nil)
+// FindFollowingIDs return the subset of userIDs followed by follower
+func (u *UserRepo) FindFollowingIDs(ctx context.Context, follower *entity.User, userIDs []uint64) (map[uint64]bool, error) {
+	following := make(map[uint64]bool)
+	if len(userIDs) == 0 {
+		return following, nil
+	}
+	ids := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, id)
+	}
+	users, err := follower.FollowingUsers(
+		qm.Select("users.id"),
+		qm.WhereIn("users.id IN ?", ids...)).All(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to find following ids")
+		return nil, err
 	}
-	defer tx.Rollback()
-	err = user.AddFollowerUsers(context.Background(), u.Db, false, follower)
+	for _, user := range users {
+		following[user.ID] = true
+	}
+	return following, nil
+}
+
+// unrepliedComments match the comments which no comment outside the trash replies to, the derived table
+// is materialized, which lets it read comments while they are updated
+const unrepliedComments = "id NOT IN (SELECT parent_id FROM (SELECT DISTINCT parent_id FROM comments" +
+	" WHERE parent_id IS NOT NULL AND deleted_at IS NULL) AS replied)"
+
+// DeleteUser move user to the trash with its articles, the comments of those articles and its comments, all
+// stamped with the deletion time of user so that RestoreUser brings them back together.
+// A comment of user which others still reply to stays in its thread as a placeholder instead, as when it is
+// deleted alone
+func (u *UserRepo) DeleteUser(ctx context.Context, user *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := user.Delete(ctx, tx, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete user")
+			return err
+		}
+		trashed := entity.M{entity.ArticleColumns.DeletedAt: user.DeletedAt}
+		_, err = entity.Articles(entity.ArticleWhere.AuthorID.EQ(null.Uint64From(user.ID))).UpdateAll(ctx, tx, trashed)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete articles of user")
+			return err
+		}
+		_, err = entity.Comments(
+			qm.Where("article_id IN (SELECT id FROM articles WHERE author_id = ? AND deleted_at = ?)", user.ID, user.DeletedAt)).
+			UpdateAll(ctx, tx, trashed)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to delete comments of the articles of user")
+			return err
+		}
+		// every pass trashes the comments of user at the end of their threads, a reply being at most
+		// MaxCommentDepth levels deep this ends after a few passes
+		for {
+			n, err := entity.Comments(entity.CommentWhere.UserID.EQ(null.Uint64From(user.ID)), qm.Where(unrepliedComments)).
+				UpdateAll(ctx, tx, trashed)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete comments of user")
+				return err
+			}
+			if n == 0 {
+				break
+			}
+		}
+		_, err = entity.Comments(entity.CommentWhere.UserID.EQ(null.Uint64From(user.ID))).UpdateAll(ctx, tx, entity.M{
+			entity.CommentColumns.Body:      model.DeletedCommentBody,
+			entity.CommentColumns.UserID:    nil,
+			entity.CommentColumns.UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to blank replied comments of user")
+			return err
+		}
+		return nil
+	})
+}
+
+// FindDeletedUserByUserName find the trashed user named s
+func (u *UserRepo) FindDeletedUserByUserName(ctx context.Context, s string) (*entity.User, error) {
+	user, err := entity.Users(
+		qm.WithDeleted(),
+		entity.UserWhere.DeletedAt.IsNotNull(),
+		entity.UserWhere.Username.EQ(s)).One(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to add follower")
-		return err
+		log.Error().Err(err).Msg("failed to find deleted user")
+		return nil, err
 	}
-	tx.Commit()
-	return nil
+	return user, nil
 }
 
-func (u *UserRepo) RemoveFollower(user *entity.User, follower *entity.User) error {
-	tx, err := u.Db.BeginTx(context.Background(), nil)
+// ListDeletedUsers list the trashed users, the last deleted first
+func (u *UserRepo) ListDeletedUsers(ctx context.Context, offset, limit int) ([]*entity.User, int64, error) {
+	criteria := []qm.QueryMod{
+		qm.WithDeleted(),
+		entity.UserWhere.DeletedAt.IsNotNull(),
+	}
+	count, err := entity.Users(criteria...).Count(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to start transaction")
-		return err
+		log.Error().Err(err).Msg("failed to count deleted users")
+		return nil, 0, err
 	}
-	defer tx.Rollback()
-	err = user.RemoveFollowerUsers(context.Background(), u.Db, follower)
+	users, err := entity.Users(append(criteria,
+		qm.OrderBy("deleted_at DESC, id DESC"),
+		qm.Limit(limit),
+		qm.Offset(offset))...).All(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to remove follower")
-		return err
+		log.Error().Err(err).Msg("failed to list deleted users")
+		return nil, 0, err
 	}
-	tx.Commit()
-	return nil
+	return users, count, nil
 }
 
-func (u *UserRepo) IsFollower(user, follower *entity.User) (bool, error) {
-	_, err := user.FollowerUsers(qm.Where("follower_id=?", follower.ID)).One(context.Background(), u.Db)
+// RestoreUser take user out of the trash along with the articles and comments trashed with it
+func (u *UserRepo) RestoreUser(ctx context.Context, user *entity.User) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		deletedAt := user.DeletedAt
+		user.DeletedAt = null.Time{}
+		_, err := user.Update(ctx, tx, boil.Whitelist(entity.UserColumns.DeletedAt))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore user")
+			return err
+		}
+		_, err = entity.Articles(
+			qm.WithDeleted(),
+			entity.ArticleWhere.AuthorID.EQ(null.Uint64From(user.ID)),
+			entity.ArticleWhere.DeletedAt.EQ(deletedAt)).
+			UpdateAll(ctx, tx, entity.M{entity.ArticleColumns.DeletedAt: nil})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore articles of user")
+			return err
+		}
+		_, err = entity.Comments(
+			qm.WithDeleted(),
+			entity.CommentWhere.DeletedAt.EQ(deletedAt),
+			qm.Where("(user_id = ? OR article_id IN (SELECT id FROM articles WHERE author_id = ?))", user.ID, user.ID)).
+			UpdateAll(ctx, tx, entity.M{entity.CommentColumns.DeletedAt: nil})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to restore comments of user")
+			return err
+		}
+		return nil
+	})
+}
+
+// purgeableUsersWhere match the users trashed before the time bound to it who no longer author any
+// article or comment
+const purgeableUsersWhere = "deleted_at < ?" +
+	" AND id NOT IN (SELECT author_id FROM articles WHERE author_id IS NOT NULL)" +
+	" AND id NOT IN (SELECT user_id FROM comments WHERE user_id IS NOT NULL)"
+
+const purgeableUsers = "SELECT id FROM users WHERE " + purgeableUsersWhere
+
+// PurgeDeleted remove for good the users trashed before before, with their follows and favorites, which are
+// uncounted from the favorited articles.
+// The users still authoring articles or comments are kept until those are purged.
+// It returns the number of users removed
+func (u *UserRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := queries.Raw(
+			"DELETE FROM follows WHERE follower_id IN ("+purgeableUsers+") OR following_id IN ("+purgeableUsers+")",
+			before, before).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge follows")
+			return err
+		}
+		// the grouped derived table is materialized, which lets it read articles while they are updated
+		_, err = queries.Raw(
+			"UPDATE articles JOIN (SELECT article_id, COUNT(*) AS purged FROM favorites WHERE user_id IN ("+purgeableUsers+")"+
+				" GROUP BY article_id) AS f ON f.article_id = articles.id"+
+				" SET articles.favorites_count = articles.favorites_count - f.purged", before).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to uncount purged favorites")
+			return err
+		}
+		_, err = queries.Raw(
+			"DELETE FROM favorites WHERE user_id IN ("+purgeableUsers+")", before).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge favorites")
+			return err
+		}
+		// mysql refuses a subquery on the table deleted from, hence the bare conditions
+		res, err := queries.Raw("DELETE FROM users WHERE "+purgeableUsersWhere, before).ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge users")
+			return err
+		}
+		purged, err = res.RowsAffected()
+		return err
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("failed to check follower")
-		return false, nil
+		return 0, err
 	}
-	return true, err
+	return purged, nil
 }
 
-func (u *UserRepo) GetFollowers(user *entity.User) ([]*entity.User, error) {
-	followers, err := user.FollowerUsers().All(context.Background(), u.Db)
+// CreateRefreshToken store token, the expired refresh tokens of its user are dropped on the way
+func (u *UserRepo) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := entity.RefreshTokens(qm.Where("user_id = ? AND expires_at < ?", token.UserID, time.Now())).
+			DeleteAll(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to drop expired refresh tokens")
+			return err
+		}
+		if err = token.Insert(ctx, tx, boil.Infer()); err != nil {
+			log.Error().Err(err).Msg("failed to create refresh token")
+			return err
+		}
+		return nil
+	})
+}
+
+// FindRefreshToken find the refresh token whose sha256 is hash, revoked or expired alike
+func (u *UserRepo) FindRefreshToken(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	token, err := entity.RefreshTokens(qm.Where("token_hash = ?", hash)).One(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get followers")
+		log.Error().Err(err).Msg("error in finding refresh token")
 		return nil, err
 	}
-	return followers, nil
+	return token, nil
+}
+
+// RotateRefreshToken revoke old and store next in its place. It returns sql.ErrNoRows when old was
+// revoked meanwhile, so that a refresh token is never rotated twice
+func (u *UserRepo) RotateRefreshToken(ctx context.Context, old, next *entity.RefreshToken) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		n, err := entity.RefreshTokens(qm.Where("id = ? AND revoked_at IS NULL", old.ID)).
+			UpdateAll(ctx, tx, entity.M{entity.RefreshTokenColumns.RevokedAt: now})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to revoke refresh token")
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		old.RevokedAt = null.TimeFrom(now)
+		if err = next.Insert(ctx, tx, boil.Infer()); err != nil {
+			log.Error().Err(err).Msg("failed to create refresh token")
+			return err
+		}
+		return nil
+	})
+}
+
+// RevokeRefreshToken revoke token, revoking it again changes nothing
+func (u *UserRepo) RevokeRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	return u.revokeRefreshTokens(ctx, qm.Where("id = ?", token.ID))
+}
+
+// RevokeRefreshTokens revoke every refresh token of the user uid
+func (u *UserRepo) RevokeRefreshTokens(ctx context.Context, uid uint64) error {
+	return u.revokeRefreshTokens(ctx, qm.Where("user_id = ?", uid))
 }
 
-func (u *UserRepo) GetFollowingUsers(user *entity.User) ([]*entity.User, error) {
-	following, err := user.FollowingUsers().All(context.Background(), u.Db)
+func (u *UserRepo) revokeRefreshTokens(ctx context.Context, where qm.QueryMod) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := entity.RefreshTokens(where, qm.Where("revoked_at IS NULL")).
+			UpdateAll(ctx, tx, entity.M{entity.RefreshTokenColumns.RevokedAt: time.Now()})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to revoke refresh tokens")
+			return err
+		}
+		return nil
+	})
+}
+
+// RevokeAccessToken deny the access token jti until it expires at expiresAt, revoking it again changes
+// nothing. The denials already expired are dropped on the way
+func (u *UserRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return inTx(ctx, u.Db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := entity.RevokedTokens(qm.Where("expires_at < ?", time.Now())).DeleteAll(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to drop expired revoked tokens")
+			return err
+		}
+		_, err = queries.Raw("INSERT IGNORE INTO revoked_tokens (jti, expires_at) VALUES (?, ?)", jti, expiresAt).
+			ExecContext(ctx, tx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to revoke access token")
+			return err
+		}
+		return nil
+	})
+}
+
+// IsAccessTokenRevoked tell whether the access token jti was revoked
+func (u *UserRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := entity.RevokedTokens(qm.Where("jti = ?", jti)).Exists(ctx, executor(ctx, u.Db))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get following")
-		return nil, err
+		log.Error().Err(err).Msg("failed to check revoked token")
+		return false, err
 	}
-	return following, nil
+	return revoked, nil
 }
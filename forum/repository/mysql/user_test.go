@@ -1,8 +1,11 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"regexp"
+	"schema/entity"
 	"testing"
 	"time"
 
@@ -22,7 +25,7 @@ func TestUserRepo_CreateUser(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewUserRepo(db)
-		err = repo.CreateUser(userFoo)
+		err = repo.CreateUser(context.Background(), userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -31,14 +34,14 @@ func TestUserRepo_CreateUser(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewUserRepo(db)
-		err = repo.CreateUser(userFoo)
+		err = repo.CreateUser(context.Background(), userFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transactions begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		err = repo.CreateUser(userFoo)
+		err = repo.CreateUser(context.Background(), userFoo)
 		assert.Errorf(t, err, "failed to start transaction")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -53,7 +56,7 @@ func TestUserRepo_UpdateUser(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("UPDATE")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewUserRepo(db)
-		err = repo.UpdateUser(userFoo)
+		err = repo.UpdateUser(context.Background(), userFoo)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -61,7 +64,7 @@ func TestUserRepo_UpdateUser(t *testing.T) {
 	t.Run("transactions begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		err = repo.UpdateUser(userFoo)
+		err = repo.UpdateUser(context.Background(), userFoo)
 		assert.Errorf(t, err, "failed to start transaction")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -70,7 +73,7 @@ func TestUserRepo_UpdateUser(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("UPDATE")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewUserRepo(db)
-		err = repo.UpdateUser(userFoo)
+		err = repo.UpdateUser(context.Background(), userFoo)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -82,26 +85,36 @@ func TestUserRepo_AddFollower(t *testing.T) {
 	defer db.Close()
 	t.Run("transaction rollback when add follower", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO follows (follower_id, following_id) VALUES (?, ?)")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewUserRepo(db)
-		err = repo.AddFollower(userFoo, userBar)
+		err = repo.AddFollower(context.Background(), userFoo, userBar)
 		assert.Errorf(t, err, "some error")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transactions begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		err = repo.AddFollower(userFoo, userBar)
+		err = repo.AddFollower(context.Background(), userFoo, userBar)
 		assert.Errorf(t, err, "failed to start transaction")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("transaction commit when add follower", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("insert into")).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO follows (follower_id, following_id) VALUES (?, ?)")).
+			WithArgs(userBar.ID, userFoo.ID).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewUserRepo(db)
-		err = repo.AddFollower(userFoo, userBar)
+		err = repo.AddFollower(context.Background(), userFoo, userBar)
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction commit when the follower already follows", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO follows")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		err = repo.AddFollower(context.Background(), userFoo, userBar)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -116,13 +129,13 @@ func TestUerRepo_RemoveFollower(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete")).WillReturnError(fmt.Errorf("some error"))
 		mock.ExpectRollback()
 		repo := NewUserRepo(db)
-		err = repo.RemoveFollower(userFoo, userBar)
+		err = repo.RemoveFollower(context.Background(), userFoo, userBar)
 		assert.Errorf(t, err, "some error")
 	})
 	t.Run("transactions begin with error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		err = repo.RemoveFollower(userFoo, userBar)
+		err = repo.RemoveFollower(context.Background(), userFoo, userBar)
 		assert.Errorf(t, err, "failed to start transaction")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -131,7 +144,7 @@ func TestUerRepo_RemoveFollower(t *testing.T) {
 		mock.ExpectExec(regexp.QuoteMeta("delete")).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		repo := NewUserRepo(db)
-		err = repo.RemoveFollower(userFoo, userBar)
+		err = repo.RemoveFollower(context.Background(), userFoo, userBar)
 		assert.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -148,7 +161,7 @@ func TestUserRepo_IsFollower(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.IsFollower(userFoo, userBar)
+		result, err := repo.IsFollower(context.Background(), userFoo, userBar)
 		assert.NoError(t, err)
 		assert.True(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -156,13 +169,22 @@ func TestUserRepo_IsFollower(t *testing.T) {
 	t.Run("when follower is false", func(t *testing.T) {
 		userBar.ID = 2
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
-			WillReturnError(fmt.Errorf("some error"))
+			WillReturnError(sql.ErrNoRows)
 		repo := NewUserRepo(db)
-		result, err := repo.IsFollower(userFoo, userBar)
+		result, err := repo.IsFollower(context.Background(), userFoo, userBar)
 		assert.NoError(t, err)
 		assert.False(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+	t.Run("when check follower failed", func(t *testing.T) {
+		userBar.ID = 2
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
+			WillReturnError(fmt.Errorf("some error"))
+		repo := NewUserRepo(db)
+		_, err := repo.IsFollower(context.Background(), userFoo, userBar)
+		assert.ErrorContains(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestUerRepo_GetFollowers(t *testing.T) {
@@ -173,21 +195,37 @@ func TestUerRepo_GetFollowers(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "username", "email", "password", "bio", "image", "created_at", "updated_at", "deleted_at", "following_id"}).
 			AddRow(2, "foo", "foo@foo.com", "foo-password", "foo desc", "http://foo.com/foo.jpg", null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), 2).
 			AddRow(2, "foo", "foo@foo.com", "foo-password", "foo desc", "http://foo.com/foo.jpg", null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), 2)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY users.username LIMIT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.GetFollowers(userFoo)
+		result, n, err := repo.GetFollowers(context.Background(), userFoo, 0, 2)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(result))
+		assert.Equal(t, int64(7), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count followers with error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnError(fmt.Errorf("some error"))
+		repo := NewUserRepo(db)
+		result, n, err := repo.GetFollowers(context.Background(), userFoo, 0, 2)
+		assert.Errorf(t, err, "some error")
+		assert.Nil(t, result)
+		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when get followers with error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		result, err := repo.GetFollowers(userFoo)
+		result, n, err := repo.GetFollowers(context.Background(), userFoo, 0, 2)
 		assert.Errorf(t, err, "some error")
 		assert.Nil(t, result)
+		assert.Equal(t, int64(0), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -200,19 +238,57 @@ func TestUerRepo_GetFollowingUsers(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "username", "email", "password", "bio", "image", "created_at", "updated_at", "deleted_at", "following_id"}).
 			AddRow(2, "foo", "foo@foo.com", "foo-password", "foo desc", "http://foo.com/foo.jpg", null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), 2).
 			AddRow(2, "foo", "foo@foo.com", "foo-password", "foo desc", "http://foo.com/foo.jpg", null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), null.TimeFrom(time.Now()), 2)
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY users.username LIMIT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.GetFollowingUsers(userFoo)
+		result, n, err := repo.GetFollowingUsers(context.Background(), userFoo, 0, 20)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(result))
+		assert.Equal(t, int64(2), n)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("when get following users with error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		result, err := repo.GetFollowingUsers(userFoo)
+		result, n, err := repo.GetFollowingUsers(context.Background(), userFoo, 0, 20)
+		assert.Errorf(t, err, "some error")
+		assert.Nil(t, result)
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_FindFollowingIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when no user ids given", func(t *testing.T) {
+		repo := NewUserRepo(db)
+		result, err := repo.FindFollowingIDs(context.Background(), userFoo, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find following ids", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `users`.`id`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		repo := NewUserRepo(db)
+		result, err := repo.FindFollowingIDs(context.Background(), userFoo, []uint64{2, 3})
+		assert.NoError(t, err)
+		assert.True(t, result[2])
+		assert.False(t, result[3])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when find following ids with error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `users`.`id`")).
+			WillReturnError(fmt.Errorf("some error"))
+		repo := NewUserRepo(db)
+		result, err := repo.FindFollowingIDs(context.Background(), userFoo, []uint64{2, 3})
 		assert.Errorf(t, err, "some error")
 		assert.Nil(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -229,7 +305,7 @@ func TestUserRepo_FindByID(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.FindUserByID(2)
+		result, err := repo.FindUserByID(context.Background(), 2)
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(2), result.ID)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -238,7 +314,7 @@ func TestUserRepo_FindByID(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		result, err := repo.FindUserByID(2)
+		result, err := repo.FindUserByID(context.Background(), 2)
 		assert.Errorf(t, err, "some error")
 		assert.Nil(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -255,7 +331,7 @@ func TestUserRepo_FindByEmail(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.FindByEmail("foo@foo.com")
+		result, err := repo.FindByEmail(context.Background(), "foo@foo.com")
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(2), result.ID)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -264,7 +340,7 @@ func TestUserRepo_FindByEmail(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		result, err := repo.FindByEmail("foo@foo.com")
+		result, err := repo.FindByEmail(context.Background(), "foo@foo.com")
 		assert.Errorf(t, err, "some error")
 		assert.Nil(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -281,7 +357,7 @@ func TestUserRepo_FindByUserName(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnRows(rows)
 		repo := NewUserRepo(db)
-		result, err := repo.FindUserByUserName("foo")
+		result, err := repo.FindUserByUserName(context.Background(), "foo")
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(2), result.ID)
 		require.NoError(t, mock.ExpectationsWereMet())
@@ -290,9 +366,256 @@ func TestUserRepo_FindByUserName(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT")).
 			WillReturnError(fmt.Errorf("some error"))
 		repo := NewUserRepo(db)
-		result, err := repo.FindUserByUserName("foo")
+		result, err := repo.FindUserByUserName(context.Background(), "foo")
 		assert.Errorf(t, err, "some error")
 		assert.Nil(t, result)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestUserRepo_DeleteUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("the user is trashed with its content", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `deleted_at`=? WHERE `id`=?")).
+			WithArgs(sqlmock.AnyArg(), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at` = ? WHERE (`articles`.`author_id` = ?) AND (`articles`.`deleted_at` is null)")).
+			WithArgs(sqlmock.AnyArg(), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (article_id IN (SELECT id FROM articles WHERE author_id = ? AND deleted_at = ?))")).
+			WithArgs(sqlmock.AnyArg(), uint64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`user_id` = ?) AND (id NOT IN (SELECT parent_id FROM (SELECT DISTINCT parent_id")).
+			WithArgs(sqlmock.AnyArg(), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`user_id` = ?) AND (id NOT IN")).
+			WithArgs(sqlmock.AnyArg(), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `body` = ?, `updated_at` = ?, `user_id` = ? WHERE (`comments`.`user_id` = ?)")).
+			WithArgs("[deleted]", sqlmock.AnyArg(), nil, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		err = repo.DeleteUser(context.Background(), &entity.User{ID: 1})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when delete articles of user failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `deleted_at`=? WHERE `id`=?")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewUserRepo(db)
+		err = repo.DeleteUser(context.Background(), &entity.User{ID: 1})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("the user is restored with its content", func(t *testing.T) {
+		deletedAt := null.TimeFrom(time.Now())
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `deleted_at`=? WHERE `id`=?")).
+			WithArgs(nil, uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `articles` SET `deleted_at` = ? WHERE (`articles`.`author_id` = ?) AND (`articles`.`deleted_at` = ?)")).
+			WithArgs(nil, uint64(1), deletedAt).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `comments` SET `deleted_at` = ? WHERE (`comments`.`deleted_at` = ?) AND ((user_id = ? OR article_id IN (SELECT id FROM articles WHERE author_id = ?)))")).
+			WithArgs(nil, deletedAt, uint64(1), uint64(1)).WillReturnResult(sqlmock.NewResult(0, 5))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		err = repo.RestoreUser(context.Background(), &entity.User{ID: 1, DeletedAt: deletedAt})
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_FindDeletedUserByUserName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when the user is in the trash", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `users`.* FROM `users` WHERE (`users`.`deleted_at` is not null) AND (`users`.`username` = ?)")).
+			WithArgs("foo").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "deleted_at"}).AddRow(1, "foo", time.Now()))
+		repo := NewUserRepo(db)
+		user, err := repo.FindDeletedUserByUserName(context.Background(), "foo")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), user.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when the user is not in the trash", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `users`.* FROM `users`")).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		repo := NewUserRepo(db)
+		_, err := repo.FindDeletedUserByUserName(context.Background(), "foo")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_ListDeletedUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("when list deleted users success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `users` WHERE (`users`.`deleted_at` is not null)")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY deleted_at DESC, id DESC LIMIT 1")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "foo"))
+		repo := NewUserRepo(db)
+		users, n, err := repo.ListDeletedUsers(context.Background(), 0, 1)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, int64(3), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("when count deleted users failed", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(fmt.Errorf("some error"))
+		repo := NewUserRepo(db)
+		_, n, err := repo.ListDeletedUsers(context.Background(), 0, 1)
+		assert.Errorf(t, err, "some error")
+		assert.Equal(t, int64(0), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_PurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("transaction commit when purge success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM follows WHERE follower_id IN (SELECT id FROM users WHERE deleted_at < ?")).
+			WithArgs(before, before).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE articles JOIN (SELECT article_id, COUNT(*) AS purged FROM favorites WHERE user_id IN (SELECT id FROM users WHERE deleted_at < ?")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM favorites WHERE user_id IN (SELECT id FROM users WHERE deleted_at < ?")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE deleted_at < ? AND id NOT IN (SELECT author_id FROM articles")).
+			WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		n, err := repo.PurgeDeleted(context.Background(), before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when purge failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM follows")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewUserRepo(db)
+		_, err := repo.PurgeDeleted(context.Background(), before)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_CreateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("transaction commit when create refresh token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `refresh_tokens` WHERE (user_id = ? AND expires_at < ?)")).
+			WithArgs(uint64(2), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		token := &entity.RefreshToken{UserID: 2, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+		err = repo.CreateRefreshToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(5), token.ID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when create refresh token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `refresh_tokens`")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewUserRepo(db)
+		err = repo.CreateRefreshToken(context.Background(), &entity.RefreshToken{UserID: 2})
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_RotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	revoke := regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at` = ? WHERE (id = ? AND revoked_at IS NULL)")
+	t.Run("transaction commit when the old token was live", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(revoke).WithArgs(sqlmock.AnyArg(), uint64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		old := &entity.RefreshToken{ID: 3}
+		err = repo.RotateRefreshToken(context.Background(), old, &entity.RefreshToken{UserID: 2})
+		assert.NoError(t, err)
+		assert.True(t, old.RevokedAt.Valid)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when the old token was revoked meanwhile", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(revoke).WithArgs(sqlmock.AnyArg(), uint64(3)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		repo := NewUserRepo(db)
+		err = repo.RotateRefreshToken(context.Background(), &entity.RefreshToken{ID: 3}, &entity.RefreshToken{UserID: 2})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_RevokeRefreshTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at` = ? WHERE (user_id = ?) AND (revoked_at IS NULL)")).
+		WithArgs(sqlmock.AnyArg(), uint64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	repo := NewUserRepo(db)
+	err = repo.RevokeRefreshTokens(context.Background(), 2)
+	assert.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_RevokeAccessToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	exp := time.Now().Add(time.Minute)
+	t.Run("transaction commit when revoke access token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `revoked_tokens` WHERE (expires_at < ?)")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO revoked_tokens (jti, expires_at) VALUES (?, ?)")).
+			WithArgs("abc", exp).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		repo := NewUserRepo(db)
+		err = repo.RevokeAccessToken(context.Background(), "abc", exp)
+		assert.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("transaction rollback when revoke access token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `revoked_tokens`")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO revoked_tokens")).WillReturnError(fmt.Errorf("some error"))
+		mock.ExpectRollback()
+		repo := NewUserRepo(db)
+		err = repo.RevokeAccessToken(context.Background(), "abc", exp)
+		assert.Errorf(t, err, "some error")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepo_IsAccessTokenRevoked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `revoked_tokens` WHERE (jti = ?) LIMIT 1;")).
+		WithArgs("abc").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	repo := NewUserRepo(db)
+	revoked, err := repo.IsAccessTokenRevoked(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
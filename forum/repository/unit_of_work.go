@@ -0,0 +1,9 @@
+package repository
+
+import "context"
+
+// UnitOfWork run several repository calls in a single transaction, the repositories called with
+// the ctx given to fn take part in it
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -3,19 +3,59 @@
 package repository
 
 import (
+	"context"
 	"schema/entity"
+	"time"
 )
 
 // IRepoUser ...
 type IRepoUser interface {
-	FindUserByID(uid uint) (*entity.User, error)
-	FindByEmail(s string) (*entity.User, error)
-	FindUserByUserName(s string) (*entity.User, error)
-	CreateUser(user *entity.User) error
-	UpdateUser(user *entity.User) error
-	AddFollower(user *entity.User, follower *entity.User) error
-	RemoveFollower(user *entity.User, follower *entity.User) error
-	IsFollower(user, follower *entity.User) (bool, error)
-	GetFollowers(user *entity.User) ([]*entity.User, error)
-	GetFollowingUsers(user *entity.User) ([]*entity.User, error)
+	FindUserByID(ctx context.Context, uid uint) (*entity.User, error)
+	FindByEmail(ctx context.Context, s string) (*entity.User, error)
+	FindUserByUserName(ctx context.Context, s string) (*entity.User, error)
+	CreateUser(ctx context.Context, user *entity.User) error
+	UpdateUser(ctx context.Context, user *entity.User) error
+	// AddFollower make follower follow user. Following a user twice changes nothing
+	AddFollower(ctx context.Context, user *entity.User, follower *entity.User) error
+	RemoveFollower(ctx context.Context, user *entity.User, follower *entity.User) error
+	// IsFollower tell whether follower follows user
+	IsFollower(ctx context.Context, user, follower *entity.User) (bool, error)
+	// GetFollowers list the users following user by username with pagination
+	GetFollowers(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.User, int64, error)
+	// GetFollowingUsers list the users followed by user by username with pagination
+	GetFollowingUsers(ctx context.Context, user *entity.User, offset, limit int) ([]*entity.User, int64, error)
+	// FindFollowingIDs return the subset of userIDs followed by follower
+	FindFollowingIDs(ctx context.Context, follower *entity.User, userIDs []uint64) (map[uint64]bool, error)
+	// DeleteUser move user to the trash with its articles, the comments of those articles and its comments, all
+	// stamped with the deletion time of user so that RestoreUser brings them back together.
+	// A comment of user which others still reply to stays in its thread as a placeholder instead, as when it is
+	// deleted alone
+	DeleteUser(ctx context.Context, user *entity.User) error
+	// FindDeletedUserByUserName find the trashed user named s
+	FindDeletedUserByUserName(ctx context.Context, s string) (*entity.User, error)
+	// ListDeletedUsers list the trashed users, the last deleted first
+	ListDeletedUsers(ctx context.Context, offset, limit int) ([]*entity.User, int64, error)
+	// RestoreUser take user out of the trash along with the articles and comments trashed with it
+	RestoreUser(ctx context.Context, user *entity.User) error
+	// PurgeDeleted remove for good the users trashed before before, with their follows and favorites, which are
+	// uncounted from the favorited articles.
+	// The users still authoring articles or comments are kept until those are purged.
+	// It returns the number of users removed
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// CreateRefreshToken store token, the expired refresh tokens of its user are dropped on the way
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	// FindRefreshToken find the refresh token whose sha256 is hash, revoked or expired alike
+	FindRefreshToken(ctx context.Context, hash string) (*entity.RefreshToken, error)
+	// RotateRefreshToken revoke old and store next in its place. It returns sql.ErrNoRows when old was
+	// revoked meanwhile, so that a refresh token is never rotated twice
+	RotateRefreshToken(ctx context.Context, old, next *entity.RefreshToken) error
+	// RevokeRefreshToken revoke token, revoking it again changes nothing
+	RevokeRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	// RevokeRefreshTokens revoke every refresh token of the user uid
+	RevokeRefreshTokens(ctx context.Context, uid uint64) error
+	// RevokeAccessToken deny the access token jti until it expires at expiresAt, revoking it again changes
+	// nothing. The denials already expired are dropped on the way
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsAccessTokenRevoked tell whether the access token jti was revoked
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
 }
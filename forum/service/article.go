@@ -3,24 +3,86 @@
 package service
 
 import (
+	"context"
+	"forum/model"
+	"forum/repository"
 	"schema/entity"
+	"time"
 )
 
 // IServiceArticle ...
 type IServiceArticle interface {
-	CreateArticle(a *entity.Article) error
-	UpdateArticle(slug string, newArticle *entity.Article) error
-	DeleteArticle(slug string) error
-	FindArticle(slug string) (*entity.Article, *entity.User, []*entity.Tag, error)
-	FindArticleByAuthor(userName string, offset, limit int) ([]*entity.Article, int64, error)
-	FindArticles(tag, author string, offset, limit int) ([]*entity.Article, int64, error)
-	FindCommentsBySlug(slug string, offset, limit int) ([]*entity.Comment, error)
-	FindAuthorBySlug(slug string) (*entity.User, error)
-	AddCommentToArticle(slug string, cm *entity.Comment) error
-	DeleteCommentFromArticle(slug string, commentId uint64) error
-	AddFavoriteArticleBySlug(slug string, uid uint) error
-	RemoveFavoriteArticleBySlug(slug string, uid uint) error
-	FindArticleAndUserBySlugAndUserID(slug string, uid uint) (*entity.Article, *entity.User, error)
-	AddTagToArticle(slug string, tagStr []string) error
-	GetAllTags() ([]*entity.Tag, error)
+	// CreateArticle insert the article under a slug derived from its title, tagged with tags
+	CreateArticle(ctx context.Context, a *entity.Article, tags []string) error
+	// UpdateArticle update the article identified by slug, only its author uid is allowed to.
+	// A new title gives the article a new slug, the previous one is kept as a redirect.
+	// The tags of the article are replaced unless tags is nil
+	UpdateArticle(ctx context.Context, uid uint, slug string, newArticle *entity.Article, tags []string) (*entity.Article, error)
+	// DeleteArticle delete the article identified by slug, only its author uid or a moderator is allowed to
+	DeleteArticle(ctx context.Context, uid uint, slug string) error
+	FindArticle(ctx context.Context, slug string) (*entity.Article, *entity.User, []*entity.Tag, error)
+	FindArticleBySlug(ctx context.Context, slug string) (*entity.Article, error)
+	// FindArticleBySlugRedirect find the article which was renamed away from the old slug
+	FindArticleBySlugRedirect(ctx context.Context, slug string) (*entity.Article, error)
+	// ArticleResponse build the response of a single article as seen by the viewer uid, uid 0 means anonymous
+	ArticleResponse(ctx context.Context, uid uint, a *entity.Article) (*model.SingleArticleResponse, error)
+	// ArticleListResponse build the response of a page of articles as seen by the viewer uid, uid 0 means anonymous
+	ArticleListResponse(ctx context.Context, uid uint, articles []*entity.Article, count int64) (*model.ArticleListResponse, error)
+	FindArticleByAuthor(ctx context.Context, userName string, page model.Page) ([]*entity.Article, int64, error)
+	// FindArticles list a page of the articles matching filter, and count them all
+	FindArticles(ctx context.Context, filter model.ArticleFilter, page model.Page) ([]*entity.Article, int64, error)
+	// SearchArticles list a page of the articles matching the full-text query q and filter, the most
+	// relevant first, and count them all
+	SearchArticles(ctx context.Context, q string, filter model.ArticleFilter, page model.Page) ([]*repository.SearchHit, int64, error)
+	// FindFeed list the articles written by the users that uid follows
+	FindFeed(ctx context.Context, uid uint, page model.Page) ([]*entity.Article, int64, error)
+	// FindCommentsBySlug list a page of the threads of comments of the article identified by slug, and count
+	// them all. The comments come in reading order, every one followed by its replies
+	FindCommentsBySlug(ctx context.Context, slug string, page model.Page) ([]*entity.Comment, int64, error)
+	FindAuthorBySlug(ctx context.Context, slug string) (*entity.User, error)
+	// AddCommentToArticle add cm to the article identified by slug, as a reply to the comment cm.ParentID of
+	// the same article when set. Replies nest at most MaxCommentDepth levels below the top-level comments
+	AddCommentToArticle(ctx context.Context, slug string, cm *entity.Comment) error
+	// DeleteCommentFromArticle delete a comment of the article identified by slug, only the comment author uid or a
+	// moderator is allowed to
+	DeleteCommentFromArticle(ctx context.Context, uid uint, slug string, commentId uint64) error
+	// UpdateCommentOfArticle replace the body of the comment commentId of the article identified by slug, only the
+	// comment author uid is allowed to. The former body is kept in the history of the comment
+	UpdateCommentOfArticle(ctx context.Context, uid uint, slug string, commentId uint64, body string) (*entity.Comment, error)
+	// FindCommentHistory find the comment commentId of the article identified by slug, along with its former
+	// bodies, the oldest first. Only a moderator uid is allowed to
+	FindCommentHistory(ctx context.Context, uid uint, slug string, commentId uint64) (*entity.Comment, []*entity.CommentRevision, error)
+	// FindTrashedArticles list the articles of uid in the trash, the last deleted first
+	FindTrashedArticles(ctx context.Context, uid uint, offset, limit int) ([]*entity.Article, int64, error)
+	// FindTrashedComments list the comments of uid in the trash, the last deleted first
+	FindTrashedComments(ctx context.Context, uid uint, offset, limit int) ([]*entity.Comment, int64, error)
+	// RestoreArticle take the article of uid identified by slug out of the trash
+	RestoreArticle(ctx context.Context, uid uint, slug string) (*entity.Article, error)
+	// RestoreComment take the comment commentID out of the trash, only its author uid is allowed to.
+	// A reply cannot come back while the comment it replies to is deleted
+	RestoreComment(ctx context.Context, uid uint, commentID uint64) (*entity.Comment, error)
+	// PurgeTrash remove for good what was trashed before before, the users last as their content has to go
+	// first. It returns the number of rows removed
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	// AddFavoriteArticleBySlug make the article identified by slug a favorite of uid, favoriting it again changes
+	// nothing. It returns the article with its favorites counted
+	AddFavoriteArticleBySlug(ctx context.Context, slug string, uid uint) (*entity.Article, error)
+	// RemoveFavoriteArticleBySlug take the article identified by slug out of the favorites of uid, unfavoriting it
+	// again changes nothing. It returns the article with its favorites counted
+	RemoveFavoriteArticleBySlug(ctx context.Context, slug string, uid uint) (*entity.Article, error)
+	// FindFavoriteArticles list a page of the articles favorited by the user named userName, and count them all
+	FindFavoriteArticles(ctx context.Context, userName string, page model.Page) ([]*entity.Article, int64, error)
+	FindArticleAndUserBySlugAndUserID(ctx context.Context, slug string, uid uint) (*entity.Article, *entity.User, error)
+	// AddTagToArticle tag the article identified by slug, unknown tags are created. Only its author uid is allowed to
+	AddTagToArticle(ctx context.Context, uid uint, slug string, tagStr []string) (*entity.Article, error)
+	// RemoveTagFromArticle untag the article identified by slug, only its author uid is allowed to
+	RemoveTagFromArticle(ctx context.Context, uid uint, slug string, tagStr string) (*entity.Article, error)
+	GetAllTags(ctx context.Context) ([]*entity.Tag, error)
+	// GetPopularTags list the tags used by at least one article, the most used first, with their usage counts
+	GetPopularTags(ctx context.Context) ([]*entity.Tag, map[uint64]int, error)
+	// RenameTag rename the tag name to newName, merge it instead into the tag already named newName, taking that one
+	// out of the trash if needed. Only an admin uid is allowed to
+	RenameTag(ctx context.Context, uid uint, name, newName string) error
+	// MergeTags retag the articles tagged with from by into, then delete from. Only an admin uid is allowed to
+	MergeTags(ctx context.Context, uid uint, from, into string) error
 }
@@ -1,6 +1,7 @@
 package article
 
 import (
+	"context"
 	"schema/entity"
 
 	"github.com/volatiletech/null/v8"
@@ -17,7 +18,10 @@ var (
 		Email:    "bar@bar.com",
 		Password: "123456",
 	}
-	articleFoo = &entity.Article{
+	memberFoo    = &entity.User{ID: 2, Username: "member", Role: entity.UsersRoleUser}
+	moderatorFoo = &entity.User{ID: 2, Username: "moderator", Role: entity.UsersRoleModerator}
+	adminFoo     = &entity.User{ID: 1, Username: "admin", Role: entity.UsersRoleAdmin}
+	articleFoo   = &entity.Article{
 		Title:       "foo Title",
 		Description: null.NewString("foo Description", false),
 		Body:        null.NewString("foo Body", false),
@@ -35,3 +39,10 @@ var (
 		UserID:    null.Uint64From(1),
 	}
 )
+
+// directUnitOfWork run the unit of work straight away, the mocked repositories have no transaction
+type directUnitOfWork struct{}
+
+func (directUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -1,276 +1,765 @@
 package article
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"schema/entity"
 	"sort"
+	"strings"
+	"time"
 
+	"forum/domain"
+	"forum/model"
 	"forum/repository"
 
 	"github.com/rs/zerolog/log"
+	"github.com/volatiletech/null/v8"
 )
 
 type Service struct {
 	Repo     repository.IRepoArticle
 	UserRepo repository.IRepoUser
+	Tx       repository.UnitOfWork
+	Search   repository.ArticleSearch
 }
 
-func NewServiceArticle(r repository.IRepoArticle, u repository.IRepoUser) *Service {
+func NewServiceArticle(r repository.IRepoArticle, u repository.IRepoUser, tx repository.UnitOfWork, search repository.ArticleSearch) *Service {
 	return &Service{
 		Repo:     r,
 		UserRepo: u,
+		Tx:       tx,
+		Search:   search,
 	}
 }
 
-func (r *Service) CreateArticle(a *entity.Article) error {
-	return r.Repo.CreateArticle(a)
+// CreateArticle insert the article under a slug derived from its title, tagged with tags
+func (r *Service) CreateArticle(ctx context.Context, a *entity.Article, tags []string) error {
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		slug, err := r.uniqueSlug(ctx, a.Title, 0)
+		if err != nil {
+			log.Error().Err(err).Msg("FindTakenSlugs error")
+			return err
+		}
+		a.Slug = slug
+		return r.Repo.CreateArticle(ctx, a, normalizeTags(tags))
+	})
+	return domain.Wrap("article", err)
 }
 
-func (r *Service) UpdateArticle(slug string, newArticle *entity.Article) error {
-	as, err := r.Repo.FindArticleBySlug(slug)
-	if err != nil {
-		log.Error().Err(err).Msg("FindArticleBySlug error")
+// UpdateArticle update the article identified by slug, only its author uid is allowed to.
+// A new title gives the article a new slug, the previous one is kept as a redirect.
+// The tags of the article are replaced unless tags is nil
+func (r *Service) UpdateArticle(ctx context.Context, uid uint, slug string, newArticle *entity.Article, tags []string) (*entity.Article, error) {
+	tags = normalizeTags(tags)
+	var as *entity.Article
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		as, err = r.findOwnedArticle(ctx, uid, slug)
+		if err != nil {
+			return err
+		}
+		if newArticle.Body.Valid {
+			as.Body = newArticle.Body
+		}
+		if newArticle.Description.Valid {
+			as.Description = newArticle.Description
+		}
+		renamed := false
+		if newArticle.Title != "" && newArticle.Title != as.Title {
+			as.Title = newArticle.Title
+			as.Slug, err = r.uniqueSlug(ctx, as.Title, as.ID)
+			if err != nil {
+				log.Error().Err(err).Msg("FindTakenSlugs error")
+				return err
+			}
+			renamed = as.Slug != slug
+		}
+		if !renamed {
+			err = r.Repo.UpdateArticle(ctx, as, tags)
+		} else {
+			err = r.Repo.RenameArticle(ctx, as, slug, tags)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("UpdateArticle error")
+		}
 		return err
+	})
+	if err != nil {
+		return nil, domain.Wrap("article", err)
 	}
-	if newArticle.Body.Valid {
-		as.Body.String = newArticle.Body.String
-	}
-	if newArticle.Slug != "" {
-		as.Slug = newArticle.Slug
-	}
-	if newArticle.Title != "" {
-		as.Title = newArticle.Title
-	}
-	if newArticle.Description.Valid {
-		as.Description = newArticle.Description
-	}
-	err = r.Repo.UpdateArticle(as)
+	return as, nil
+}
+
+// moderators are the roles allowed to delete the articles and comments of others
+var moderators = []string{entity.UsersRoleModerator, entity.UsersRoleAdmin}
+
+// requireRole check the user uid has one of roles, access to resource is forbidden otherwise. The role is read
+// from the database rather than trusted from the token
+func (r *Service) requireRole(ctx context.Context, uid uint, resource string, roles ...string) error {
+	u, err := r.UserRepo.FindUserByID(ctx, uid)
 	if err != nil {
-		log.Error().Err(err).Msg("UpdateArticle error")
-		return err
+		log.Error().Err(err).Msg("FindUserByID error")
+		return domain.Wrap("user", err)
 	}
-	return nil
+	for _, role := range roles {
+		if u.Role == role {
+			return nil
+		}
+	}
+	return domain.Forbidden(resource)
 }
 
-func (r *Service) DeleteArticle(slug string) error {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// DeleteArticle delete the article identified by slug, only its author uid or a moderator is allowed to
+func (r *Service) DeleteArticle(ctx context.Context, uid uint, slug string) error {
+	a, err := r.findOwnedArticle(ctx, uid, slug)
+	if domain.KindOf(err) == domain.KindForbidden {
+		if err = r.requireRole(ctx, uid, "article", moderators...); err != nil {
+			return err
+		}
+		a, err = r.Repo.FindArticleBySlug(ctx, slug)
+		if err != nil {
+			log.Error().Err(err).Msg("FindArticleBySlug error")
+			return domain.Wrap("article", err)
+		}
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("FindArticleBySlug error")
 		return err
 	}
-	err = r.Repo.DeleteArticle(a)
+	err = r.Repo.DeleteArticle(ctx, a)
 	if err != nil {
 		log.Error().Err(err).Msg("DeleteArticle error")
-		return err
+		return domain.Wrap("article", err)
 	}
 	return nil
 }
 
-func (r *Service) FindArticle(slug string) (*entity.Article, *entity.User, []*entity.Tag, error) {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// findOwnedArticle find the article by slug and author, when uid is not the author it tells
+// a missing article apart from a forbidden one
+func (r *Service) findOwnedArticle(ctx context.Context, uid uint, slug string) (*entity.Article, error) {
+	a, err := r.Repo.FindArticleByAuthorIDAndSlug(ctx, uint64(uid), slug)
+	if err == nil {
+		return a, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Error().Err(err).Msg("FindArticleByAuthorIDAndSlug error")
+		return nil, domain.Internal(err)
+	}
+	if _, err := r.Repo.FindArticleBySlug(ctx, slug); err != nil {
+		log.Error().Err(err).Msg("FindArticleBySlug error")
+		return nil, domain.Wrap("article", err)
+	}
+	return nil, domain.Forbidden("article")
+}
+
+func (r *Service) FindArticle(ctx context.Context, slug string) (*entity.Article, *entity.User, []*entity.Tag, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return nil, nil, nil, err
+		return nil, nil, nil, domain.Wrap("article", err)
 	}
-	u, err := r.Repo.FindAuthorByArticle(a)
+	u, err := r.Repo.FindAuthorByArticle(ctx, a)
 	if err != nil {
 		log.Error().Err(err).Msg("FindAuthorByArticle error")
-		return nil, nil, nil, err
+		return nil, nil, nil, domain.Wrap("author", err)
 	}
-	t, err := r.Repo.FindTagsByArticle(a)
+	t, err := r.Repo.FindTagsByArticle(ctx, a)
 	if err != nil {
 		log.Error().Err(err).Msg("FindTagsByArticle error")
-		return nil, nil, nil, err
+		return nil, nil, nil, domain.Wrap("tags", err)
 	}
 	return a, u, t, nil
 }
 
-func (r *Service) FindArticleByAuthor(userName string, offset, limit int) ([]*entity.Article, int64, error) {
-	u, err := r.UserRepo.FindUserByUserName(userName)
+func (r *Service) FindArticleBySlug(ctx context.Context, slug string) (*entity.Article, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
+	if err != nil {
+		log.Error().Err(err).Msg("FindArticleBySlug error")
+		return nil, domain.Wrap("article", err)
+	}
+	return a, nil
+}
+
+// FindArticleBySlugRedirect find the article which was renamed away from the old slug
+func (r *Service) FindArticleBySlugRedirect(ctx context.Context, slug string) (*entity.Article, error) {
+	a, err := r.Repo.FindArticleBySlugRedirect(ctx, slug)
+	if err != nil {
+		log.Error().Err(err).Msg("FindArticleBySlugRedirect error")
+		return nil, domain.Wrap("article", err)
+	}
+	return a, nil
+}
+
+// ArticleResponse build the response of a single article as seen by the viewer uid, uid 0 means anonymous
+func (r *Service) ArticleResponse(ctx context.Context, uid uint, a *entity.Article) (*model.SingleArticleResponse, error) {
+	d, err := r.findArticleDetails(ctx, uid, []*entity.Article{a})
+	if err != nil {
+		return nil, err
+	}
+	return SingleArticleResponseMapper(a, d), nil
+}
+
+// ArticleListResponse build the response of a page of articles as seen by the viewer uid, uid 0 means anonymous
+func (r *Service) ArticleListResponse(ctx context.Context, uid uint, articles []*entity.Article, count int64) (*model.ArticleListResponse, error) {
+	d, err := r.findArticleDetails(ctx, uid, articles)
+	if err != nil {
+		return nil, err
+	}
+	return ArticleListResponseMapper(articles, d, count), nil
+}
+
+// findArticleDetails load authors, tags and favorites of articles with a fixed number of queries, their
+// favorites are counted in the articles themselves
+func (r *Service) findArticleDetails(ctx context.Context, uid uint, articles []*entity.Article) (*ArticleDetails, error) {
+	var err error
+	d := &ArticleDetails{
+		Favorited: map[uint64]bool{},
+		Following: map[uint64]bool{},
+	}
+	if d.Authors, err = r.Repo.FindAuthorsByArticles(ctx, articles); err != nil {
+		log.Error().Err(err).Msg("FindAuthorsByArticles error")
+		return nil, domain.Wrap("authors", err)
+	}
+	if d.Tags, err = r.Repo.FindTagsByArticles(ctx, articles); err != nil {
+		log.Error().Err(err).Msg("FindTagsByArticles error")
+		return nil, domain.Wrap("tags", err)
+	}
+	if uid == 0 {
+		return d, nil
+	}
+	if d.Favorited, err = r.Repo.FindFavoritedArticleIDs(ctx, uint64(uid), articles); err != nil {
+		log.Error().Err(err).Msg("FindFavoritedArticleIDs error")
+		return nil, domain.Wrap("favorites", err)
+	}
+	authorIDs := make([]uint64, 0, len(d.Authors))
+	for id := range d.Authors {
+		authorIDs = append(authorIDs, id)
+	}
+	if d.Following, err = r.UserRepo.FindFollowingIDs(ctx, &entity.User{ID: uint64(uid)}, authorIDs); err != nil {
+		log.Error().Err(err).Msg("FindFollowingIDs error")
+		return nil, domain.Wrap("follows", err)
+	}
+	return d, nil
+}
+
+func (r *Service) FindArticleByAuthor(ctx context.Context, userName string, page model.Page) ([]*entity.Article, int64, error) {
+	u, err := r.UserRepo.FindUserByUserName(ctx, userName)
 	if err != nil {
 		log.Error().Err(err).Msg("FindByUserName error")
-		return nil, 0, err
+		return nil, 0, domain.Wrap("user", err)
 	}
-	a, n, err := r.Repo.ListArticlesByAuthor(u, offset, limit)
+	a, n, err := r.Repo.ListArticlesByAuthor(ctx, u, page)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleByID error")
-		return nil, 0, err
+		return nil, 0, domain.Wrap("articles", err)
 	}
 	return a, n, nil
 }
 
-func (r *Service) FindArticles(tag, author string, offset, limit int) ([]*entity.Article, int64, error) {
-	user, err := r.UserRepo.FindUserByUserName(author)
+// FindArticles list a page of the articles matching filter, and count them all
+func (r *Service) FindArticles(ctx context.Context, filter model.ArticleFilter, page model.Page) ([]*entity.Article, int64, error) {
+	a, n, err := r.Repo.ListArticles(ctx, filter, page)
 	if err != nil {
-		log.Error().Err(err).Msg("FindByUserName error")
-		return nil, 0, err
+		log.Error().Err(err).Msg("ListArticles error")
+		return nil, 0, domain.Wrap("articles", err)
 	}
-	if tag != "" {
-		a, n, err := r.Repo.ListArticlesByTag(tag, offset, limit)
-		if err != nil {
-			log.Error().Err(err).Msg("FindArticlesByTag error")
-			return nil, 0, err
-		}
-		return a, n, nil
-	} else if author != "" {
-		a, n, err := r.Repo.ListArticlesByAuthor(user, offset, limit)
-		if err != nil {
-			log.Error().Err(err).Msg("FindArticleByAuthor error")
-			return nil, 0, err
-		}
-		return a, n, nil
-	} else {
-		a, n, err := r.Repo.FindArticles(offset, limit)
-		if err != nil {
-			log.Error().Err(err).Msg("FindArticleByID error")
-			return nil, 0, err
-		}
-		return a, n, nil
+	return a, n, nil
+}
+
+// SearchArticles list a page of the articles matching the full-text query q and filter, the most
+// relevant first, and count them all
+func (r *Service) SearchArticles(ctx context.Context, q string, filter model.ArticleFilter, page model.Page) ([]*repository.SearchHit, int64, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, 0, domain.Validation("search query is required")
 	}
+	hits, n, err := r.Search.SearchArticles(ctx, q, filter, page)
+	if err != nil {
+		log.Error().Err(err).Msg("SearchArticles error")
+		return nil, 0, domain.Wrap("articles", err)
+	}
+	return hits, n, nil
+}
+
+// FindFeed list the articles written by the users that uid follows
+func (r *Service) FindFeed(ctx context.Context, uid uint, page model.Page) ([]*entity.Article, int64, error) {
+	a, n, err := r.Repo.ListFeed(ctx, uid, page)
+	if err != nil {
+		log.Error().Err(err).Msg("ListFeed error")
+		return nil, 0, domain.Wrap("articles", err)
+	}
+	return a, n, nil
 }
 
-func (r *Service) FindCommentsBySlug(slug string, offset, limit int) ([]*entity.Comment, error) {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// FindCommentsBySlug list a page of the threads of comments of the article identified by slug, and count
+// them all. The comments come in reading order, every one followed by its replies
+func (r *Service) FindCommentsBySlug(ctx context.Context, slug string, page model.Page) ([]*entity.Comment, int64, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return nil, err
+		return nil, 0, domain.Wrap("article", err)
 	}
-	c, err := r.Repo.FindCommentsByArticle(a, offset, limit)
+	c, n, err := r.Repo.FindCommentsByArticle(ctx, a, page)
 	if err != nil {
 		log.Error().Err(err).Msg("FindCommentsBySlug error")
-		return nil, err
+		return nil, 0, domain.Wrap("comments", err)
 	}
-	return c, nil
+	replies, err := r.Repo.FindRepliesByRoots(ctx, c)
+	if err != nil {
+		log.Error().Err(err).Msg("FindRepliesByRoots error")
+		return nil, 0, domain.Wrap("comments", err)
+	}
+	return threadOrder(c, replies), n, nil
 }
 
-func (r *Service) FindAuthorBySlug(slug string) (*entity.User, error) {
-	a, err := r.Repo.FindArticleBySlug(slug)
+func (r *Service) FindAuthorBySlug(ctx context.Context, slug string) (*entity.User, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return nil, err
+		return nil, domain.Wrap("article", err)
 	}
-	u, err := r.Repo.FindAuthorByArticle(a)
+	u, err := r.Repo.FindAuthorByArticle(ctx, a)
 	if err != nil {
 		log.Error().Err(err).Msg("FindAuthorByArticle error")
-		return nil, err
+		return nil, domain.Wrap("author", err)
 	}
 	return u, nil
 }
 
-func (r *Service) AddCommentToArticle(slug string, cm *entity.Comment) error {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// AddCommentToArticle add cm to the article identified by slug, as a reply to the comment cm.ParentID of
+// the same article when set. Replies nest at most MaxCommentDepth levels below the top-level comments
+func (r *Service) AddCommentToArticle(ctx context.Context, slug string, cm *entity.Comment) error {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return err
+		return domain.Wrap("article", err)
 	}
-	err = r.Repo.AddComment(a, cm)
+	if cm.ParentID.Valid {
+		parent, err := r.Repo.FindCommentByID(ctx, cm.ParentID.Uint64)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Error().Err(err).Msg("FindCommentByID error")
+			return domain.Wrap("comment", err)
+		}
+		if err != nil || parent.ArticleID.Uint64 != a.ID {
+			return domain.Validation("parent comment not found on this article")
+		}
+		if parent.Depth >= MaxCommentDepth {
+			return domain.Validation(fmt.Sprintf("replies nest at most %d levels deep", MaxCommentDepth))
+		}
+		cm.Depth = parent.Depth + 1
+		cm.RootID = parent.RootID
+		if !parent.RootID.Valid {
+			cm.RootID = null.Uint64From(parent.ID)
+		}
+	}
+	err = r.Repo.AddComment(ctx, a, cm)
 	if err != nil {
 		log.Error().Err(err).Msg("AddComment error")
-		return err
+		return domain.Wrap("comment", err)
 	}
 	return nil
 }
 
-func (r *Service) DeleteCommentFromArticle(slug string, commentId uint64) error {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// DeleteCommentFromArticle delete a comment of the article identified by slug, only the comment author uid or a
+// moderator is allowed to
+func (r *Service) DeleteCommentFromArticle(ctx context.Context, uid uint, slug string, commentId uint64) error {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return err
+		return domain.Wrap("article", err)
 	}
-	c, err := r.Repo.FindCommentByID(commentId)
+	c, err := r.Repo.FindCommentByID(ctx, commentId)
 	if err != nil {
 		log.Error().Err(err).Msg("FindCommentByID error")
-		return err
+		return domain.Wrap("comment", err)
+	}
+	if c.ArticleID.Uint64 != a.ID {
+		return domain.NotFound("comment")
+	}
+	if !c.UserID.Valid || c.UserID.Uint64 != uint64(uid) {
+		if err = r.requireRole(ctx, uid, "comment", moderators...); err != nil {
+			return err
+		}
 	}
-	err = r.Repo.DeleteCommentByArticle(a, c)
+	err = r.Repo.DeleteCommentByArticle(ctx, a, c)
 	if err != nil {
 		log.Error().Err(err).Msg("DeleteCommentByArticle error")
-		return err
+		return domain.Wrap("comment", err)
 	}
 	return nil
 }
 
-func (r *Service) AddFavoriteArticleBySlug(slug string, uid uint) error {
-	a, u, err := r.FindArticleAndUserBySlugAndUserID(slug, uid)
+// UpdateCommentOfArticle replace the body of the comment commentId of the article identified by slug, only the
+// comment author uid is allowed to. The former body is kept in the history of the comment
+func (r *Service) UpdateCommentOfArticle(ctx context.Context, uid uint, slug string, commentId uint64, body string) (*entity.Comment, error) {
+	c, err := r.findCommentOfArticle(ctx, slug, commentId)
+	if err != nil {
+		return nil, err
+	}
+	if !c.UserID.Valid || c.UserID.Uint64 != uint64(uid) {
+		return nil, domain.Forbidden("comment")
+	}
+	if c.Body.String == body {
+		return c, nil
+	}
+	err = r.Repo.EditComment(ctx, c, body)
+	if err != nil {
+		log.Error().Err(err).Msg("EditComment error")
+		return nil, domain.Wrap("comment", err)
+	}
+	return c, nil
+}
+
+// FindCommentHistory find the comment commentId of the article identified by slug, along with its former
+// bodies, the oldest first. Only a moderator uid is allowed to
+func (r *Service) FindCommentHistory(ctx context.Context, uid uint, slug string, commentId uint64) (*entity.Comment, []*entity.CommentRevision, error) {
+	if err := r.requireRole(ctx, uid, "comment history", moderators...); err != nil {
+		return nil, nil, err
+	}
+	c, err := r.findCommentOfArticle(ctx, slug, commentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := r.Repo.ListCommentRevisions(ctx, c)
+	if err != nil {
+		log.Error().Err(err).Msg("ListCommentRevisions error")
+		return nil, nil, domain.Wrap("comment revisions", err)
+	}
+	return c, revisions, nil
+}
+
+// findCommentOfArticle find the comment commentId, NotFound unless it belongs to the article identified by slug
+func (r *Service) findCommentOfArticle(ctx context.Context, slug string, commentId uint64) (*entity.Comment, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
+	if err != nil {
+		log.Error().Err(err).Msg("FindArticleBySlug error")
+		return nil, domain.Wrap("article", err)
+	}
+	c, err := r.Repo.FindCommentByID(ctx, commentId)
+	if err != nil {
+		log.Error().Err(err).Msg("FindCommentByID error")
+		return nil, domain.Wrap("comment", err)
+	}
+	if c.ArticleID.Uint64 != a.ID {
+		return nil, domain.NotFound("comment")
+	}
+	return c, nil
+}
+
+// FindTrashedArticles list the articles of uid in the trash, the last deleted first
+func (r *Service) FindTrashedArticles(ctx context.Context, uid uint, offset, limit int) ([]*entity.Article, int64, error) {
+	a, n, err := r.Repo.ListDeletedArticlesByAuthor(ctx, &entity.User{ID: uint64(uid)}, offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("ListDeletedArticlesByAuthor error")
+		return nil, 0, domain.Wrap("articles", err)
+	}
+	return a, n, nil
+}
+
+// FindTrashedComments list the comments of uid in the trash, the last deleted first
+func (r *Service) FindTrashedComments(ctx context.Context, uid uint, offset, limit int) ([]*entity.Comment, int64, error) {
+	c, n, err := r.Repo.ListDeletedCommentsByAuthor(ctx, &entity.User{ID: uint64(uid)}, offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("ListDeletedCommentsByAuthor error")
+		return nil, 0, domain.Wrap("comments", err)
+	}
+	return c, n, nil
+}
+
+// RestoreArticle take the article of uid identified by slug out of the trash
+func (r *Service) RestoreArticle(ctx context.Context, uid uint, slug string) (*entity.Article, error) {
+	a, err := r.Repo.FindDeletedArticleByAuthorIDAndSlug(ctx, uint64(uid), slug)
+	if err != nil {
+		log.Error().Err(err).Msg("FindDeletedArticleByAuthorIDAndSlug error")
+		return nil, domain.Wrap("article", err)
+	}
+	err = r.Repo.RestoreArticle(ctx, a)
+	if err != nil {
+		log.Error().Err(err).Msg("RestoreArticle error")
+		return nil, domain.Wrap("article", err)
+	}
+	return a, nil
+}
+
+// RestoreComment take the comment commentID out of the trash, only its author uid is allowed to.
+// A reply cannot come back while the comment it replies to is deleted
+func (r *Service) RestoreComment(ctx context.Context, uid uint, commentID uint64) (*entity.Comment, error) {
+	c, err := r.Repo.FindDeletedCommentByID(ctx, commentID)
+	if err != nil {
+		log.Error().Err(err).Msg("FindDeletedCommentByID error")
+		return nil, domain.Wrap("comment", err)
+	}
+	if !c.UserID.Valid || c.UserID.Uint64 != uint64(uid) {
+		return nil, domain.Forbidden("comment")
+	}
+	if c.ParentID.Valid {
+		_, err = r.Repo.FindCommentByID(ctx, c.ParentID.Uint64)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.Conflict("the comment replied to is deleted")
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("FindCommentByID error")
+			return nil, domain.Wrap("comment", err)
+		}
+	}
+	err = r.Repo.RestoreComment(ctx, c)
+	if err != nil {
+		log.Error().Err(err).Msg("RestoreComment error")
+		return nil, domain.Wrap("comment", err)
+	}
+	return c, nil
+}
+
+// PurgeTrash remove for good what was trashed before before, the users last as their content has to go
+// first. It returns the number of rows removed
+func (r *Service) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		n, err := r.Repo.PurgeDeleted(ctx, before)
+		if err != nil {
+			log.Error().Err(err).Msg("PurgeDeleted articles error")
+			return err
+		}
+		u, err := r.UserRepo.PurgeDeleted(ctx, before)
+		if err != nil {
+			log.Error().Err(err).Msg("PurgeDeleted users error")
+			return err
+		}
+		purged = n + u
+		return nil
+	})
+	if err != nil {
+		return 0, domain.Wrap("trash", err)
+	}
+	return purged, nil
+}
+
+// AddFavoriteArticleBySlug make the article identified by slug a favorite of uid, favoriting it again changes
+// nothing. It returns the article with its favorites counted
+func (r *Service) AddFavoriteArticleBySlug(ctx context.Context, slug string, uid uint) (*entity.Article, error) {
+	a, u, err := r.FindArticleAndUserBySlugAndUserID(ctx, slug, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleAndUserBySlugAndUserID error")
-		return err
+		return nil, err
 	}
-	err = r.Repo.AddFavoriteArticle(a, u)
+	err = r.Repo.AddFavoriteArticle(ctx, a, u)
 	if err != nil {
 		log.Error().Err(err).Msg("AddFavoriteArticle error")
-		return err
+		return nil, domain.Wrap("favorite", err)
 	}
-	return nil
+	return a, nil
 }
 
-func (r *Service) RemoveFavoriteArticleBySlug(slug string, uid uint) error {
-	a, u, err := r.FindArticleAndUserBySlugAndUserID(slug, uid)
+// RemoveFavoriteArticleBySlug take the article identified by slug out of the favorites of uid, unfavoriting it
+// again changes nothing. It returns the article with its favorites counted
+func (r *Service) RemoveFavoriteArticleBySlug(ctx context.Context, slug string, uid uint) (*entity.Article, error) {
+	a, u, err := r.FindArticleAndUserBySlugAndUserID(ctx, slug, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleAndUserBySlugAndUserID error")
-		return err
+		return nil, err
 	}
-	err = r.Repo.RemoveFavorite(a, u)
+	err = r.Repo.RemoveFavorite(ctx, a, u)
 	if err != nil {
 		log.Error().Err(err).Msg("RemoveFavorite error")
-		return err
+		return nil, domain.Wrap("favorite", err)
 	}
-	return nil
+	return a, nil
 }
 
-func (r *Service) FindArticleAndUserBySlugAndUserID(slug string, uid uint) (*entity.Article, *entity.User, error) {
-	a, err := r.Repo.FindArticleBySlug(slug)
+// FindFavoriteArticles list a page of the articles favorited by the user named userName, and count them all
+func (r *Service) FindFavoriteArticles(ctx context.Context, userName string, page model.Page) ([]*entity.Article, int64, error) {
+	u, err := r.UserRepo.FindUserByUserName(ctx, userName)
 	if err != nil {
-		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return nil, nil, err
+		log.Error().Err(err).Msg("FindUserByUserName error")
+		return nil, 0, domain.Wrap("user", err)
 	}
-	u, err := r.UserRepo.FindUserByID(uid)
+	a, n, err := r.Repo.FindFavoriteArticlesByUser(ctx, u, page)
 	if err != nil {
-		log.Error().Err(err).Msg("FindUserByID error")
-		return nil, nil, err
+		log.Error().Err(err).Msg("FindFavoriteArticlesByUser error")
+		return nil, 0, domain.Wrap("articles", err)
 	}
-	return a, u, nil
+	return a, n, nil
 }
 
-func (r *Service) AddTagToArticle(slug string, tagStr []string) error {
-	a, err := r.Repo.FindArticleBySlug(slug)
+func (r *Service) FindArticleAndUserBySlugAndUserID(ctx context.Context, slug string, uid uint) (*entity.Article, *entity.User, error) {
+	a, err := r.Repo.FindArticleBySlug(ctx, slug)
 	if err != nil {
 		log.Error().Err(err).Msg("FindArticleBySlug error")
-		return err
+		return nil, nil, domain.Wrap("article", err)
 	}
-	t, err := r.Repo.ListTags()
+	u, err := r.UserRepo.FindUserByID(ctx, uid)
 	if err != nil {
-		log.Error().Err(err).Msg("ListTags error")
-		return err
+		log.Error().Err(err).Msg("FindUserByID error")
+		return nil, nil, domain.Wrap("user", err)
 	}
-	sort.Strings(tagStr)
-	var tag []*entity.Tag
-	for _, v := range t {
-		if contains(tagStr, v.Tag.String) {
-			tag = append(tag, v)
+	return a, u, nil
+}
+
+// AddTagToArticle tag the article identified by slug, unknown tags are created. Only its author uid is allowed to
+func (r *Service) AddTagToArticle(ctx context.Context, uid uint, slug string, tagStr []string) (*entity.Article, error) {
+	var a *entity.Article
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = r.findOwnedArticle(ctx, uid, slug)
+		if err != nil {
+			return err
 		}
-	}
-	err = r.Repo.AddTagsToArticle(a, tag)
+		err = r.Repo.TagArticle(ctx, a, normalizeTags(tagStr))
+		if err != nil {
+			log.Error().Err(err).Msg("AddTagToArticle error")
+		}
+		return err
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("AddTagToArticle error")
+		return nil, domain.Wrap("tags", err)
+	}
+	return a, nil
+}
+
+// RemoveTagFromArticle untag the article identified by slug, only its author uid is allowed to
+func (r *Service) RemoveTagFromArticle(ctx context.Context, uid uint, slug string, tagStr string) (*entity.Article, error) {
+	var a *entity.Article
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = r.findOwnedArticle(ctx, uid, slug)
+		if err != nil {
+			return err
+		}
+		t, err := r.Repo.FindTagByName(ctx, strings.TrimSpace(tagStr))
+		if err != nil {
+			log.Error().Err(err).Msg("FindTagByName error")
+			return err
+		}
+		err = r.Repo.RemoveTagFromArticle(ctx, a, t)
+		if err != nil {
+			log.Error().Err(err).Msg("RemoveTagFromArticle error")
+		}
 		return err
+	})
+	if err != nil {
+		return nil, domain.Wrap("tag", err)
 	}
-	return nil
+	return a, nil
 }
 
-func contains(s []string, e string) bool {
-	for _, a := range s {
-		if a == e {
-			return true
+// normalizeTags trim the tags and drop the blank and duplicated ones, regardless of case, nil stays nil
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	r := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
 		}
+		seen[strings.ToLower(t)] = true
+		r = append(r, t)
 	}
-	return false
+	return r
 }
 
-func (r *Service) GetAllTags() ([]*entity.Tag, error) {
-	t, err := r.Repo.ListTags()
+func (r *Service) GetAllTags(ctx context.Context) ([]*entity.Tag, error) {
+	t, err := r.Repo.ListTags(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("ListTags error")
-		return nil, err
+		return nil, domain.Wrap("tags", err)
 	}
 	return t, nil
 }
+
+// GetPopularTags list the tags used by at least one article, the most used first, with their usage counts
+func (r *Service) GetPopularTags(ctx context.Context) ([]*entity.Tag, map[uint64]int, error) {
+	t, err := r.Repo.ListTags(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("ListTags error")
+		return nil, nil, domain.Wrap("tags", err)
+	}
+	counts, err := r.Repo.CountArticlesByTags(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("CountArticlesByTags error")
+		return nil, nil, domain.Wrap("tags", err)
+	}
+	used := make([]*entity.Tag, 0, len(t))
+	for _, v := range t {
+		if counts[v.ID] > 0 {
+			used = append(used, v)
+		}
+	}
+	sort.SliceStable(used, func(i, j int) bool {
+		if counts[used[i].ID] != counts[used[j].ID] {
+			return counts[used[i].ID] > counts[used[j].ID]
+		}
+		return used[i].Tag.String < used[j].Tag.String
+	})
+	return used, counts, nil
+}
+
+// RenameTag rename the tag name to newName, merge it instead into the tag already named newName, taking that one
+// out of the trash if needed. Only an admin uid is allowed to
+func (r *Service) RenameTag(ctx context.Context, uid uint, name, newName string) error {
+	if err := r.requireRole(ctx, uid, "tags", entity.UsersRoleAdmin); err != nil {
+		return err
+	}
+	name, newName = strings.TrimSpace(name), strings.TrimSpace(newName)
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		t, err := r.Repo.FindTagByName(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msg("FindTagByName error")
+			return err
+		}
+		into, err := r.Repo.RestoreTagByName(ctx, newName)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Error().Err(err).Msg("RestoreTagByName error")
+			return err
+		}
+		// the name is free, or only differs from the current one by case
+		if into == nil || into.ID == t.ID {
+			err = r.Repo.RenameTag(ctx, t, newName)
+			if err != nil {
+				log.Error().Err(err).Msg("RenameTag error")
+			}
+			return err
+		}
+		err = r.Repo.MergeTags(ctx, t, into)
+		if err != nil {
+			log.Error().Err(err).Msg("MergeTags error")
+		}
+		return err
+	})
+	return domain.Wrap("tag", err)
+}
+
+// MergeTags retag the articles tagged with from by into, then delete from. Only an admin uid is allowed to
+func (r *Service) MergeTags(ctx context.Context, uid uint, from, into string) error {
+	from, into = strings.TrimSpace(from), strings.TrimSpace(into)
+	if from == into {
+		return domain.Validation("a tag cannot be merged into itself")
+	}
+	if err := r.requireRole(ctx, uid, "tags", entity.UsersRoleAdmin); err != nil {
+		return err
+	}
+	err := r.Tx.Do(ctx, func(ctx context.Context) error {
+		f, err := r.Repo.FindTagByName(ctx, from)
+		if err != nil {
+			log.Error().Err(err).Msg("FindTagByName error")
+			return err
+		}
+		i, err := r.Repo.FindTagByName(ctx, into)
+		if err != nil {
+			log.Error().Err(err).Msg("FindTagByName error")
+			return err
+		}
+		// names differing by case only find the same tag
+		if f.ID == i.ID {
+			return domain.Validation("a tag cannot be merged into itself")
+		}
+		err = r.Repo.MergeTags(ctx, f, i)
+		if err != nil {
+			log.Error().Err(err).Msg("MergeTags error")
+		}
+		return err
+	})
+	return domain.Wrap("tag", err)
+}
@@ -6,7 +6,15 @@ import (
 	. "forum/model"
 )
 
-func SingleArticleResponseMapper(a *entity.Article, author *entity.User, tags []*entity.Tag) *SingleArticleResponse {
+// ArticleDetails hold the data related to a batch of articles, keyed by article id or author id
+type ArticleDetails struct {
+	Authors   map[uint64]*entity.User
+	Tags      map[uint64][]*entity.Tag
+	Favorited map[uint64]bool
+	Following map[uint64]bool
+}
+
+func ArticleResponseMapper(a *entity.Article, d *ArticleDetails) *ArticleResponse {
 	ar := new(ArticleResponse)
 	ar.TagList = make([]string, 0)
 	ar.Slug = a.Slug
@@ -24,31 +32,34 @@ func SingleArticleResponseMapper(a *entity.Article, author *entity.User, tags []
 		ar.UpdatedAt = a.UpdatedAt.Time
 	}
 
-	for _, t := range tags {
+	for _, t := range d.Tags[a.ID] {
 		ar.TagList = append(ar.TagList, t.Tag.String)
 	}
+	ar.FavoritesCount = int(a.FavoritesCount)
+	ar.Favorited = d.Favorited[a.ID]
 
-	ar.Author.Username = author.Username
-	if author.Bio.Valid {
-		ar.Author.Bio = &author.Bio.String
-	}
-	if author.Image.Valid {
-		ar.Author.Image = &author.Image.String
+	if author, ok := d.Authors[a.AuthorID.Uint64]; ok && a.AuthorID.Valid {
+		ar.Author.Username = author.Username
+		if author.Bio.Valid {
+			ar.Author.Bio = &author.Bio.String
+		}
+		if author.Image.Valid {
+			ar.Author.Image = &author.Image.String
+		}
+		ar.Author.Following = d.Following[author.ID]
 	}
-	return &SingleArticleResponse{Article: ar}
+	return ar
+}
+
+func SingleArticleResponseMapper(a *entity.Article, d *ArticleDetails) *SingleArticleResponse {
+	return &SingleArticleResponse{Article: ArticleResponseMapper(a, d)}
 }
 
-func SimpleArticleListMapper(articles []*entity.Article, count int64) *SimpleArticleList {
-	r := new(SimpleArticleList)
-	r.Articles = make([]*SimpleArticle, 0)
+func ArticleListResponseMapper(articles []*entity.Article, d *ArticleDetails, count int64) *ArticleListResponse {
+	r := new(ArticleListResponse)
+	r.Articles = make([]*ArticleResponse, 0)
 	for _, a := range articles {
-		ar := new(SimpleArticle)
-		ar.Slug = a.Slug
-		ar.Title = a.Title
-		if a.Description.Valid {
-			ar.Description = a.Description.String
-		}
-		r.Articles = append(r.Articles, ar)
+		r.Articles = append(r.Articles, ArticleResponseMapper(a, d))
 	}
 	r.ArticlesCount = count
 	return r
@@ -62,6 +73,16 @@ func TagListResponseMapper(tags []*entity.Tag) *TagListResponse {
 	return r
 }
 
+// TagCountListResponseMapper map the tags with the number of articles using them
+func TagCountListResponseMapper(tags []*entity.Tag, counts map[uint64]int) *TagCountListResponse {
+	r := new(TagCountListResponse)
+	r.Tags = make([]TagCount, 0, len(tags))
+	for _, t := range tags {
+		r.Tags = append(r.Tags, TagCount{Tag: t.Tag.String, Count: counts[t.ID]})
+	}
+	return r
+}
+
 func CommentResponseMapper(cm *entity.Comment) *CommentResponse {
 	comment := CommentResponse{}
 	comment.ID = uint(cm.ID)
@@ -72,11 +93,31 @@ func CommentResponseMapper(cm *entity.Comment) *CommentResponse {
 	if cm.UpdatedAt.Valid {
 		comment.UpdatedAt = cm.UpdatedAt.Time
 	}
+	if cm.ParentID.Valid {
+		parentID := uint(cm.ParentID.Uint64)
+		comment.ParentID = &parentID
+	}
+	comment.Depth = int(cm.Depth)
+	comment.Edited = cm.EditedAt.Valid
 	return &comment
 }
 
-func CommentListResponseMapper(comments []*entity.Comment) *CommentListResponse {
-	r := CommentListResponse{}
+func SingleCommentResponseMapper(cm *entity.Comment) *SingleCommentResponse {
+	return &SingleCommentResponse{Comment: CommentResponseMapper(cm)}
+}
+
+// CommentHistoryResponseMapper map cm with its revisions, listed oldest first
+func CommentHistoryResponseMapper(cm *entity.Comment, revisions []*entity.CommentRevision) *CommentHistoryResponse {
+	r := CommentHistoryResponse{Comment: CommentResponseMapper(cm)}
+	r.Revisions = make([]CommentRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		r.Revisions = append(r.Revisions, CommentRevision{Body: rev.Body.String, ReplacedAt: rev.CreatedAt.Time})
+	}
+	return &r
+}
+
+func CommentListResponseMapper(comments []*entity.Comment, count int64) *CommentListResponse {
+	r := CommentListResponse{CommentsCount: count}
 	r.Comments = make([]CommentResponse, 0)
 	for _, i := range comments {
 		cr := CommentResponseMapper(i)
@@ -84,3 +125,31 @@ func CommentListResponseMapper(comments []*entity.Comment) *CommentListResponse
 	}
 	return &r
 }
+
+// CommentTreeResponseMapper map comments, listed in reading order, to a tree: the top-level comments with
+// their replies nested in them
+func CommentTreeResponseMapper(comments []*entity.Comment, count int64) *CommentListResponse {
+	children := make(map[uint64][]*entity.Comment)
+	roots := make([]*entity.Comment, 0)
+	for _, cm := range comments {
+		if cm.ParentID.Valid {
+			children[cm.ParentID.Uint64] = append(children[cm.ParentID.Uint64], cm)
+		} else {
+			roots = append(roots, cm)
+		}
+	}
+	var nest func(cm *entity.Comment) CommentResponse
+	nest = func(cm *entity.Comment) CommentResponse {
+		cr := CommentResponseMapper(cm)
+		for _, reply := range children[cm.ID] {
+			cr.Replies = append(cr.Replies, nest(reply))
+		}
+		return *cr
+	}
+	r := CommentListResponse{CommentsCount: count}
+	r.Comments = make([]CommentResponse, 0, len(roots))
+	for _, root := range roots {
+		r.Comments = append(r.Comments, nest(root))
+	}
+	return &r
+}
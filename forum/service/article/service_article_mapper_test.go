@@ -65,7 +65,8 @@ func TestCommentListResponseMapper(t *testing.T) {
 			},
 		}
 
-		actual := CommentListResponseMapper(comments)
+		actual := CommentListResponseMapper(comments, 5)
+		assert.Equal(t, int64(5), actual.CommentsCount)
 		bodyList := []string{}
 		for _, a := range actual.Comments {
 			bodyList = append(bodyList, a.Body)
@@ -74,3 +75,71 @@ func TestCommentListResponseMapper(t *testing.T) {
 		assert.Contains(t, bodyList, "bar Body", "bar Body should be in the list")
 	})
 }
+
+func TestCommentTreeResponseMapper(t *testing.T) {
+	t.Run("when replies are nested in their comment", func(t *testing.T) {
+		comments := []*entity.Comment{
+			{ID: 1, Body: null.StringFrom("root")},
+			{ID: 3, Body: null.StringFrom("reply"), ParentID: null.Uint64From(1), Depth: 1},
+			{ID: 4, Body: null.StringFrom("nested reply"), ParentID: null.Uint64From(3), Depth: 2},
+			{ID: 2, Body: null.StringFrom("other root")},
+		}
+
+		actual := CommentTreeResponseMapper(comments, 2)
+		assert.Equal(t, int64(2), actual.CommentsCount)
+		assert.Len(t, actual.Comments, 2)
+		assert.Equal(t, "other root", actual.Comments[1].Body)
+		assert.Empty(t, actual.Comments[1].Replies)
+		reply := actual.Comments[0].Replies[0]
+		assert.Equal(t, uint(1), *reply.ParentID)
+		assert.Equal(t, 1, reply.Depth)
+		assert.Equal(t, "nested reply", reply.Replies[0].Body)
+		assert.Equal(t, 2, reply.Replies[0].Depth)
+	})
+}
+
+func TestCommentHistoryResponseMapper(t *testing.T) {
+	t.Run("when the comment was edited", func(t *testing.T) {
+		replacedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+		comment := &entity.Comment{ID: 2, Body: null.StringFrom("second"), EditedAt: null.TimeFrom(replacedAt)}
+		revisions := []*entity.CommentRevision{{ID: 1, CommentID: 2, Body: null.StringFrom("first"), CreatedAt: null.TimeFrom(replacedAt)}}
+
+		actual := CommentHistoryResponseMapper(comment, revisions)
+		assert.True(t, actual.Comment.Edited)
+		assert.Equal(t, []CommentRevision{{Body: "first", ReplacedAt: replacedAt}}, actual.Revisions)
+	})
+}
+
+func TestArticleResponseMapper(t *testing.T) {
+	t.Run("when author is missing", func(t *testing.T) {
+		a := &entity.Article{ID: 1, Title: "foo", Slug: "foo"}
+		actual := ArticleResponseMapper(a, &ArticleDetails{})
+		assert.Equal(t, "foo", actual.Slug)
+		assert.Equal(t, "", actual.Author.Username)
+		assert.Empty(t, actual.TagList)
+	})
+	t.Run("when details are given", func(t *testing.T) {
+		a := &entity.Article{ID: 1, Title: "foo", Slug: "foo", AuthorID: null.Uint64From(2), FavoritesCount: 2}
+		d := &ArticleDetails{
+			Authors:   map[uint64]*entity.User{2: {ID: 2, Username: "bar", Bio: null.StringFrom("bio")}},
+			Tags:      map[uint64][]*entity.Tag{1: {{Tag: null.StringFrom("go")}, {Tag: null.StringFrom("sql")}}},
+			Favorited: map[uint64]bool{1: true},
+			Following: map[uint64]bool{2: true},
+		}
+		actual := ArticleResponseMapper(a, d)
+		assert.Equal(t, []string{"go", "sql"}, actual.TagList)
+		assert.Equal(t, 2, actual.FavoritesCount)
+		assert.True(t, actual.Favorited)
+		assert.Equal(t, "bar", actual.Author.Username)
+		assert.Equal(t, "bio", *actual.Author.Bio)
+		assert.True(t, actual.Author.Following)
+	})
+}
+
+func TestArticleListResponseMapper(t *testing.T) {
+	t.Run("when list is empty", func(t *testing.T) {
+		actual := ArticleListResponseMapper(nil, &ArticleDetails{}, 0)
+		assert.NotNil(t, actual.Articles)
+		assert.Equal(t, int64(0), actual.ArticlesCount)
+	})
+}
@@ -1,11 +1,17 @@
 package article
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"schema/entity"
 	"testing"
+	"time"
 
+	"forum/domain"
 	mockRepo "forum/mock/repository"
+	"forum/model"
+	"forum/repository"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/volatiletech/null/v8"
@@ -17,13 +23,55 @@ func TestArticle_CreateArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 
 		// When
-		articleMock.On("CreateArticle", mock.Anything).Return(fmt.Errorf("CreateArticle error"))
+		articleMock.On("FindTakenSlugs", mock.Anything, "foo-title", uint64(0)).Return(map[string]bool{}, nil)
+		articleMock.On("CreateArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("CreateArticle error"))
 		// Then
-		err := ServiceArticleMock.CreateArticle(articleFoo)
-		assert.Error(t, err, "CreateArticle error")
+		err := ServiceArticleMock.CreateArticle(context.Background(), &entity.Article{Title: "foo Title"}, nil)
+		assert.ErrorContains(t, err, "CreateArticle error")
+	})
+	t.Run("When CreateArticle, FindTakenSlugs failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+
+		// When
+		articleMock.On("FindTakenSlugs", mock.Anything, "foo-title", uint64(0)).Return(nil, fmt.Errorf("FindTakenSlugs error"))
+		// Then
+		err := ServiceArticleMock.CreateArticle(context.Background(), &entity.Article{Title: "foo Title"}, nil)
+		assert.ErrorContains(t, err, "FindTakenSlugs error")
+	})
+	t.Run("When CreateArticle, the transaction failed to commit", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		txMock := mockRepo.NewUnitOfWork(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, txMock, nil)
+
+		// When
+		txMock.On("Do", mock.Anything, mock.Anything).Return(fmt.Errorf("commit error"))
+		// Then
+		err := ServiceArticleMock.CreateArticle(context.Background(), &entity.Article{Title: "foo Title"}, nil)
+		assert.ErrorContains(t, err, "commit error")
+		assert.Equal(t, domain.KindOf(err), domain.KindInternal)
+	})
+	t.Run("When CreateArticle, the slug is derived from the title with a collision suffix and tags are normalized", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		article := &entity.Article{Title: "foo Title", Slug: "client-slug"}
+
+		// When
+		articleMock.On("FindTakenSlugs", mock.Anything, "foo-title", uint64(0)).Return(map[string]bool{"foo-title": true, "foo-title-2": true}, nil)
+		articleMock.On("CreateArticle", mock.Anything, article, []string{"go", "web"}).Return(nil)
+		// Then
+		err := ServiceArticleMock.CreateArticle(context.Background(), article, []string{"go", " web", "go"})
+		assert.NilError(t, err)
+		assert.Equal(t, article.Slug, "foo-title-3")
 	})
 }
 
@@ -32,35 +80,74 @@ func TestArticle_DeleteArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug").Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		// Then
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 1, "slug")
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
+	})
+	t.Run("When caller is not the author", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(2), "slug").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug").Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(memberFoo, nil)
+		// Then
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 2, "slug")
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When caller is a moderator", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(2), "slug").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug").Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(moderatorFoo, nil)
+		articleMock.On("DeleteArticle", mock.Anything, articleFoo).Return(nil)
+		// Then
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 2, "slug")
+		assert.NilError(t, err)
+	})
+	t.Run("When Find owned article get error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug").Return(nil, fmt.Errorf("FindArticleByAuthorIDAndSlug error"))
 		// Then
-		err := ServiceArticleMock.DeleteArticle("slug")
-		assert.Error(t, err, "FindArticleBySlug error")
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 1, "slug")
+		assert.ErrorContains(t, err, "FindArticleByAuthorIDAndSlug error")
 	})
 	t.Run("when delete article get error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("DeleteArticle", mock.Anything).Return(fmt.Errorf("DeleteArticle error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug").Return(articleFoo, nil)
+		articleMock.On("DeleteArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("DeleteArticle error"))
 		// Then
-		err := ServiceArticleMock.DeleteArticle("slug")
-		assert.Error(t, err, "DeleteArticle error")
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 1, "slug")
+		assert.ErrorContains(t, err, "DeleteArticle error")
 	})
 	t.Run("when delete article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("DeleteArticle", mock.Anything).Return(nil)
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug").Return(articleFoo, nil)
+		articleMock.On("DeleteArticle", mock.Anything, articleFoo).Return(nil)
 		// Then
-		err := ServiceArticleMock.DeleteArticle("slug")
+		err := ServiceArticleMock.DeleteArticle(context.Background(), 1, "slug")
 		assert.NilError(t, err)
 	})
 }
@@ -70,192 +157,289 @@ func TestArticle_FindArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		_, _, _, err := ServiceArticleMock.FindArticle("slug")
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, _, _, err := ServiceArticleMock.FindArticle(context.Background(), "slug")
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("When find author get error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindAuthorByArticle", mock.Anything).Return(nil, fmt.Errorf("FindAuthorByArticle error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		articleMock.On("FindAuthorByArticle", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindAuthorByArticle error"))
 		// Then
-		_, _, _, err := ServiceArticleMock.FindArticle("slug")
-		assert.Error(t, err, "FindAuthorByArticle error")
+		_, _, _, err := ServiceArticleMock.FindArticle(context.Background(), "slug")
+		assert.ErrorContains(t, err, "FindAuthorByArticle error")
 	})
 	t.Run("When find tag get error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindAuthorByArticle", mock.Anything).Return(userFoo, nil)
-		articleMock.On("FindTagsByArticle", mock.Anything).Return(nil, fmt.Errorf("FindTagsByArticle error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		articleMock.On("FindAuthorByArticle", mock.Anything, mock.Anything).Return(userFoo, nil)
+		articleMock.On("FindTagsByArticle", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindTagsByArticle error"))
 		// Then
-		_, _, _, err := ServiceArticleMock.FindArticle("slug")
-		assert.Error(t, err, "FindTagsByArticle error")
+		_, _, _, err := ServiceArticleMock.FindArticle(context.Background(), "slug")
+		assert.ErrorContains(t, err, "FindTagsByArticle error")
 	})
 	t.Run("When find article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindAuthorByArticle", mock.Anything).Return(userFoo, nil)
-		articleMock.On("FindTagsByArticle", mock.Anything).Return(nil, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		articleMock.On("FindAuthorByArticle", mock.Anything, mock.Anything).Return(userFoo, nil)
+		articleMock.On("FindTagsByArticle", mock.Anything, mock.Anything).Return(nil, nil)
 
 		// Then
-		_, _, _, err := ServiceArticleMock.FindArticle("slug")
+		_, _, _, err := ServiceArticleMock.FindArticle(context.Background(), "slug")
 		assert.NilError(t, err)
 	})
 }
 
-func TestArticle_FindArticleByAuthor(t *testing.T) {
-	t.Run("When Find user by username return error", func(t *testing.T) {
+func TestArticle_ArticleResponse(t *testing.T) {
+	t.Run("When viewer is anonymous", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		article := &entity.Article{ID: 1, Slug: "foo-slug", AuthorID: null.Uint64From(2), FavoritesCount: 3}
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(nil, fmt.Errorf("FindUserByUsername error"))
-
+		articleMock.On("FindAuthorsByArticles", mock.Anything, mock.Anything).Return(map[uint64]*entity.User{2: {ID: 2, Username: "foo"}}, nil)
+		articleMock.On("FindTagsByArticles", mock.Anything, mock.Anything).Return(map[uint64][]*entity.Tag{1: {{Tag: null.StringFrom("go")}}}, nil)
 		// Then
-		_, n, err := ServiceArticleMock.FindArticleByAuthor("username", 0, 1)
-		assert.Error(t, err, "FindUserByUsername error")
-		assert.Equal(t, n, int64(0))
+		r, err := ServiceArticleMock.ArticleResponse(context.Background(), 0, article)
+		assert.NilError(t, err)
+		assert.Equal(t, r.Article.Author.Username, "foo")
+		assert.DeepEqual(t, r.Article.TagList, []string{"go"})
+		assert.Equal(t, r.Article.FavoritesCount, 3)
+		assert.Equal(t, r.Article.Favorited, false)
+		assert.Equal(t, r.Article.Author.Following, false)
 	})
-	t.Run("When Find article by author return error", func(t *testing.T) {
+	t.Run("When viewer is logged in", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		article := &entity.Article{ID: 1, Slug: "foo-slug", AuthorID: null.Uint64From(2)}
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("FindArticleByAuthor error"))
-
+		articleMock.On("FindAuthorsByArticles", mock.Anything, mock.Anything).Return(map[uint64]*entity.User{2: {ID: 2, Username: "foo"}}, nil)
+		articleMock.On("FindTagsByArticles", mock.Anything, mock.Anything).Return(map[uint64][]*entity.Tag{}, nil)
+		articleMock.On("FindFavoritedArticleIDs", mock.Anything, uint64(5), mock.Anything).Return(map[uint64]bool{1: true}, nil)
+		userMock.On("FindFollowingIDs", mock.Anything, &entity.User{ID: 5}, []uint64{2}).Return(map[uint64]bool{2: true}, nil)
 		// Then
-		_, n, err := ServiceArticleMock.FindArticleByAuthor("username", 0, 1)
-		assert.Error(t, err, "FindArticleByAuthor error")
-		assert.Equal(t, n, int64(0))
+		r, err := ServiceArticleMock.ArticleResponse(context.Background(), 5, article)
+		assert.NilError(t, err)
+		assert.Equal(t, r.Article.Favorited, true)
+		assert.Equal(t, r.Article.Author.Following, true)
 	})
-	t.Run("when find article return ok", func(t *testing.T) {
+	t.Run("When find tags return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		articleMock.On("FindAuthorsByArticles", mock.Anything, mock.Anything).Return(map[uint64]*entity.User{}, nil)
+		articleMock.On("FindTagsByArticles", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindTagsByArticles error"))
+		// Then
+		_, err := ServiceArticleMock.ArticleResponse(context.Background(), 0, articleFoo)
+		assert.ErrorContains(t, err, "FindTagsByArticles error")
+	})
+}
 
+func TestArticle_ArticleListResponse(t *testing.T) {
+	t.Run("When list is built", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		articles := []*entity.Article{
+			{ID: 1, Slug: "foo-slug", AuthorID: null.Uint64From(2)},
+			{ID: 2, Slug: "bar-slug", AuthorID: null.Uint64From(2), FavoritesCount: 4},
+		}
+		// When
+		articleMock.On("FindAuthorsByArticles", mock.Anything, articles).Return(map[uint64]*entity.User{2: {ID: 2, Username: "foo"}}, nil).Once()
+		articleMock.On("FindTagsByArticles", mock.Anything, articles).Return(map[uint64][]*entity.Tag{}, nil).Once()
+		articleMock.On("FindFavoritedArticleIDs", mock.Anything, uint64(5), articles).Return(map[uint64]bool{2: true}, nil).Once()
+		userMock.On("FindFollowingIDs", mock.Anything, mock.Anything, []uint64{2}).Return(map[uint64]bool{}, nil).Once()
 		// Then
-		_, n, err := ServiceArticleMock.FindArticleByAuthor("username", 0, 1)
+		r, err := ServiceArticleMock.ArticleListResponse(context.Background(), 5, articles, 10)
 		assert.NilError(t, err)
-		assert.Equal(t, n, int64(1))
+		assert.Equal(t, r.ArticlesCount, int64(10))
+		assert.Equal(t, len(r.Articles), 2)
+		assert.Equal(t, r.Articles[0].Favorited, false)
+		assert.Equal(t, r.Articles[1].Favorited, true)
+		assert.Equal(t, r.Articles[1].FavoritesCount, 4)
+	})
+	t.Run("When find favorited articles return error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindAuthorsByArticles", mock.Anything, mock.Anything).Return(map[uint64]*entity.User{}, nil)
+		articleMock.On("FindTagsByArticles", mock.Anything, mock.Anything).Return(map[uint64][]*entity.Tag{}, nil)
+		articleMock.On("FindFavoritedArticleIDs", mock.Anything, uint64(5), mock.Anything).Return(nil, fmt.Errorf("FindFavoritedArticleIDs error"))
+		// Then
+		_, err := ServiceArticleMock.ArticleListResponse(context.Background(), 5, []*entity.Article{articleFoo}, 1)
+		assert.ErrorContains(t, err, "FindFavoritedArticleIDs error")
 	})
 }
 
-func TestArticle_FindArticles(t *testing.T) {
-	t.Run("When Find articles return error", func(t *testing.T) {
+func TestArticle_FindArticleByAuthor(t *testing.T) {
+	t.Run("When Find user by username return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(nil, fmt.Errorf("FindUserByUsername error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindUserByUsername error"))
 
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("test-tag", "test-user", 0, 1)
-		assert.Error(t, err, "FindUserByUsername error")
+		_, n, err := ServiceArticleMock.FindArticleByAuthor(context.Background(), "username", model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "FindUserByUsername error")
 		assert.Equal(t, n, int64(0))
 	})
-	t.Run("When Find articles by tag return error", func(t *testing.T) {
+	t.Run("When Find article by author return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("ListArticlesByTag", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("FindArticleByTag error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("FindArticleByAuthor error"))
+
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("test-tag", "test-user", 0, 1)
-		assert.Error(t, err, "FindArticleByTag error")
+		_, n, err := ServiceArticleMock.FindArticleByAuthor(context.Background(), "username", model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "FindArticleByAuthor error")
 		assert.Equal(t, n, int64(0))
 	})
-	t.Run("When Find articles by tag return OK", func(t *testing.T) {
+	t.Run("when find article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("ListArticlesByTag", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleBar}, int64(1), nil)
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleFoo}, int64(1), nil)
+
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("test-tag", "test-user", 0, 1)
+		_, n, err := ServiceArticleMock.FindArticleByAuthor(context.Background(), "username", model.Page{Limit: 1})
 		assert.NilError(t, err)
 		assert.Equal(t, n, int64(1))
 	})
-	t.Run("When find articles by author return error", func(t *testing.T) {
+}
+
+func TestArticle_FindArticles(t *testing.T) {
+	t.Run("When the filters are combined", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		filter := model.ArticleFilter{Tag: "test-tag", Author: "test-user", Favorited: "fan", Sort: model.SortOldest}
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-
-		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("FindArticleByAuthor error"))
+		articleMock.On("ListArticles", mock.Anything, filter, model.Page{Limit: 1}).Return([]*entity.Article{articleBar}, int64(3), nil)
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("", "test-user", 0, 1)
-		assert.Error(t, err, "FindArticleByAuthor error")
-		assert.Equal(t, n, int64(0))
+		a, n, err := ServiceArticleMock.FindArticles(context.Background(), filter, model.Page{Limit: 1})
+		assert.NilError(t, err)
+		assert.Equal(t, len(a), 1)
+		assert.Equal(t, n, int64(3))
 	})
-	t.Run("When find articles by author return OK", func(t *testing.T) {
+	t.Run("When no filter is set", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-
-		articleMock.On("ListArticlesByAuthor", mock.Anything, mock.Anything, mock.Anything).Return([]*entity.Article{articleBar}, int64(1), nil)
+		articleMock.On("ListArticles", mock.Anything, model.ArticleFilter{}, model.Page{Limit: 1}).Return([]*entity.Article{articleBar}, int64(1), nil)
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("", "test-user", 0, 1)
+		_, n, err := ServiceArticleMock.FindArticles(context.Background(), model.ArticleFilter{}, model.Page{Limit: 1})
 		assert.NilError(t, err)
 		assert.Equal(t, n, int64(1))
 	})
-	t.Run("When find articles without user return ok", func(t *testing.T) {
+	t.Run("When list articles get error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("ListArticles", mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("ListArticles error"))
+		// Then
+		_, n, err := ServiceArticleMock.FindArticles(context.Background(), model.ArticleFilter{Tag: "test-tag"}, model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "ListArticles error")
+		assert.Equal(t, n, int64(0))
+	})
+}
+
+func TestArticle_SearchArticles(t *testing.T) {
+	t.Run("When the query is blank", func(t *testing.T) {
+		// Given
+		searchMock := mockRepo.NewArticleSearch(t)
+		ServiceArticleMock := NewServiceArticle(mockRepo.NewIRepoArticle(t), mockRepo.NewIRepoUser(t), directUnitOfWork{}, searchMock)
+		// Then
+		_, _, err := ServiceArticleMock.SearchArticles(context.Background(), "  ", model.ArticleFilter{}, model.Page{Limit: 1})
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
+	t.Run("When search articles return OK", func(t *testing.T) {
+		// Given
+		searchMock := mockRepo.NewArticleSearch(t)
+		ServiceArticleMock := NewServiceArticle(mockRepo.NewIRepoArticle(t), mockRepo.NewIRepoUser(t), directUnitOfWork{}, searchMock)
+		filter := model.ArticleFilter{Tag: "go"}
+		hits := []*repository.SearchHit{{Article: articleFoo, Snippet: "<em>foo</em>"}}
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("FindArticles", mock.Anything, mock.Anything).Return([]*entity.Article{articleBar}, int64(1), nil)
+		searchMock.On("SearchArticles", mock.Anything, "foo", filter, model.Page{Limit: 1}).Return(hits, int64(5), nil)
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("", "", 0, 1)
+		h, n, err := ServiceArticleMock.SearchArticles(context.Background(), "foo", filter, model.Page{Limit: 1})
 		assert.NilError(t, err)
-		assert.Equal(t, n, int64(1))
+		assert.Equal(t, len(h), 1)
+		assert.Equal(t, n, int64(5))
 	})
-	t.Run("When find articles without user get error", func(t *testing.T) {
+	t.Run("When search articles get error", func(t *testing.T) {
+		// Given
+		searchMock := mockRepo.NewArticleSearch(t)
+		ServiceArticleMock := NewServiceArticle(mockRepo.NewIRepoArticle(t), mockRepo.NewIRepoUser(t), directUnitOfWork{}, searchMock)
+		// When
+		searchMock.On("SearchArticles", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("SearchArticles error"))
+		// Then
+		_, _, err := ServiceArticleMock.SearchArticles(context.Background(), "foo", model.ArticleFilter{}, model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "SearchArticles error")
+	})
+}
+
+func TestArticle_FindFeed(t *testing.T) {
+	t.Run("When list feed return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		articleMock.On("FindArticles", mock.Anything, mock.Anything).Return([]*entity.Article{articleBar}, int64(1), fmt.Errorf("FindArticle error"))
+		articleMock.On("ListFeed", mock.Anything, uint(1), model.Page{Limit: 1}).Return(nil, int64(0), fmt.Errorf("ListFeed error"))
 		// Then
-		_, n, err := ServiceArticleMock.FindArticles("", "", 0, 1)
-		assert.Error(t, err, "FindArticle error")
+		_, n, err := ServiceArticleMock.FindFeed(context.Background(), 1, model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "ListFeed error")
 		assert.Equal(t, n, int64(0))
 	})
+	t.Run("When list feed return ok", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("ListFeed", mock.Anything, uint(1), model.Page{Limit: 1}).Return([]*entity.Article{articleFoo}, int64(3), nil)
+		// Then
+		a, n, err := ServiceArticleMock.FindFeed(context.Background(), 1, model.Page{Limit: 1})
+		assert.NilError(t, err)
+		assert.Equal(t, len(a), 1)
+		assert.Equal(t, n, int64(3))
+	})
 }
 
 func TestArticle_FindCommentsBySlug(t *testing.T) {
@@ -263,41 +447,60 @@ func TestArticle_FindCommentsBySlug(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 
 		// Then
-		_, err := ServiceArticleMock.FindCommentsBySlug("test-slug", 0, 1)
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, _, err := ServiceArticleMock.FindCommentsBySlug(context.Background(), "test-slug", model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("When find comments by article return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleBar, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleBar, nil)
 		articleMock.On("FindCommentsByArticle", mock.Anything, mock.Anything, mock.Anything).
-			Return(nil, fmt.Errorf("FindCommentsBySlug error"))
+			Return(nil, int64(0), fmt.Errorf("FindCommentsBySlug error"))
 		// Then
-		comments, err := ServiceArticleMock.FindCommentsBySlug("test-slug", 0, 1)
-		assert.Error(t, err, "FindCommentsBySlug error")
+		comments, _, err := ServiceArticleMock.FindCommentsBySlug(context.Background(), "test-slug", model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "FindCommentsBySlug error")
 		assert.Equal(t, len(comments), 0)
 	})
 	t.Run("When Find comments by slug return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleBar, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleBar, nil)
+		root := &entity.Comment{ID: 1}
+		reply := &entity.Comment{ID: 2, ParentID: null.Uint64From(1), RootID: null.Uint64From(1), Depth: 1}
 		articleMock.On("FindCommentsByArticle", mock.Anything, mock.Anything, mock.Anything).
-			Return([]*entity.Comment{commentFoo}, nil)
+			Return([]*entity.Comment{root}, int64(3), nil)
+		articleMock.On("FindRepliesByRoots", mock.Anything, []*entity.Comment{root}).
+			Return([]*entity.Comment{reply}, nil)
 		// Then
-		comments, err := ServiceArticleMock.FindCommentsBySlug("test-slug", 0, 1)
+		comments, n, err := ServiceArticleMock.FindCommentsBySlug(context.Background(), "test-slug", model.Page{Limit: 1})
 		assert.NilError(t, err)
-		assert.Equal(t, len(comments), 1)
+		assert.DeepEqual(t, comments, []*entity.Comment{root, reply})
+		assert.Equal(t, n, int64(3))
+	})
+	t.Run("When find replies return error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleBar, nil)
+		articleMock.On("FindCommentsByArticle", mock.Anything, mock.Anything, mock.Anything).
+			Return([]*entity.Comment{commentFoo}, int64(1), nil)
+		articleMock.On("FindRepliesByRoots", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindRepliesByRoots error"))
+		// Then
+		_, _, err := ServiceArticleMock.FindCommentsBySlug(context.Background(), "test-slug", model.Page{Limit: 1})
+		assert.ErrorContains(t, err, "FindRepliesByRoots error")
 	})
 }
 
@@ -306,37 +509,37 @@ func TestArticle_FindAuthorBySlug(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 
 		// Then
-		_, err := ServiceArticleMock.FindAuthorBySlug("test-slug")
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, err := ServiceArticleMock.FindAuthorBySlug(context.Background(), "test-slug")
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("when find author by article return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleBar, nil)
-		articleMock.On("FindAuthorByArticle", mock.Anything).Return(nil, fmt.Errorf("FindAuthorBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleBar, nil)
+		articleMock.On("FindAuthorByArticle", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindAuthorBySlug error"))
 
 		// Then
-		_, err := ServiceArticleMock.FindAuthorBySlug("test-slug")
-		assert.Error(t, err, "FindAuthorBySlug error")
+		_, err := ServiceArticleMock.FindAuthorBySlug(context.Background(), "test-slug")
+		assert.ErrorContains(t, err, "FindAuthorBySlug error")
 	})
 	t.Run("when find author by article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleBar, nil)
-		articleMock.On("FindAuthorByArticle", mock.Anything).Return(userFoo, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleBar, nil)
+		articleMock.On("FindAuthorByArticle", mock.Anything, mock.Anything).Return(userFoo, nil)
 		// Then
-		author, err := ServiceArticleMock.FindAuthorBySlug("test-slug")
+		author, err := ServiceArticleMock.FindAuthorBySlug(context.Background(), "test-slug")
 		assert.NilError(t, err)
 		assert.Equal(t, author.ID, userFoo.ID)
 	})
@@ -347,88 +550,200 @@ func TestArticle_AddCommentToArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		err := ServiceArticleMock.AddCommentToArticle("test-slug", commentFoo)
-		assert.Error(t, err, "FindArticleBySlug error")
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", commentFoo)
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("when add comment return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("AddComment", mock.Anything, mock.Anything).Return(fmt.Errorf("AddComment error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		articleMock.On("AddComment", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("AddComment error"))
 		// Then
-		err := ServiceArticleMock.AddCommentToArticle("test-slug", commentFoo)
-		assert.Error(t, err, "AddComment error")
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", commentFoo)
+		assert.ErrorContains(t, err, "AddComment error")
 	})
 	t.Run("when add comment return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("AddComment", mock.Anything, mock.Anything).Return(nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		articleMock.On("AddComment", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		// Then
-		err := ServiceArticleMock.AddCommentToArticle("test-slug", commentFoo)
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", commentFoo)
 		assert.NilError(t, err)
 	})
+	t.Run("when the reply joins the thread of its parent", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		article := &entity.Article{ID: 1}
+		parent := &entity.Comment{ID: 5, ArticleID: null.Uint64From(1), RootID: null.Uint64From(3), Depth: 2}
+		reply := &entity.Comment{Body: null.StringFrom("reply"), ParentID: null.Uint64From(5)}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(5)).Return(parent, nil)
+		articleMock.On("AddComment", mock.Anything, article, reply).Return(nil)
+		// Then
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", reply)
+		assert.NilError(t, err)
+		assert.Equal(t, reply.RootID, null.Uint64From(3))
+		assert.Equal(t, reply.Depth, uint(3))
+	})
+	t.Run("when the reply starts below a top-level comment", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		article := &entity.Article{ID: 1}
+		reply := &entity.Comment{Body: null.StringFrom("reply"), ParentID: null.Uint64From(3)}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(3)).Return(&entity.Comment{ID: 3, ArticleID: null.Uint64From(1)}, nil)
+		articleMock.On("AddComment", mock.Anything, article, reply).Return(nil)
+		// Then
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", reply)
+		assert.NilError(t, err)
+		assert.Equal(t, reply.RootID, null.Uint64From(3))
+		assert.Equal(t, reply.Depth, uint(1))
+	})
+	t.Run("when the parent is on another article", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(&entity.Article{ID: 1}, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(3)).Return(&entity.Comment{ID: 3, ArticleID: null.Uint64From(2)}, nil)
+		// Then
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", &entity.Comment{ParentID: null.Uint64From(3)})
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
+	t.Run("when the parent does not exist", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(&entity.Article{ID: 1}, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(3)).Return(nil, sql.ErrNoRows)
+		// Then
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", &entity.Comment{ParentID: null.Uint64From(3)})
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
+	t.Run("when the thread is too deep", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		parent := &entity.Comment{ID: 3, ArticleID: null.Uint64From(1), RootID: null.Uint64From(1), Depth: MaxCommentDepth}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(&entity.Article{ID: 1}, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(3)).Return(parent, nil)
+		// Then
+		err := ServiceArticleMock.AddCommentToArticle(context.Background(), "test-slug", &entity.Comment{ParentID: null.Uint64From(3)})
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
 }
 
 func TestArticle_DeleteCommentFromArticle(t *testing.T) {
+	article := &entity.Article{ID: 1, Slug: "test-slug"}
 	t.Run("when Find article by slug return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		err := ServiceArticleMock.DeleteCommentFromArticle("test-slug", commentFoo.ID)
-		assert.Error(t, err, "FindArticleBySlug error")
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 1, "test-slug", commentFoo.ID)
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("when Find comment return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindCommentByID", mock.Anything).Return(nil, fmt.Errorf("FindCommentByID error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindCommentByID error"))
 		// Then
-		err := ServiceArticleMock.DeleteCommentFromArticle("test-slug", commentFoo.ID)
-		assert.Error(t, err, "FindCommentByID error")
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 1, "test-slug", commentFoo.ID)
+		assert.ErrorContains(t, err, "FindCommentByID error")
 	})
-	t.Run("when delete comment return ok", func(t *testing.T) {
+	t.Run("when comment belongs to another article", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(&entity.Article{ID: 2}, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(commentFoo, nil)
+		// Then
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 1, "test-slug", commentFoo.ID)
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("when caller is not the comment author", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(commentFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(memberFoo, nil)
+		// Then
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 2, "test-slug", commentFoo.ID)
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("when caller is a moderator", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(commentFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(moderatorFoo, nil)
+		articleMock.On("DeleteCommentByArticle", mock.Anything, article, commentFoo).Return(nil)
+		// Then
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 2, "test-slug", commentFoo.ID)
+		assert.NilError(t, err)
+	})
+	t.Run("when delete comment return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindCommentByID", mock.Anything).Return(commentFoo, nil)
-		articleMock.On("DeleteCommentByArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("DeleteCommentByArticle error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(commentFoo, nil)
+		articleMock.On("DeleteCommentByArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("DeleteCommentByArticle error"))
 		// Then
-		err := ServiceArticleMock.DeleteCommentFromArticle("test-slug", commentFoo.ID)
-		assert.Error(t, err, "DeleteCommentByArticle error")
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 1, "test-slug", commentFoo.ID)
+		assert.ErrorContains(t, err, "DeleteCommentByArticle error")
 	})
 	t.Run("when delete comment return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("FindCommentByID", mock.Anything).Return(commentFoo, nil)
-		articleMock.On("DeleteCommentByArticle", mock.Anything, mock.Anything).Return(nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, mock.Anything).Return(commentFoo, nil)
+		articleMock.On("DeleteCommentByArticle", mock.Anything, article, commentFoo).Return(nil)
 
 		// Then
-		err := ServiceArticleMock.DeleteCommentFromArticle("test-slug", commentFoo.ID)
+		err := ServiceArticleMock.DeleteCommentFromArticle(context.Background(), 1, "test-slug", commentFoo.ID)
 		assert.NilError(t, err)
 	})
 }
@@ -438,50 +753,51 @@ func TestArticle_AddFavoriteArticleBySlug(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		err := ServiceArticleMock.AddFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, err := ServiceArticleMock.AddFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("when find user by id return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("FindUserByID error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindUserByID error"))
 		// Then
-		err := ServiceArticleMock.AddFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "FindUserByID error")
+		_, err := ServiceArticleMock.AddFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "FindUserByID error")
 	})
 	t.Run("when find user by id return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userBar, nil)
-		articleMock.On("AddFavoriteArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("AddFavoriteArticle error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userBar, nil)
+		articleMock.On("AddFavoriteArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("AddFavoriteArticle error"))
 		// Then
-		err := ServiceArticleMock.AddFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "AddFavoriteArticle error")
+		_, err := ServiceArticleMock.AddFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "AddFavoriteArticle error")
 	})
 	t.Run("when add favorite article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userBar, nil)
-		articleMock.On("AddFavoriteArticle", mock.Anything, mock.Anything).Return(nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userBar, nil)
+		articleMock.On("AddFavoriteArticle", mock.Anything, articleFoo, userBar).Return(nil)
 		// Then
-		err := ServiceArticleMock.AddFavoriteArticleBySlug("test-slug", 1)
+		a, err := ServiceArticleMock.AddFavoriteArticleBySlug(context.Background(), "test-slug", 1)
 		assert.NilError(t, err)
+		assert.Equal(t, a, articleFoo)
 	})
 }
 
@@ -490,50 +806,80 @@ func TestArticle_RemoveFavoriteArticleBySlug(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		err := ServiceArticleMock.RemoveFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, err := ServiceArticleMock.RemoveFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("when find user by id return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("FindUserByID error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FindUserByID error"))
 		// Then
-		err := ServiceArticleMock.AddFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "FindUserByID error")
+		_, err := ServiceArticleMock.AddFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "FindUserByID error")
 	})
 	t.Run("when find user by id return error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userBar, nil)
-		articleMock.On("RemoveFavorite", mock.Anything, mock.Anything).Return(fmt.Errorf("RemoveFavorite error"))
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userBar, nil)
+		articleMock.On("RemoveFavorite", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("RemoveFavorite error"))
 		// Then
-		err := ServiceArticleMock.RemoveFavoriteArticleBySlug("test-slug", 1)
-		assert.Error(t, err, "RemoveFavorite error")
+		_, err := ServiceArticleMock.RemoveFavoriteArticleBySlug(context.Background(), "test-slug", 1)
+		assert.ErrorContains(t, err, "RemoveFavorite error")
 	})
 	t.Run("when add favorite article return ok", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userBar, nil)
-		articleMock.On("RemoveFavorite", mock.Anything, mock.Anything).Return(nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, mock.Anything).Return(articleFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userBar, nil)
+		articleMock.On("RemoveFavorite", mock.Anything, articleFoo, userBar).Return(nil)
 		// Then
-		err := ServiceArticleMock.RemoveFavoriteArticleBySlug("test-slug", 1)
+		a, err := ServiceArticleMock.RemoveFavoriteArticleBySlug(context.Background(), "test-slug", 1)
 		assert.NilError(t, err)
+		assert.Equal(t, a, articleFoo)
+	})
+}
+
+func TestArticle_FindFavoriteArticles(t *testing.T) {
+	t.Run("When the user does not exist", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "foo").Return(nil, sql.ErrNoRows)
+		// Then
+		_, _, err := ServiceArticleMock.FindFavoriteArticles(context.Background(), "foo", model.Page{Limit: 20})
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When the favorites are listed", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		page := model.Page{Limit: 20}
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "foo").Return(userFoo, nil)
+		articleMock.On("FindFavoriteArticlesByUser", mock.Anything, userFoo, page).Return([]*entity.Article{articleFoo}, int64(3), nil)
+		// Then
+		a, n, err := ServiceArticleMock.FindFavoriteArticles(context.Background(), "foo", page)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, a, []*entity.Article{articleFoo})
+		assert.Equal(t, n, int64(3))
 	})
 }
 
@@ -542,68 +888,277 @@ func TestArticle_AddTagToArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(nil, fmt.Errorf("FindArticleByAuthorIDAndSlug error"))
 		// Then
-		err := ServiceArticleMock.AddTagToArticle("slug-test", []string{"tag2"})
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, err := ServiceArticleMock.AddTagToArticle(context.Background(), 1, "slug-test", []string{"tag2"})
+		assert.ErrorContains(t, err, "FindArticleByAuthorIDAndSlug error")
 	})
-	t.Run("When ListTags failed with error", func(t *testing.T) {
+	t.Run("When caller is not the author", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
-		tag1 := &entity.Tag{
-			Tag: null.StringFrom("tag1"),
-		}
-		tag2 := &entity.Tag{
-			Tag: null.StringFrom("tag2"),
-		}
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("ListTags").Return([]*entity.Tag{tag1, tag2}, fmt.Errorf("ListTags error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(2), "slug-test").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug-test").Return(articleFoo, nil)
 		// Then
-		err := ServiceArticleMock.AddTagToArticle("slug-test", []string{"tag2"})
-		assert.Error(t, err, "ListTags error")
+		_, err := ServiceArticleMock.AddTagToArticle(context.Background(), 2, "slug-test", []string{"tag2"})
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
 	})
+	t.Run("When TagArticle failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("TagArticle", mock.Anything, articleFoo, []string{"tag2"}).Return(fmt.Errorf("TagArticle error"))
+		// Then
+		_, err := ServiceArticleMock.AddTagToArticle(context.Background(), 1, "slug-test", []string{"tag2"})
+		assert.ErrorContains(t, err, "TagArticle error")
+	})
+	t.Run("When AddTagToArticle return ok, unknown tags are passed along", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("TagArticle", mock.Anything, articleFoo, []string{"tag2", "new"}).Return(nil)
+		// Then
+		a, err := ServiceArticleMock.AddTagToArticle(context.Background(), 1, "slug-test", []string{" tag2", "new", "", "tag2"})
+		assert.NilError(t, err)
+		assert.Equal(t, a, articleFoo)
+	})
+}
 
-	t.Run("When AddTagToArticle failed with error", func(t *testing.T) {
+func TestArticle_RemoveTagFromArticle(t *testing.T) {
+	t.Run("When tag does not exist", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
-		tag1 := &entity.Tag{
-			Tag: null.StringFrom("tag1"),
-		}
-		tag2 := &entity.Tag{
-			Tag: null.StringFrom("tag2"),
-		}
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("ListTags").Return([]*entity.Tag{tag1, tag2}, nil)
-		articleMock.On("AddTagsToArticle", mock.Anything, mock.Anything).Return(fmt.Errorf("AddTagsToArticle error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(nil, sql.ErrNoRows)
 		// Then
-		err := ServiceArticleMock.AddTagToArticle("slug-test", []string{"tag2"})
-		assert.Error(t, err, "AddTagsToArticle error")
+		_, err := ServiceArticleMock.RemoveTagFromArticle(context.Background(), 1, "slug-test", "go")
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
 	})
-	t.Run("When AddTagToArticle return ok", func(t *testing.T) {
+	t.Run("When RemoveTagFromArticle failed with error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
-		tag1 := &entity.Tag{
-			Tag: null.StringFrom("tag1"),
-		}
-		tag2 := &entity.Tag{
-			Tag: null.StringFrom("tag2"),
-		}
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("ListTags").Return([]*entity.Tag{tag1, tag2}, nil)
-		articleMock.On("AddTagsToArticle", mock.Anything, mock.Anything).Return(nil)
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		articleMock.On("RemoveTagFromArticle", mock.Anything, articleFoo, tag).Return(fmt.Errorf("RemoveTagFromArticle error"))
 		// Then
-		err := ServiceArticleMock.AddTagToArticle("slug-test", []string{"tag2"})
+		_, err := ServiceArticleMock.RemoveTagFromArticle(context.Background(), 1, "slug-test", "go")
+		assert.ErrorContains(t, err, "RemoveTagFromArticle error")
+	})
+	t.Run("When RemoveTagFromArticle return ok", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		articleMock.On("RemoveTagFromArticle", mock.Anything, articleFoo, tag).Return(nil)
+		// Then
+		a, err := ServiceArticleMock.RemoveTagFromArticle(context.Background(), 1, "slug-test", "go")
+		assert.NilError(t, err)
+		assert.Equal(t, a, articleFoo)
+	})
+}
+
+func TestArticle_GetPopularTags(t *testing.T) {
+	t.Run("When CountArticlesByTags failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("ListTags", mock.Anything, mock.Anything).Return([]*entity.Tag{}, nil)
+		articleMock.On("CountArticlesByTags", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("CountArticlesByTags error"))
+		// Then
+		_, _, err := ServiceArticleMock.GetPopularTags(context.Background())
+		assert.ErrorContains(t, err, "CountArticlesByTags error")
+	})
+	t.Run("When GetPopularTags return ok, unused tags are left out", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		golang := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		web := &entity.Tag{ID: 2, Tag: null.StringFrom("web")}
+		api := &entity.Tag{ID: 3, Tag: null.StringFrom("api")}
+		unused := &entity.Tag{ID: 4, Tag: null.StringFrom("unused")}
+		// When
+		articleMock.On("ListTags", mock.Anything, mock.Anything).Return([]*entity.Tag{golang, web, api, unused}, nil)
+		articleMock.On("CountArticlesByTags", mock.Anything, mock.Anything).Return(map[uint64]int{1: 1, 2: 3, 3: 1}, nil)
+		// Then
+		tags, counts, err := ServiceArticleMock.GetPopularTags(context.Background())
+		assert.NilError(t, err)
+		assert.DeepEqual(t, tags, []*entity.Tag{web, api, golang})
+		assert.Equal(t, counts[2], 3)
+	})
+}
+
+func TestArticle_RenameTag(t *testing.T) {
+	t.Run("When caller is not an admin", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(moderatorFoo, nil)
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 2, "go", "golang")
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When tag does not exist", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(nil, sql.ErrNoRows)
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 1, "go", "golang")
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When RenameTag return ok", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		articleMock.On("RestoreTagByName", mock.Anything, "golang").Return(nil, sql.ErrNoRows)
+		articleMock.On("RenameTag", mock.Anything, tag, "golang").Return(nil)
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 1, "go", " golang ")
+		assert.NilError(t, err)
+	})
+	t.Run("When only the case of the name changes", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		articleMock.On("RestoreTagByName", mock.Anything, "Go").Return(tag, nil)
+		articleMock.On("RenameTag", mock.Anything, tag, "Go").Return(nil)
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 1, "go", "Go")
+		assert.NilError(t, err)
+	})
+	t.Run("When newName is taken by another tag", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		from := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		into := &entity.Tag{ID: 2, Tag: null.StringFrom("golang")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(from, nil)
+		articleMock.On("RestoreTagByName", mock.Anything, "golang").Return(into, nil)
+		articleMock.On("MergeTags", mock.Anything, from, into).Return(nil)
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 1, "go", "golang")
+		assert.NilError(t, err)
+	})
+	t.Run("When RestoreTagByName failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		articleMock.On("RestoreTagByName", mock.Anything, "golang").Return(nil, fmt.Errorf("some error"))
+		// Then
+		err := ServiceArticleMock.RenameTag(context.Background(), 1, "go", "golang")
+		assert.Equal(t, domain.KindOf(err), domain.KindInternal)
+	})
+}
+
+func TestArticle_MergeTags(t *testing.T) {
+	t.Run("When a tag is merged into itself", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// Then
+		err := ServiceArticleMock.MergeTags(context.Background(), 1, "go ", " go")
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
+	t.Run("When a tag is merged into a name differing by case only", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		tag := &entity.Tag{ID: 1, Tag: null.StringFrom("go")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "Go").Return(tag, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(tag, nil)
+		// Then
+		err := ServiceArticleMock.MergeTags(context.Background(), 1, "Go", "go")
+		assert.Equal(t, domain.KindOf(err), domain.KindValidation)
+	})
+	t.Run("When caller is not an admin", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(memberFoo, nil)
+		// Then
+		err := ServiceArticleMock.MergeTags(context.Background(), 2, "golang", "go")
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When target tag does not exist", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "golang").Return(&entity.Tag{ID: 1}, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(nil, sql.ErrNoRows)
+		// Then
+		err := ServiceArticleMock.MergeTags(context.Background(), 1, "golang", "go")
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When MergeTags return ok", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		from := &entity.Tag{ID: 1, Tag: null.StringFrom("golang")}
+		into := &entity.Tag{ID: 2, Tag: null.StringFrom("go")}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindTagByName", mock.Anything, "golang").Return(from, nil)
+		articleMock.On("FindTagByName", mock.Anything, "go").Return(into, nil)
+		articleMock.On("MergeTags", mock.Anything, from, into).Return(nil)
+		// Then
+		err := ServiceArticleMock.MergeTags(context.Background(), 1, " golang ", "go")
 		assert.NilError(t, err)
 	})
 }
@@ -613,61 +1168,316 @@ func TestArticle_UpdateArticle(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, fmt.Errorf("FindArticleBySlug error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug-test").Return(nil, fmt.Errorf("FindArticleBySlug error"))
 		// Then
-		err := ServiceArticleMock.UpdateArticle("slug-test", articleFoo)
-		assert.Error(t, err, "FindArticleBySlug error")
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 1, "slug-test", articleFoo, nil)
+		assert.ErrorContains(t, err, "FindArticleBySlug error")
 	})
 	t.Run("When Update article failed with error", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("UpdateArticle", mock.Anything).Return(fmt.Errorf("update article error"))
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("update article error"))
 		// Then
-		err := ServiceArticleMock.UpdateArticle("slug-test", articleFoo)
-		assert.Error(t, err, "update article error")
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 1, "slug-test", articleFoo, nil)
+		assert.ErrorContains(t, err, "update article error")
 	})
 	t.Run("When update article return OK", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("UpdateArticle", mock.Anything).Return(nil)
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		// Then
-		err := ServiceArticleMock.UpdateArticle("slug-test", articleFoo)
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 1, "slug-test", articleFoo, nil)
 		assert.NilError(t, err)
 	})
 	t.Run("When update article return OK, body is not valid", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
 		articleFoo.Body = null.StringFrom("")
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("UpdateArticle", mock.Anything).Return(nil)
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		// Then
-		err := ServiceArticleMock.UpdateArticle("slug-test", articleFoo)
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 1, "slug-test", articleFoo, nil)
 		assert.NilError(t, err)
 	})
 	t.Run("When update article return OK, description is not valid", func(t *testing.T) {
 		// Given
 		userMock := mockRepo.NewIRepoUser(t)
 		articleMock := mockRepo.NewIRepoArticle(t)
-		ServiceArticleMock := NewServiceArticle(articleMock, userMock)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
 		// When
 		articleFoo.Description = null.StringFrom("")
-		articleMock.On("FindArticleBySlug", mock.Anything).Return(articleFoo, nil)
-		articleMock.On("UpdateArticle", mock.Anything).Return(nil)
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "slug-test").Return(articleFoo, nil)
+		articleMock.On("UpdateArticle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		// Then
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 1, "slug-test", articleFoo, nil)
+		assert.NilError(t, err)
+	})
+	t.Run("When caller is not the author", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleByAuthorIDAndSlug", mock.Anything, uint64(2), "slug-test").Return(nil, sql.ErrNoRows)
+		articleMock.On("FindArticleBySlug", mock.Anything, "slug-test").Return(articleFoo, nil)
+		// Then
+		_, err := ServiceArticleMock.UpdateArticle(context.Background(), 2, "slug-test", articleFoo, nil)
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+}
+
+func TestArticle_FindTrashedArticles(t *testing.T) {
+	t.Run("When the trashed articles of the caller are listed", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("ListDeletedArticlesByAuthor", mock.Anything, &entity.User{ID: 1}, 0, 20).Return([]*entity.Article{articleFoo}, int64(1), nil)
+		// Then
+		a, n, err := ServiceArticleMock.FindTrashedArticles(context.Background(), 1, 0, 20)
+		assert.NilError(t, err)
+		assert.Equal(t, len(a), 1)
+		assert.Equal(t, n, int64(1))
+	})
+	t.Run("When ListDeletedArticlesByAuthor failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("ListDeletedArticlesByAuthor", mock.Anything, mock.Anything, 0, 20).Return(nil, int64(0), fmt.Errorf("ListDeletedArticlesByAuthor error"))
+		// Then
+		_, _, err := ServiceArticleMock.FindTrashedArticles(context.Background(), 1, 0, 20)
+		assert.ErrorContains(t, err, "ListDeletedArticlesByAuthor error")
+	})
+}
+
+func TestArticle_RestoreArticle(t *testing.T) {
+	t.Run("When the article is not in the trash of the caller", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindDeletedArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "foo-slug").Return(nil, sql.ErrNoRows)
+		// Then
+		_, err := ServiceArticleMock.RestoreArticle(context.Background(), 1, "foo-slug")
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When the article is restored", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		trashed := &entity.Article{ID: 1, Slug: "foo-slug"}
+		// When
+		articleMock.On("FindDeletedArticleByAuthorIDAndSlug", mock.Anything, uint64(1), "foo-slug").Return(trashed, nil)
+		articleMock.On("RestoreArticle", mock.Anything, trashed).Return(nil)
+		// Then
+		a, err := ServiceArticleMock.RestoreArticle(context.Background(), 1, "foo-slug")
+		assert.NilError(t, err)
+		assert.Equal(t, a, trashed)
+	})
+}
+
+func TestArticle_RestoreComment(t *testing.T) {
+	t.Run("When caller is not the comment author", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindDeletedCommentByID", mock.Anything, uint64(2)).Return(&entity.Comment{ID: 2, UserID: null.Uint64From(3)}, nil)
+		// Then
+		_, err := ServiceArticleMock.RestoreComment(context.Background(), 1, 2)
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When the comment is restored", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		trashed := &entity.Comment{ID: 2, UserID: null.Uint64From(1)}
+		// When
+		articleMock.On("FindDeletedCommentByID", mock.Anything, uint64(2)).Return(trashed, nil)
+		articleMock.On("RestoreComment", mock.Anything, trashed).Return(nil)
+		// Then
+		c, err := ServiceArticleMock.RestoreComment(context.Background(), 1, 2)
+		assert.NilError(t, err)
+		assert.Equal(t, c, trashed)
+	})
+	t.Run("When the comment replied to is deleted", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		trashed := &entity.Comment{ID: 2, UserID: null.Uint64From(1), ParentID: null.Uint64From(1)}
+		// When
+		articleMock.On("FindDeletedCommentByID", mock.Anything, uint64(2)).Return(trashed, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(1)).Return(nil, sql.ErrNoRows)
+		// Then
+		_, err := ServiceArticleMock.RestoreComment(context.Background(), 1, 2)
+		assert.Equal(t, domain.KindOf(err), domain.KindConflict)
+	})
+}
+
+func TestArticle_UpdateCommentOfArticle(t *testing.T) {
+	article := &entity.Article{ID: 1, Slug: "test-slug"}
+	t.Run("When the comment is on another article", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(&entity.Comment{ID: 2, ArticleID: null.Uint64From(3)}, nil)
+		// Then
+		_, err := ServiceArticleMock.UpdateCommentOfArticle(context.Background(), 1, "test-slug", 2, "new body")
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When caller is not the comment author", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).
+			Return(&entity.Comment{ID: 2, ArticleID: null.Uint64From(1), UserID: null.Uint64From(3)}, nil)
+		// Then
+		_, err := ServiceArticleMock.UpdateCommentOfArticle(context.Background(), 1, "test-slug", 2, "new body")
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When the body is unchanged", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		comment := &entity.Comment{ID: 2, ArticleID: null.Uint64From(1), UserID: null.Uint64From(1), Body: null.StringFrom("body")}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(comment, nil)
 		// Then
-		err := ServiceArticleMock.UpdateArticle("slug-test", articleFoo)
+		c, err := ServiceArticleMock.UpdateCommentOfArticle(context.Background(), 1, "test-slug", 2, "body")
 		assert.NilError(t, err)
+		assert.Equal(t, c, comment)
+	})
+	t.Run("When edit comment return error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		comment := &entity.Comment{ID: 2, ArticleID: null.Uint64From(1), UserID: null.Uint64From(1), Body: null.StringFrom("body")}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(comment, nil)
+		articleMock.On("EditComment", mock.Anything, comment, "new body").Return(fmt.Errorf("EditComment error"))
+		// Then
+		_, err := ServiceArticleMock.UpdateCommentOfArticle(context.Background(), 1, "test-slug", 2, "new body")
+		assert.ErrorContains(t, err, "EditComment error")
+	})
+	t.Run("When the comment is edited", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		comment := &entity.Comment{ID: 2, ArticleID: null.Uint64From(1), UserID: null.Uint64From(1), Body: null.StringFrom("body")}
+		// When
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(comment, nil)
+		articleMock.On("EditComment", mock.Anything, comment, "new body").Return(nil)
+		// Then
+		c, err := ServiceArticleMock.UpdateCommentOfArticle(context.Background(), 1, "test-slug", 2, "new body")
+		assert.NilError(t, err)
+		assert.Equal(t, c, comment)
+	})
+}
+
+func TestArticle_FindCommentHistory(t *testing.T) {
+	article := &entity.Article{ID: 1, Slug: "test-slug"}
+	comment := &entity.Comment{ID: 2, ArticleID: null.Uint64From(1)}
+	t.Run("When caller is not a moderator", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(memberFoo, nil)
+		// Then
+		_, _, err := ServiceArticleMock.FindCommentHistory(context.Background(), 2, "test-slug", 2)
+		assert.Equal(t, domain.KindOf(err), domain.KindForbidden)
+	})
+	t.Run("When the comment does not exist", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(moderatorFoo, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(nil, sql.ErrNoRows)
+		// Then
+		_, _, err := ServiceArticleMock.FindCommentHistory(context.Background(), 2, "test-slug", 2)
+		assert.Equal(t, domain.KindOf(err), domain.KindNotFound)
+	})
+	t.Run("When the revisions are listed", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		revisions := []*entity.CommentRevision{{ID: 1, CommentID: 2, Body: null.StringFrom("first")}}
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(adminFoo, nil)
+		articleMock.On("FindArticleBySlug", mock.Anything, "test-slug").Return(article, nil)
+		articleMock.On("FindCommentByID", mock.Anything, uint64(2)).Return(comment, nil)
+		articleMock.On("ListCommentRevisions", mock.Anything, comment).Return(revisions, nil)
+		// Then
+		c, revs, err := ServiceArticleMock.FindCommentHistory(context.Background(), 1, "test-slug", 2)
+		assert.NilError(t, err)
+		assert.Equal(t, c, comment)
+		assert.DeepEqual(t, revs, revisions)
+	})
+}
+
+func TestArticle_PurgeTrash(t *testing.T) {
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("When the articles and the users are purged", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("PurgeDeleted", mock.Anything, before).Return(int64(3), nil)
+		userMock.On("PurgeDeleted", mock.Anything, before).Return(int64(1), nil)
+		// Then
+		n, err := ServiceArticleMock.PurgeTrash(context.Background(), before)
+		assert.NilError(t, err)
+		assert.Equal(t, n, int64(4))
+	})
+	t.Run("When the articles purge failed with error", func(t *testing.T) {
+		// Given
+		userMock := mockRepo.NewIRepoUser(t)
+		articleMock := mockRepo.NewIRepoArticle(t)
+		ServiceArticleMock := NewServiceArticle(articleMock, userMock, directUnitOfWork{}, nil)
+		// When
+		articleMock.On("PurgeDeleted", mock.Anything, before).Return(int64(0), fmt.Errorf("PurgeDeleted error"))
+		// Then
+		_, err := ServiceArticleMock.PurgeTrash(context.Background(), before)
+		assert.ErrorContains(t, err, "PurgeDeleted error")
 	})
 }
@@ -0,0 +1,85 @@
+package article
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// maxSlugLength leave room for a collision suffix within the 255 chars of articles.slug
+	maxSlugLength = 200
+	// defaultSlug is used when nothing of the title survives the transliteration
+	defaultSlug = "article"
+)
+
+// transliterations of the letters which have no ASCII decomposition
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'đ': "d", 'ð': "d", 'ħ': "h",
+	'ı': "i", 'ł': "l", 'þ': "th", 'ŋ': "ng",
+	// cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ј': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y",
+	'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// Slugify turn a title into a lowercase, ASCII only, hyphen separated slug
+func Slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, c := range norm.NFKD.String(strings.ToLower(title)) {
+		var s string
+		switch t, ok := transliterations[c]; {
+		case ok:
+			s = t
+		case unicode.Is(unicode.Mn, c):
+			continue
+		case c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)):
+			s = string(c)
+		default:
+			hyphen = b.Len() > 0
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(s)
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		return defaultSlug
+	}
+	return slug
+}
+
+// uniqueSlug derive the slug of a title, suffixed with -2, -3... when it is already used by
+// another article than articleID or by one of their redirects
+func (r *Service) uniqueSlug(ctx context.Context, title string, articleID uint64) (string, error) {
+	base := Slugify(title)
+	taken, err := r.Repo.FindTakenSlugs(ctx, base, articleID)
+	if err != nil {
+		return "", err
+	}
+	slug := base
+	for i := 2; taken[slug]; i++ {
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	return slug, nil
+}
@@ -0,0 +1,38 @@
+package article
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "ascii", title: "How to train your dragon", want: "how-to-train-your-dragon"},
+		{name: "punctuation", title: "  Hello, World!!  (again) ", want: "hello-world-again"},
+		{name: "accents", title: "Crème brûlée à la française", want: "creme-brulee-a-la-francaise"},
+		{name: "special letters", title: "Straße Ærø Łódź", want: "strasse-aero-lodz"},
+		{name: "cyrillic", title: "Привет мир", want: "privet-mir"},
+		{name: "greek", title: "Γειά σου κόσμε", want: "geia-soy-kosme"},
+		{name: "ligatures", title: "ﬁne ½", want: "fine-1-2"},
+		{name: "nothing left", title: "日本語 🎉", want: defaultSlug},
+		{name: "empty", title: "", want: defaultSlug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slugify(tt.title); got != tt.want {
+				t.Errorf("Slugify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugify_TooLong(t *testing.T) {
+	got := Slugify(strings.Repeat("abcd ", 100))
+	if len(got) > maxSlugLength || strings.HasSuffix(got, "-") {
+		t.Errorf("Slugify() = %v, want at most %d chars without trailing hyphen", got, maxSlugLength)
+	}
+}
@@ -0,0 +1,27 @@
+package article
+
+import "schema/entity"
+
+// MaxCommentDepth is the number of levels of replies allowed below a top-level comment
+const MaxCommentDepth = 5
+
+// threadOrder lay out the threads started by roots in reading order: every comment followed by its
+// replies, oldest first. The replies whose parent is not in the threads are left out
+func threadOrder(roots, replies []*entity.Comment) []*entity.Comment {
+	children := make(map[uint64][]*entity.Comment)
+	for _, reply := range replies {
+		children[reply.ParentID.Uint64] = append(children[reply.ParentID.Uint64], reply)
+	}
+	ordered := make([]*entity.Comment, 0, len(roots)+len(replies))
+	var walk func(cm *entity.Comment)
+	walk = func(cm *entity.Comment) {
+		ordered = append(ordered, cm)
+		for _, reply := range children[cm.ID] {
+			walk(reply)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return ordered
+}
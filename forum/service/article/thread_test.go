@@ -0,0 +1,24 @@
+package article
+
+import (
+	"schema/entity"
+	"testing"
+
+	"github.com/volatiletech/null/v8"
+	"gotest.tools/assert"
+)
+
+func TestThreadOrder(t *testing.T) {
+	reply := func(id, parent uint64) *entity.Comment {
+		return &entity.Comment{ID: id, ParentID: null.Uint64From(parent)}
+	}
+	roots := []*entity.Comment{{ID: 1}, {ID: 2}}
+	// oldest first, as FindRepliesByRoots lists them
+	replies := []*entity.Comment{reply(3, 1), reply(4, 2), reply(5, 3), reply(6, 1), reply(7, 99)}
+
+	var ids []uint64
+	for _, cm := range threadOrder(roots, replies) {
+		ids = append(ids, cm.ID)
+	}
+	assert.DeepEqual(t, ids, []uint64{1, 3, 5, 6, 2, 4})
+}
@@ -3,20 +3,55 @@
 package service
 
 import (
+	"context"
 	"forum/model"
 	"schema/entity"
+	"time"
 )
 
 // IServiceUser ...
 type IServiceUser interface {
-	CheckUser(user *model.LoginUser) error
-	CreateUser(user *model.RegisterUser) error
-	FollowUserByUserName(uid uint, userName string) error
-	GetUserByID(uid uint) (*entity.User, error)
-	GetUserByEmail(email string) (*entity.User, error)
-	GetUserByUserName(username string) (*entity.User, error)
-	UnFollowUserByUserName(uid uint, userName string) error
-	GetFollowersByUserID(uid uint) ([]*entity.User, error)
-	GetFollowingUser(uid uint) ([]*entity.User, error)
-	UpdateUser(user *entity.User) error
+	// CheckUser verify the login credentials, return the matching user
+	CheckUser(ctx context.Context, user *model.LoginUser) (*entity.User, error)
+	// CreateUser register a new user, return the created user
+	CreateUser(ctx context.Context, user *model.RegisterUser) (*entity.User, error)
+	// FollowUserByUserName make the user uid follow the user named userName, return the followed user
+	FollowUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error)
+	GetUserByID(ctx context.Context, uid uint) (*entity.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetUserByUserName(ctx context.Context, username string) (*entity.User, error)
+	// UnFollowUserByUserName make the user uid stop following the user named userName, return the unfollowed user
+	UnFollowUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error)
+	// GetProfile find the user named userName and whether the viewer uid follows it, uid 0 means anonymous
+	GetProfile(ctx context.Context, uid uint, userName string) (*entity.User, bool, error)
+	// GetFollowersByUserName list the followers of the user named userName with pagination
+	GetFollowersByUserName(ctx context.Context, userName string, offset, limit int) ([]*entity.User, int64, error)
+	// GetFollowingByUserName list the users followed by the user named userName with pagination
+	GetFollowingByUserName(ctx context.Context, userName string, offset, limit int) ([]*entity.User, int64, error)
+	// GetFollowingFlags tell which of users are followed by the viewer uid, uid 0 means anonymous
+	GetFollowingFlags(ctx context.Context, uid uint, users []*entity.User) (map[uint64]bool, error)
+	// UpdateUser apply the fields set in update to the user uid, return the updated user
+	UpdateUser(ctx context.Context, uid uint, update *model.UpdateUser) (*entity.User, error)
+	// SetUserRole give the user named userName the role role, only the admin uid is allowed to. An admin cannot
+	// change its own role, so that the last one never locks everybody out
+	SetUserRole(ctx context.Context, uid uint, userName, role string) (*entity.User, error)
+	// DeleteUserByUserName move the user named userName to the trash and end its sessions, only the admin uid is
+	// allowed to, and not on itself
+	DeleteUserByUserName(ctx context.Context, uid uint, userName string) error
+	// FindTrashedUsers list the users in the trash, the last deleted first, only the admin uid is allowed to
+	FindTrashedUsers(ctx context.Context, uid uint, offset, limit int) ([]*entity.User, int64, error)
+	// RestoreUserByUserName take the user named userName out of the trash, only the admin uid is allowed to
+	RestoreUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error)
+	// IssueTokens open a session for u, it returns a short-lived access token and the refresh token to get the next ones
+	IssueTokens(ctx context.Context, u *entity.User) (string, string, error)
+	// RefreshSession trade the refresh token raw for a new access token and a new refresh token, it returns the
+	// user of the session with them. Presenting a refresh token already traded revokes every refresh token of its
+	// user, as one of the two parties presenting it stole it
+	RefreshSession(ctx context.Context, raw string) (*entity.User, string, string, error)
+	// Logout revoke the access token jti of the user uid until it expires at expiresAt, then the refresh token raw
+	// of the same user when it is not empty. An unknown refresh token, or one of another user, is ignored so that
+	// it never keeps the access token alive
+	Logout(ctx context.Context, uid uint, jti string, expiresAt time.Time, raw string) error
+	// IsTokenRevoked tell whether the access token jti was revoked, it is the denylist of the jwt middlewares
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
 }
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"http/utils"
 	"schema/entity"
 
 	"forum/model"
@@ -16,6 +17,12 @@ var (
 		Bio:      null.StringFrom("foo bio"),
 		Image:    null.StringFrom("foo image"),
 	}
+	userBar = &entity.User{
+		ID:       2,
+		Username: "bar",
+		Email:    "bar@bar.com",
+		Password: "123456",
+	}
 	userWithoutBio = &entity.User{
 		Username: "foo",
 		Email:    "foo@foo.com",
@@ -45,3 +52,7 @@ var (
 		Image:    nil,
 	}
 )
+
+func init() {
+	utils.JWTKeys, _ = utils.NewKeySet(utils.HMACKey("test", []byte("secret")))
+}
@@ -1,13 +1,20 @@
 package user
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"http/utils"
 	"schema/entity"
+	"time"
 
+	"forum/domain"
 	"forum/model"
 	"forum/repository"
 	"forum/service"
 
 	"github.com/rs/zerolog/log"
+	"github.com/volatiletech/null/v8"
 )
 
 type Service struct {
@@ -20,87 +27,395 @@ func NewUserService(r repository.IRepoUser) *Service {
 	}
 }
 
-func (s *Service) CheckUser(user *model.LoginUser) error {
-	userInfo, err := s.Repo.FindByEmail(user.Email)
+// CheckUser verify the login credentials, return the matching user
+func (s *Service) CheckUser(ctx context.Context, user *model.LoginUser) (*entity.User, error) {
+	userInfo, err := s.Repo.FindByEmail(ctx, user.Email)
 	if err != nil {
 		log.Error().Err(err).Msg("FindByEmail error")
-		return err
+		return nil, domain.Wrap("user", err)
 	}
-
-	return service.CheckPassword(userInfo.Password, user.Password)
+	if err = service.CheckPassword(user.Password, userInfo.Password); err != nil {
+		log.Error().Err(err).Msg("CheckPassword error")
+		return nil, domain.NotFound("user")
+	}
+	return userInfo, nil
 }
 
-func (s *Service) CreateUser(user *model.RegisterUser) error {
+// CreateUser register a new user, return the created user
+func (s *Service) CreateUser(ctx context.Context, user *model.RegisterUser) (*entity.User, error) {
 	passWord, err := service.HashPassword(user.Password)
 	if err != nil {
 		log.Error().Err(err).Msg("HashPassword error")
-		return err
+		return nil, domain.Validation(err.Error())
 	}
 	var u entity.User
 	u.Username = user.Username
 	u.Email = user.Email
 	u.Password = passWord
-	return s.Repo.CreateUser(&u)
+	u.Role = entity.UsersRoleUser
+	if err = s.Repo.CreateUser(ctx, &u); err != nil {
+		log.Error().Err(err).Msg("CreateUser error")
+		return nil, domain.Wrap("user", err)
+	}
+	return &u, nil
 }
 
-func (s *Service) FollowUserByUserName(uid uint, userName string) error {
-	targetUser, err := s.Repo.FindUserByUserName(userName)
+// FollowUserByUserName make the user uid follow the user named userName, return the followed user
+func (s *Service) FollowUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error) {
+	targetUser, err := s.Repo.FindUserByUserName(ctx, userName)
 	if err != nil {
 		log.Error().Err(err).Msg("FindByUserName error")
-		return err
+		return nil, domain.Wrap("user", err)
 	}
-	loggedUser, err := s.Repo.FindUserByID(uid)
+	if targetUser.ID == uint64(uid) {
+		return nil, domain.Validation("a user cannot follow itself")
+	}
+	loggedUser, err := s.Repo.FindUserByID(ctx, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("findCurrentUserAndTargetUser error")
-		return err
+		return nil, domain.Wrap("user", err)
 	}
-	return s.Repo.AddFollower(loggedUser, targetUser)
+	if err = s.Repo.AddFollower(ctx, targetUser, loggedUser); err != nil {
+		log.Error().Err(err).Msg("AddFollower error")
+		return nil, domain.Wrap("follow", err)
+	}
+	return targetUser, nil
 }
 
-func (s *Service) GetUserByID(uid uint) (*entity.User, error) {
-	return s.Repo.FindUserByID(uid)
+func (s *Service) GetUserByID(ctx context.Context, uid uint) (*entity.User, error) {
+	u, err := s.Repo.FindUserByID(ctx, uid)
+	return u, domain.Wrap("user", err)
 }
 
-func (s *Service) GetUserByEmail(email string) (*entity.User, error) {
-	return s.Repo.FindByEmail(email)
+func (s *Service) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	u, err := s.Repo.FindByEmail(ctx, email)
+	return u, domain.Wrap("user", err)
 }
 
-func (s *Service) GetUserByUserName(username string) (*entity.User, error) {
-	return s.Repo.FindUserByUserName(username)
+func (s *Service) GetUserByUserName(ctx context.Context, username string) (*entity.User, error) {
+	u, err := s.Repo.FindUserByUserName(ctx, username)
+	return u, domain.Wrap("user", err)
 }
 
-func (s *Service) UnFollowUserByUserName(uid uint, userName string) error {
-	targetUser, err := s.Repo.FindUserByUserName(userName)
+// UnFollowUserByUserName make the user uid stop following the user named userName, return the unfollowed user
+func (s *Service) UnFollowUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error) {
+	targetUser, err := s.Repo.FindUserByUserName(ctx, userName)
 	if err != nil {
 		log.Error().Err(err).Msg("FindByUserName error")
-		return err
+		return nil, domain.Wrap("user", err)
 	}
-	loggedUser, err := s.Repo.FindUserByID(uid)
+	loggedUser, err := s.Repo.FindUserByID(ctx, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("FindByUserID error")
-		return err
+		return nil, domain.Wrap("user", err)
+	}
+	if err = s.Repo.RemoveFollower(ctx, targetUser, loggedUser); err != nil {
+		log.Error().Err(err).Msg("RemoveFollower error")
+		return nil, domain.Wrap("follow", err)
 	}
-	return s.Repo.RemoveFollower(loggedUser, targetUser)
+	return targetUser, nil
 }
 
-func (s *Service) GetFollowersByUserID(uid uint) ([]*entity.User, error) {
-	currentUser, err := s.Repo.FindUserByID(uid)
+// GetProfile find the user named userName and whether the viewer uid follows it, uid 0 means anonymous
+func (s *Service) GetProfile(ctx context.Context, uid uint, userName string) (*entity.User, bool, error) {
+	targetUser, err := s.Repo.FindUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindByUserName error")
+		return nil, false, domain.Wrap("user", err)
+	}
+	if uid == 0 {
+		return targetUser, false, nil
+	}
+	viewer, err := s.Repo.FindUserByID(ctx, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("FindUserByID error")
-		return nil, err
+		return nil, false, domain.Wrap("user", err)
+	}
+	following, err := s.Repo.IsFollower(ctx, targetUser, viewer)
+	if err != nil {
+		log.Error().Err(err).Msg("IsFollower error")
+		return nil, false, domain.Wrap("follow", err)
+	}
+	return targetUser, following, nil
+}
+
+// GetFollowersByUserName list the followers of the user named userName with pagination
+func (s *Service) GetFollowersByUserName(ctx context.Context, userName string, offset, limit int) ([]*entity.User, int64, error) {
+	user, err := s.Repo.FindUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindByUserName error")
+		return nil, 0, domain.Wrap("user", err)
 	}
-	return s.Repo.GetFollowers(currentUser)
+	users, n, err := s.Repo.GetFollowers(ctx, user, offset, limit)
+	return users, n, domain.Wrap("followers", err)
 }
 
-func (s *Service) GetFollowingUser(uid uint) ([]*entity.User, error) {
-	currentUser, err := s.Repo.FindUserByID(uid)
+// GetFollowingByUserName list the users followed by the user named userName with pagination
+func (s *Service) GetFollowingByUserName(ctx context.Context, userName string, offset, limit int) ([]*entity.User, int64, error) {
+	user, err := s.Repo.FindUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindByUserName error")
+		return nil, 0, domain.Wrap("user", err)
+	}
+	users, n, err := s.Repo.GetFollowingUsers(ctx, user, offset, limit)
+	return users, n, domain.Wrap("following", err)
+}
+
+// GetFollowingFlags tell which of users are followed by the viewer uid, uid 0 means anonymous
+func (s *Service) GetFollowingFlags(ctx context.Context, uid uint, users []*entity.User) (map[uint64]bool, error) {
+	if uid == 0 {
+		return map[uint64]bool{}, nil
+	}
+	viewer, err := s.Repo.FindUserByID(ctx, uid)
+	if err != nil {
+		log.Error().Err(err).Msg("FindUserByID error")
+		return nil, domain.Wrap("user", err)
+	}
+	ids := make([]uint64, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	following, err := s.Repo.FindFollowingIDs(ctx, viewer, ids)
+	return following, domain.Wrap("follows", err)
+}
+
+// UpdateUser apply the fields set in update to the user uid, return the updated user
+func (s *Service) UpdateUser(ctx context.Context, uid uint, update *model.UpdateUser) (*entity.User, error) {
+	u, err := s.Repo.FindUserByID(ctx, uid)
+	if err != nil {
+		log.Error().Err(err).Msg("FindUserByID error")
+		return nil, domain.Wrap("user", err)
+	}
+	if update.Username != "" {
+		u.Username = update.Username
+	}
+	if update.Email != "" {
+		u.Email = update.Email
+	}
+	if update.Password != "" {
+		u.Password, err = service.HashPassword(update.Password)
+		if err != nil {
+			log.Error().Err(err).Msg("HashPassword error")
+			return nil, domain.Validation(err.Error())
+		}
+	}
+	if update.Bio != "" {
+		u.Bio = null.StringFrom(update.Bio)
+	}
+	if update.Image != "" {
+		u.Image = null.StringFrom(update.Image)
+	}
+	if err = s.Repo.UpdateUser(ctx, u); err != nil {
+		log.Error().Err(err).Msg("UpdateUser error")
+		return nil, domain.Wrap("user", err)
+	}
+	return u, nil
+}
+
+// requireAdmin check the user uid is an admin, its role is read from the database rather than trusted from its token
+func (s *Service) requireAdmin(ctx context.Context, uid uint) error {
+	u, err := s.Repo.FindUserByID(ctx, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("FindUserByID error")
+		return domain.Wrap("user", err)
+	}
+	if u.Role != entity.UsersRoleAdmin {
+		return domain.Forbidden("user management")
+	}
+	return nil
+}
+
+// SetUserRole give the user named userName the role role, only the admin uid is allowed to. An admin cannot
+// change its own role, so that the last one never locks everybody out
+func (s *Service) SetUserRole(ctx context.Context, uid uint, userName, role string) (*entity.User, error) {
+	if err := s.requireAdmin(ctx, uid); err != nil {
 		return nil, err
 	}
-	return s.Repo.GetFollowingUsers(currentUser)
+	valid := false
+	for _, r := range entity.AllUsersRole() {
+		valid = valid || r == role
+	}
+	if !valid {
+		return nil, domain.Validation("unknown role " + role)
+	}
+	u, err := s.Repo.FindUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindUserByUserName error")
+		return nil, domain.Wrap("user", err)
+	}
+	if u.ID == uint64(uid) {
+		return nil, domain.Conflict("an admin cannot change its own role")
+	}
+	if u.Role == role {
+		return u, nil
+	}
+	u.Role = role
+	if err = s.Repo.UpdateUser(ctx, u); err != nil {
+		log.Error().Err(err).Msg("UpdateUser error")
+		return nil, domain.Wrap("user", err)
+	}
+	return u, nil
+}
+
+// DeleteUserByUserName move the user named userName to the trash and end its sessions, only the admin uid is
+// allowed to, and not on itself
+func (s *Service) DeleteUserByUserName(ctx context.Context, uid uint, userName string) error {
+	if err := s.requireAdmin(ctx, uid); err != nil {
+		return err
+	}
+	u, err := s.Repo.FindUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindUserByUserName error")
+		return domain.Wrap("user", err)
+	}
+	if u.ID == uint64(uid) {
+		return domain.Conflict("an admin cannot delete itself")
+	}
+	if err = s.Repo.DeleteUser(ctx, u); err != nil {
+		log.Error().Err(err).Msg("DeleteUser error")
+		return domain.Wrap("user", err)
+	}
+	if err = s.Repo.RevokeRefreshTokens(ctx, u.ID); err != nil {
+		log.Error().Err(err).Msg("RevokeRefreshTokens error")
+		return domain.Wrap("refresh token", err)
+	}
+	return nil
+}
+
+// FindTrashedUsers list the users in the trash, the last deleted first, only the admin uid is allowed to
+func (s *Service) FindTrashedUsers(ctx context.Context, uid uint, offset, limit int) ([]*entity.User, int64, error) {
+	if err := s.requireAdmin(ctx, uid); err != nil {
+		return nil, 0, err
+	}
+	users, n, err := s.Repo.ListDeletedUsers(ctx, offset, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("ListDeletedUsers error")
+		return nil, 0, domain.Wrap("users", err)
+	}
+	return users, n, nil
+}
+
+// RestoreUserByUserName take the user named userName out of the trash, only the admin uid is allowed to
+func (s *Service) RestoreUserByUserName(ctx context.Context, uid uint, userName string) (*entity.User, error) {
+	if err := s.requireAdmin(ctx, uid); err != nil {
+		return nil, err
+	}
+	u, err := s.Repo.FindDeletedUserByUserName(ctx, userName)
+	if err != nil {
+		log.Error().Err(err).Msg("FindDeletedUserByUserName error")
+		return nil, domain.Wrap("user", err)
+	}
+	if err = s.Repo.RestoreUser(ctx, u); err != nil {
+		log.Error().Err(err).Msg("RestoreUser error")
+		return nil, domain.Wrap("user", err)
+	}
+	return u, nil
+}
+
+// IssueTokens open a session for u, it returns a short-lived access token and the refresh token to get the next ones
+func (s *Service) IssueTokens(ctx context.Context, u *entity.User) (string, string, error) {
+	access, err := utils.GenerateJWT(uint(u.ID), u.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("GenerateJWT error")
+		return "", "", domain.Internal(err)
+	}
+	token, raw, err := newRefreshToken(u.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("newRefreshToken error")
+		return "", "", domain.Internal(err)
+	}
+	if err = s.Repo.CreateRefreshToken(ctx, token); err != nil {
+		log.Error().Err(err).Msg("CreateRefreshToken error")
+		return "", "", domain.Wrap("refresh token", err)
+	}
+	return access, raw, nil
+}
+
+// RefreshSession trade the refresh token raw for a new access token and a new refresh token, it returns the
+// user of the session with them. Presenting a refresh token already traded revokes every refresh token of its
+// user, as one of the two parties presenting it stole it
+func (s *Service) RefreshSession(ctx context.Context, raw string) (*entity.User, string, string, error) {
+	token, err := s.Repo.FindRefreshToken(ctx, hashToken(raw))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", "", domain.Unauthorized("invalid refresh token")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("FindRefreshToken error")
+		return nil, "", "", domain.Wrap("refresh token", err)
+	}
+	if token.RevokedAt.Valid {
+		if err = s.Repo.RevokeRefreshTokens(ctx, token.UserID); err != nil {
+			log.Error().Err(err).Msg("RevokeRefreshTokens error")
+			return nil, "", "", domain.Wrap("refresh token", err)
+		}
+		return nil, "", "", domain.Unauthorized("invalid refresh token")
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, "", "", domain.Unauthorized("refresh token expired")
+	}
+	u, err := s.Repo.FindUserByID(ctx, uint(token.UserID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", "", domain.Unauthorized("invalid refresh token")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("FindUserByID error")
+		return nil, "", "", domain.Wrap("user", err)
+	}
+	access, err := utils.GenerateJWT(uint(u.ID), u.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("GenerateJWT error")
+		return nil, "", "", domain.Internal(err)
+	}
+	next, nextRaw, err := newRefreshToken(u.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("newRefreshToken error")
+		return nil, "", "", domain.Internal(err)
+	}
+	err = s.Repo.RotateRefreshToken(ctx, token, next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", "", domain.Unauthorized("invalid refresh token")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("RotateRefreshToken error")
+		return nil, "", "", domain.Wrap("refresh token", err)
+	}
+	return u, access, nextRaw, nil
+}
+
+// Logout revoke the access token jti of the user uid until it expires at expiresAt, then the refresh token raw
+// of the same user when it is not empty. An unknown refresh token, or one of another user, is ignored so that
+// it never keeps the access token alive
+func (s *Service) Logout(ctx context.Context, uid uint, jti string, expiresAt time.Time, raw string) error {
+	if jti != "" {
+		if err := s.Repo.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+			log.Error().Err(err).Msg("RevokeAccessToken error")
+			return domain.Wrap("access token", err)
+		}
+	}
+	if raw == "" {
+		return nil
+	}
+	token, err := s.Repo.FindRefreshToken(ctx, hashToken(raw))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("FindRefreshToken error")
+		return domain.Wrap("refresh token", err)
+	}
+	if token.UserID != uint64(uid) {
+		log.Warn().Uint("uid", uid).Msg("logout with the refresh token of another user")
+		return nil
+	}
+	if err = s.Repo.RevokeRefreshToken(ctx, token); err != nil {
+		log.Error().Err(err).Msg("RevokeRefreshToken error")
+		return domain.Wrap("refresh token", err)
+	}
+	return nil
 }
 
-func (s *Service) UpdateUser(user *entity.User) error {
-	return s.Repo.UpdateUser(user)
+// IsTokenRevoked tell whether the access token jti was revoked, it is the denylist of the jwt middlewares
+func (s *Service) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := s.Repo.IsAccessTokenRevoked(ctx, jti)
+	return revoked, domain.Wrap("access token", err)
 }
@@ -1,25 +1,43 @@
 package user
 
 import (
-	"http/utils"
 	"schema/entity"
 
 	"forum/model"
 )
 
-func NewProfileResponse(u *entity.User) *model.ProfileResponse {
+func NewProfileResponse(u *entity.User, following bool) *model.ProfileResponse {
 	r := new(model.ProfileResponse)
-	r.Profile.Username = u.Username
+	r.Profile = *newProfileType(u, following)
+	return r
+}
+
+func NewProfileListResponse(users []*entity.User, following map[uint64]bool, count int64) *model.ProfileListResponse {
+	r := new(model.ProfileListResponse)
+	r.Profiles = make([]model.ProfileType, 0)
+	for _, u := range users {
+		r.Profiles = append(r.Profiles, *newProfileType(u, following[u.ID]))
+	}
+	r.ProfilesCount = count
+	return r
+}
+
+func newProfileType(u *entity.User, following bool) *model.ProfileType {
+	p := new(model.ProfileType)
+	p.Username = u.Username
 	if u.Bio.Valid {
-		r.Profile.Bio = &(u.Bio.String)
+		p.Bio = &(u.Bio.String)
 	}
 	if u.Image.Valid {
-		r.Profile.Image = &(u.Image.String)
+		p.Image = &(u.Image.String)
 	}
-	return r
+	p.Following = following
+	p.Role = u.Role
+	return p
 }
 
-func NewUserResponse(u *entity.User) *model.UserResponse {
+// NewUserResponse render u with its access token, and the refresh token of its session when it is not empty
+func NewUserResponse(u *entity.User, token, refreshToken string) *model.UserResponse {
 	r := new(model.UserResponse)
 	r.User.Username = u.Username
 	r.User.Email = u.Email
@@ -29,6 +47,8 @@ func NewUserResponse(u *entity.User) *model.UserResponse {
 	if u.Image.Valid {
 		r.User.Image = &(u.Image.String)
 	}
-	r.User.Token = utils.GenerateJWT(uint(u.ID))
+	r.User.Role = u.Role
+	r.User.Token = token
+	r.User.RefreshToken = refreshToken
 	return r
 }
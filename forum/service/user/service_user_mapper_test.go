@@ -23,7 +23,8 @@ func TestNewProfileResponse(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewProfileResponse(tt.args.u)
+			got := NewProfileResponse(tt.args.u, true)
+			assert.True(t, got.Profile.Following)
 			if got.Profile.Bio != nil {
 				assert.Equal(t, tt.args.u.Bio.String, *got.Profile.Bio)
 				assert.Equal(t, tt.args.u.Image.String, *got.Profile.Image)
@@ -48,8 +49,10 @@ func TestNewUserResponse(t *testing.T) {
 		{"TestNewUserResponseWithNull", args{userWithoutBio}, &model.UserResponse{User: *userFooResponseWithNull}},
 	}
 	for _, tt := range tests {
-		got := NewUserResponse(tt.args.u)
+		got := NewUserResponse(tt.args.u, "access", "refresh")
 		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, "access", got.User.Token)
+			assert.Equal(t, "refresh", got.User.RefreshToken)
 			if got.User.Bio != nil {
 				assert.Equal(t, tt.args.u.Bio.String, *got.User.Bio)
 				assert.Equal(t, tt.args.u.Image.String, *got.User.Image)
@@ -60,3 +63,20 @@ func TestNewUserResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestNewProfileListResponse(t *testing.T) {
+	t.Run("when following flags given", func(t *testing.T) {
+		foo := &entity.User{ID: 1, Username: "foo"}
+		bar := &entity.User{ID: 2, Username: "bar"}
+		got := NewProfileListResponse([]*entity.User{foo, bar}, map[uint64]bool{2: true}, 5)
+		assert.Equal(t, int64(5), got.ProfilesCount)
+		assert.Len(t, got.Profiles, 2)
+		assert.False(t, got.Profiles[0].Following)
+		assert.True(t, got.Profiles[1].Following)
+	})
+	t.Run("when users is empty", func(t *testing.T) {
+		got := NewProfileListResponse(nil, nil, 0)
+		assert.NotNil(t, got.Profiles)
+		assert.Len(t, got.Profiles, 0)
+	})
+}
@@ -1,14 +1,22 @@
 package user
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"schema/entity"
 	"testing"
+	"time"
 
+	"forum/domain"
 	. "forum/mock/repository"
 	"forum/model"
+	"forum/service"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/volatiletech/null/v8"
 )
 
 func TestUser_CheckUser(t *testing.T) {
@@ -18,10 +26,10 @@ func TestUser_CheckUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindByEmail", mock.Anything).Return(nil, fmt.Errorf("findbyemail error"))
+		userMock.On("FindByEmail", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("findbyemail error"))
 		// Then
-		err := mockRequestUser.CheckUser(&model.LoginUser{Email: "foo@foo.com", Password: "foo"})
-		assert.EqualError(t, err, "findbyemail error")
+		_, err := mockRequestUser.CheckUser(context.Background(), &model.LoginUser{Email: "foo@foo.com", Password: "foo"})
+		assert.ErrorContains(t, err, "findbyemail error")
 	})
 	t.Run("when find by email return ok", func(t *testing.T) {
 		// Given
@@ -29,11 +37,37 @@ func TestUser_CheckUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindByEmail", mock.Anything).Return(userFoo, nil)
+		userMock.On("FindByEmail", mock.Anything, mock.Anything).Return(userFoo, nil)
 		// Then
-		err := mockRequestUser.CheckUser(&model.LoginUser{Email: "foo@foo.com", Password: "123456"})
+		_, err := mockRequestUser.CheckUser(context.Background(), &model.LoginUser{Email: "foo@foo.com", Password: "123456"})
 		assert.Errorf(t, err, "crypto/bcrypt: hashedSecret too short to be a bcrypted password")
 	})
+	t.Run("when password matches", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+		hashed := &entity.User{ID: 1, Username: "foo", Email: "foo@foo.com", Password: "$2a$10$B65SchLWy/AqA75Oap8jO.ZJGTtF40/6elzX1mYv0W/0K.yQQw7WW"}
+
+		// When
+		userMock.On("FindByEmail", mock.Anything, "foo@foo.com").Return(hashed, nil)
+		// Then
+		u, err := mockRequestUser.CheckUser(context.Background(), &model.LoginUser{Email: "foo@foo.com", Password: "123456"})
+		assert.NoError(t, err)
+		assert.Equal(t, hashed, u)
+	})
+	t.Run("when password does not match", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+		hashed := &entity.User{ID: 1, Username: "foo", Email: "foo@foo.com", Password: "$2a$10$B65SchLWy/AqA75Oap8jO.ZJGTtF40/6elzX1mYv0W/0K.yQQw7WW"}
+
+		// When
+		userMock.On("FindByEmail", mock.Anything, "foo@foo.com").Return(hashed, nil)
+		// Then
+		u, err := mockRequestUser.CheckUser(context.Background(), &model.LoginUser{Email: "foo@foo.com", Password: "654321"})
+		assert.Error(t, err)
+		assert.Nil(t, u)
+	})
 }
 
 func TestUser_CreateUser(t *testing.T) {
@@ -43,10 +77,35 @@ func TestUser_CreateUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("CreateUser", mock.Anything).Return(fmt.Errorf("create user error"))
+		userMock.On("CreateUser", mock.Anything, mock.Anything).Return(fmt.Errorf("create user error"))
+		// Then
+		_, err := mockRequestUser.CreateUser(context.Background(), &model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: "123456"})
+		assert.ErrorContains(t, err, "create user error")
+	})
+	t.Run("when user already exists", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("CreateUser", mock.Anything, mock.Anything).Return(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'foo@foo.com'"})
+		// Then
+		_, err := mockRequestUser.CreateUser(context.Background(), &model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: "123456"})
+		assert.Equal(t, domain.KindConflict, domain.KindOf(err))
+	})
+	t.Run("when create user return ok", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 		// Then
-		err := mockRequestUser.CreateUser(&model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: "123456"})
-		assert.EqualError(t, err, "create user error")
+		u, err := mockRequestUser.CreateUser(context.Background(), &model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: "123456"})
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", u.Username)
+		assert.NotEqual(t, "123456", u.Password)
+		assert.Equal(t, entity.UsersRoleUser, u.Role)
 	})
 	t.Run("when user password is empty", func(t *testing.T) {
 		// Given
@@ -54,10 +113,11 @@ func TestUser_CreateUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		// userMock.On("CreateUser", mock.Anything).Return(nil)
+		// userMock.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 		// Then
-		err := mockRequestUser.CreateUser(&model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: ""})
+		_, err := mockRequestUser.CreateUser(context.Background(), &model.RegisterUser{Username: "foo", Email: "foo@foo.com", Password: ""})
 		assert.Errorf(t, err, "password should not be empty")
+		assert.Equal(t, domain.KindValidation, domain.KindOf(err))
 	})
 }
 
@@ -68,12 +128,12 @@ func TestUser_FollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userFoo, nil)
-		userMock.On("AddFollower", mock.Anything, mock.Anything).Return(fmt.Errorf("add follower error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("AddFollower", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("add follower error"))
 		// Then
-		err := mockRequestUser.FollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "add follower error")
+		_, err := mockRequestUser.FollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "add follower error")
 	})
 	t.Run("when FindUserByUserName return error", func(t *testing.T) {
 		// Given
@@ -81,10 +141,10 @@ func TestUser_FollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
 		// Then
-		err := mockRequestUser.FollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "find user by username error")
+		_, err := mockRequestUser.FollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "find user by username error")
 	})
 	t.Run("when FindUserByID return error", func(t *testing.T) {
 		// Given
@@ -92,11 +152,36 @@ func TestUser_FollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		// Then
+		_, err := mockRequestUser.FollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "find user by id error")
+	})
+	t.Run("when user follows itself", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
 		// Then
-		err := mockRequestUser.FollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "find user by id error")
+		_, err := mockRequestUser.FollowUserByUserName(context.Background(), 2, "bar")
+		assert.Equal(t, domain.KindValidation, domain.KindOf(err))
+	})
+	t.Run("when follow user return ok", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(userFoo, nil)
+		userMock.On("AddFollower", mock.Anything, userBar, userFoo).Return(nil)
+		// Then
+		u, err := mockRequestUser.FollowUserByUserName(context.Background(), 1, "bar")
+		assert.NoError(t, err)
+		assert.Equal(t, userBar, u)
 	})
 }
 
@@ -107,10 +192,10 @@ func TestUser_GetUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
 		// Then
-		_, err := mockRequestUser.GetUserByID(1)
-		assert.EqualError(t, err, "find user by id error")
+		_, err := mockRequestUser.GetUserByID(context.Background(), 1)
+		assert.ErrorContains(t, err, "find user by id error")
 	})
 	t.Run("when get user by email return ok", func(t *testing.T) {
 		// Given
@@ -118,9 +203,9 @@ func TestUser_GetUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindByEmail", mock.Anything).Return(userFoo, nil)
+		userMock.On("FindByEmail", mock.Anything, mock.Anything).Return(userFoo, nil)
 		// Then
-		_, err := mockRequestUser.GetUserByEmail("foo@foo.com")
+		_, err := mockRequestUser.GetUserByEmail(context.Background(), "foo@foo.com")
 		assert.NoError(t, err)
 	})
 	t.Run("when get user by username return ok", func(t *testing.T) {
@@ -129,10 +214,54 @@ func TestUser_GetUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		// Then
+		_, err := mockRequestUser.GetUserByUserName(context.Background(), "foo")
+		assert.NoError(t, err)
+	})
+}
+
+func TestUser_UpdateUser(t *testing.T) {
+	t.Run("when FindUserByID return error", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(nil, sql.ErrNoRows)
+		// Then
+		_, err := mockRequestUser.UpdateUser(context.Background(), 1, &model.UpdateUser{Bio: "bio"})
+		assert.Equal(t, domain.KindNotFound, domain.KindOf(err))
+	})
+	t.Run("when only the set fields are updated", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+		u := &entity.User{ID: 1, Username: "foo", Email: "foo@foo.com", Password: "hash", Image: null.StringFrom("http://foo.com/foo.jpg")}
+
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(u, nil)
+		userMock.On("UpdateUser", mock.Anything, u).Return(nil)
 		// Then
-		_, err := mockRequestUser.GetUserByUserName("foo")
+		updated, err := mockRequestUser.UpdateUser(context.Background(), 1, &model.UpdateUser{Email: "bar@bar.com", Password: "secret", Bio: "bio"})
 		assert.NoError(t, err)
+		assert.Equal(t, "foo", updated.Username)
+		assert.Equal(t, "bar@bar.com", updated.Email)
+		assert.Equal(t, "bio", updated.Bio.String)
+		assert.Equal(t, "http://foo.com/foo.jpg", updated.Image.String)
+		assert.NoError(t, service.CheckPassword("secret", updated.Password))
+	})
+	t.Run("when the email is taken", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(&entity.User{ID: 1}, nil)
+		userMock.On("UpdateUser", mock.Anything, mock.Anything).Return(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		// Then
+		_, err := mockRequestUser.UpdateUser(context.Background(), 1, &model.UpdateUser{Email: "bar@bar.com"})
+		assert.Equal(t, domain.KindConflict, domain.KindOf(err))
 	})
 }
 
@@ -143,10 +272,10 @@ func TestUser_UnFollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
 		// Then
-		err := mockRequestUser.UnFollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "find user by username error")
+		_, err := mockRequestUser.UnFollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "find user by username error")
 	})
 	t.Run("when FindUserByID return error", func(t *testing.T) {
 		// Given
@@ -154,11 +283,11 @@ func TestUser_UnFollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
 		// Then
-		err := mockRequestUser.UnFollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "find user by id error")
+		_, err := mockRequestUser.UnFollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "find user by id error")
 	})
 	t.Run("when UnFollowUser return error", func(t *testing.T) {
 		// Given
@@ -166,26 +295,40 @@ func TestUser_UnFollowUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByUserName", mock.Anything).Return(userFoo, nil)
-		userMock.On("FindUserByID", mock.Anything).Return(userFoo, nil)
-		userMock.On("RemoveFollower", mock.Anything, mock.Anything).Return(fmt.Errorf("unfollow user error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("RemoveFollower", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("unfollow user error"))
 		// Then
-		err := mockRequestUser.UnFollowUserByUserName(1, "foo")
-		assert.EqualError(t, err, "unfollow user error")
+		_, err := mockRequestUser.UnFollowUserByUserName(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "unfollow user error")
+	})
+	t.Run("when UnFollowUser return ok", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(userFoo, nil)
+		userMock.On("RemoveFollower", mock.Anything, userBar, userFoo).Return(nil)
+		// Then
+		u, err := mockRequestUser.UnFollowUserByUserName(context.Background(), 1, "bar")
+		assert.NoError(t, err)
+		assert.Equal(t, userBar, u)
 	})
 }
 
 func TestUser_GetFollowers(t *testing.T) {
-	t.Run("when FindUserByID return error", func(t *testing.T) {
+	t.Run("when FindUserByUserName return error", func(t *testing.T) {
 		// Given
 		userMock := NewIRepoUser(t)
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
 		// Then
-		_, err := mockRequestUser.GetFollowersByUserID(1)
-		assert.EqualError(t, err, "find user by id error")
+		_, _, err := mockRequestUser.GetFollowersByUserName(context.Background(), "foo", 0, 20)
+		assert.ErrorContains(t, err, "find user by username error")
 	})
 	t.Run("when GetFollowers return error", func(t *testing.T) {
 		// Given
@@ -193,25 +336,39 @@ func TestUser_GetFollowers(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByID", mock.Anything).Return(userFoo, nil)
-		userMock.On("GetFollowers", mock.Anything).Return(nil, fmt.Errorf("get followers error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("GetFollowers", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("get followers error"))
+		// Then
+		_, _, err := mockRequestUser.GetFollowersByUserName(context.Background(), "foo", 0, 20)
+		assert.ErrorContains(t, err, "get followers error")
+	})
+	t.Run("when GetFollowers return ok", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "foo").Return(userFoo, nil)
+		userMock.On("GetFollowers", mock.Anything, userFoo, 0, 20).Return([]*entity.User{userBar}, int64(3), nil)
 		// Then
-		_, err := mockRequestUser.GetFollowersByUserID(1)
-		assert.EqualError(t, err, "get followers error")
+		users, n, err := mockRequestUser.GetFollowersByUserName(context.Background(), "foo", 0, 20)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, int64(3), n)
 	})
 }
 
 func TestUserGetFollowingUser(t *testing.T) {
-	t.Run("when FindUserByID return error", func(t *testing.T) {
+	t.Run("when FindUserByUserName return error", func(t *testing.T) {
 		// Given
 		userMock := NewIRepoUser(t)
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByID", mock.Anything).Return(nil, fmt.Errorf("find user by id error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
 		// Then
-		_, err := mockRequestUser.GetFollowingUser(1)
-		assert.EqualError(t, err, "find user by id error")
+		_, _, err := mockRequestUser.GetFollowingByUserName(context.Background(), "foo", 0, 20)
+		assert.ErrorContains(t, err, "find user by username error")
 	})
 	t.Run("when GetFollowingUsers return error", func(t *testing.T) {
 		// Given
@@ -219,10 +376,341 @@ func TestUserGetFollowingUser(t *testing.T) {
 		mockRequestUser := NewUserService(userMock)
 
 		// When
-		userMock.On("FindUserByID", mock.Anything).Return(userFoo, nil)
-		userMock.On("GetFollowingUsers", mock.Anything).Return(nil, fmt.Errorf("get following users error"))
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(userFoo, nil)
+		userMock.On("GetFollowingUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, int64(0), fmt.Errorf("get following users error"))
 		// Then
-		_, err := mockRequestUser.GetFollowingUser(1)
-		assert.EqualError(t, err, "get following users error")
+		_, _, err := mockRequestUser.GetFollowingByUserName(context.Background(), "foo", 0, 20)
+		assert.ErrorContains(t, err, "get following users error")
+	})
+}
+
+func TestUser_GetProfile(t *testing.T) {
+	t.Run("when FindUserByUserName return error", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("find user by username error"))
+		// Then
+		_, _, err := mockRequestUser.GetProfile(context.Background(), 1, "foo")
+		assert.ErrorContains(t, err, "find user by username error")
+	})
+	t.Run("when viewer is anonymous", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		// Then
+		u, following, err := mockRequestUser.GetProfile(context.Background(), 0, "bar")
+		assert.NoError(t, err)
+		assert.Equal(t, userBar, u)
+		assert.False(t, following)
+	})
+	t.Run("when viewer follows the user", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(userFoo, nil)
+		userMock.On("IsFollower", mock.Anything, userBar, userFoo).Return(true, nil)
+		// Then
+		_, following, err := mockRequestUser.GetProfile(context.Background(), 1, "bar")
+		assert.NoError(t, err)
+		assert.True(t, following)
+	})
+	t.Run("when FindUserByID return error", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(nil, fmt.Errorf("find user by id error"))
+		// Then
+		_, _, err := mockRequestUser.GetProfile(context.Background(), 1, "bar")
+		assert.ErrorContains(t, err, "find user by id error")
+	})
+}
+
+func TestUser_GetFollowingFlags(t *testing.T) {
+	t.Run("when viewer is anonymous", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// Then
+		flags, err := mockRequestUser.GetFollowingFlags(context.Background(), 0, []*entity.User{userBar})
+		assert.NoError(t, err)
+		assert.Empty(t, flags)
+	})
+	t.Run("when viewer is logged in", func(t *testing.T) {
+		// Given
+		userMock := NewIRepoUser(t)
+		mockRequestUser := NewUserService(userMock)
+
+		// When
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(userFoo, nil)
+		userMock.On("FindFollowingIDs", mock.Anything, userFoo, []uint64{userBar.ID}).Return(map[uint64]bool{userBar.ID: true}, nil)
+		// Then
+		flags, err := mockRequestUser.GetFollowingFlags(context.Background(), 1, []*entity.User{userBar})
+		assert.NoError(t, err)
+		assert.True(t, flags[userBar.ID])
+	})
+}
+
+func TestUser_IssueTokens(t *testing.T) {
+	t.Run("when the refresh token is stored", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		var stored *entity.RefreshToken
+		userMock.On("CreateRefreshToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*entity.RefreshToken)
+		}).Return(nil)
+		access, refresh, err := s.IssueTokens(context.Background(), userBar)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, access)
+		assert.Equal(t, userBar.ID, stored.UserID)
+		assert.Equal(t, hashToken(refresh), stored.TokenHash)
+		assert.WithinDuration(t, time.Now().Add(RefreshTokenTTL), stored.ExpiresAt, time.Minute)
+	})
+	t.Run("when the refresh token is not stored", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(fmt.Errorf("some error"))
+		_, _, err := s.IssueTokens(context.Background(), userBar)
+		assert.ErrorContains(t, err, "some error")
+	})
+}
+
+func TestUser_RefreshSession(t *testing.T) {
+	live := func() *entity.RefreshToken {
+		return &entity.RefreshToken{ID: 3, UserID: userBar.ID, TokenHash: hashToken("old"), ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	t.Run("when the refresh token is rotated", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		old := live()
+		userMock.On("FindRefreshToken", mock.Anything, hashToken("old")).Return(old, nil)
+		userMock.On("FindUserByID", mock.Anything, uint(userBar.ID)).Return(userBar, nil)
+		var next *entity.RefreshToken
+		userMock.On("RotateRefreshToken", mock.Anything, old, mock.Anything).Run(func(args mock.Arguments) {
+			next = args.Get(2).(*entity.RefreshToken)
+		}).Return(nil)
+		u, access, refresh, err := s.RefreshSession(context.Background(), "old")
+		assert.NoError(t, err)
+		assert.Equal(t, userBar, u)
+		assert.NotEmpty(t, access)
+		assert.NotEqual(t, "old", refresh)
+		assert.Equal(t, hashToken(refresh), next.TokenHash)
+	})
+	t.Run("when the refresh token is unknown", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+		_, _, _, err := s.RefreshSession(context.Background(), "old")
+		assert.Equal(t, domain.KindUnauthorized, domain.KindOf(err))
+	})
+	t.Run("when the refresh token was already used", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		used := live()
+		used.RevokedAt = null.TimeFrom(time.Now())
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(used, nil)
+		userMock.On("RevokeRefreshTokens", mock.Anything, userBar.ID).Return(nil)
+		_, _, _, err := s.RefreshSession(context.Background(), "old")
+		assert.Equal(t, domain.KindUnauthorized, domain.KindOf(err))
+	})
+	t.Run("when the refresh token expired", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		expired := live()
+		expired.ExpiresAt = time.Now().Add(-time.Hour)
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(expired, nil)
+		_, _, _, err := s.RefreshSession(context.Background(), "old")
+		assert.ErrorContains(t, err, "refresh token expired")
+	})
+	t.Run("when the user is gone", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(live(), nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+		_, _, _, err := s.RefreshSession(context.Background(), "old")
+		assert.Equal(t, domain.KindUnauthorized, domain.KindOf(err))
+	})
+	t.Run("when the refresh token is rotated concurrently", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(live(), nil)
+		userMock.On("FindUserByID", mock.Anything, mock.Anything).Return(userBar, nil)
+		userMock.On("RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything).Return(sql.ErrNoRows)
+		_, _, _, err := s.RefreshSession(context.Background(), "old")
+		assert.Equal(t, domain.KindUnauthorized, domain.KindOf(err))
+	})
+}
+
+func TestUser_Logout(t *testing.T) {
+	exp := time.Now().Add(time.Minute)
+	token := &entity.RefreshToken{ID: 3, UserID: userBar.ID}
+	t.Run("when both tokens are revoked", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindRefreshToken", mock.Anything, hashToken("old")).Return(token, nil)
+		userMock.On("RevokeRefreshToken", mock.Anything, token).Return(nil)
+		userMock.On("RevokeAccessToken", mock.Anything, "abc", exp).Return(nil)
+		err := s.Logout(context.Background(), uint(userBar.ID), "abc", exp, "old")
+		assert.NoError(t, err)
+	})
+	t.Run("when no refresh token is given", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("RevokeAccessToken", mock.Anything, "abc", exp).Return(nil)
+		err := s.Logout(context.Background(), uint(userBar.ID), "abc", exp, "")
+		assert.NoError(t, err)
+	})
+	t.Run("when the refresh token belongs to another user", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("RevokeAccessToken", mock.Anything, "abc", exp).Return(nil)
+		userMock.On("FindRefreshToken", mock.Anything, mock.Anything).Return(token, nil)
+		err := s.Logout(context.Background(), 1, "abc", exp, "old")
+		assert.NoError(t, err)
+	})
+	t.Run("when the refresh token is unknown", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("RevokeAccessToken", mock.Anything, "abc", exp).Return(nil)
+		userMock.On("FindRefreshToken", mock.Anything, hashToken("unknown")).Return(nil, sql.ErrNoRows)
+		err := s.Logout(context.Background(), uint(userBar.ID), "abc", exp, "unknown")
+		assert.NoError(t, err)
+	})
+	t.Run("when the access token is not revoked", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("RevokeAccessToken", mock.Anything, "abc", exp).Return(fmt.Errorf("some error"))
+		err := s.Logout(context.Background(), uint(userBar.ID), "abc", exp, "")
+		assert.ErrorContains(t, err, "some error")
+	})
+}
+
+func TestUser_IsTokenRevoked(t *testing.T) {
+	userMock := NewIRepoUser(t)
+	s := NewUserService(userMock)
+	userMock.On("IsAccessTokenRevoked", mock.Anything, "abc").Return(true, nil)
+	revoked, err := s.IsTokenRevoked(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestUser_SetUserRole(t *testing.T) {
+	admin := &entity.User{ID: 1, Username: "admin", Role: entity.UsersRoleAdmin}
+	t.Run("when the role is changed", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		target := &entity.User{ID: 2, Username: "bar", Role: entity.UsersRoleUser}
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(target, nil)
+		userMock.On("UpdateUser", mock.Anything, target).Return(nil)
+		u, err := s.SetUserRole(context.Background(), 1, "bar", entity.UsersRoleModerator)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.UsersRoleModerator, u.Role)
+	})
+	t.Run("when the caller is not an admin", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(&entity.User{ID: 2, Role: entity.UsersRoleModerator}, nil)
+		_, err := s.SetUserRole(context.Background(), 2, "foo", entity.UsersRoleAdmin)
+		assert.Equal(t, domain.KindForbidden, domain.KindOf(err))
+	})
+	t.Run("when the role is unknown", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		_, err := s.SetUserRole(context.Background(), 1, "bar", "root")
+		assert.Equal(t, domain.KindValidation, domain.KindOf(err))
+	})
+	t.Run("when the admin changes its own role", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindUserByUserName", mock.Anything, "admin").Return(admin, nil)
+		_, err := s.SetUserRole(context.Background(), 1, "admin", entity.UsersRoleUser)
+		assert.Equal(t, domain.KindConflict, domain.KindOf(err))
+	})
+}
+
+func TestUser_DeleteUserByUserName(t *testing.T) {
+	admin := &entity.User{ID: 1, Username: "admin", Role: entity.UsersRoleAdmin}
+	t.Run("when the user is deleted", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("DeleteUser", mock.Anything, userBar).Return(nil)
+		userMock.On("RevokeRefreshTokens", mock.Anything, userBar.ID).Return(nil)
+		err := s.DeleteUserByUserName(context.Background(), 1, "bar")
+		assert.NoError(t, err)
+	})
+	t.Run("when the caller is not an admin", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(userBar, nil)
+		err := s.DeleteUserByUserName(context.Background(), 2, "foo")
+		assert.Equal(t, domain.KindForbidden, domain.KindOf(err))
+	})
+	t.Run("when the admin deletes itself", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindUserByUserName", mock.Anything, "admin").Return(admin, nil)
+		err := s.DeleteUserByUserName(context.Background(), 1, "admin")
+		assert.Equal(t, domain.KindConflict, domain.KindOf(err))
+	})
+}
+
+func TestUser_FindTrashedUsers(t *testing.T) {
+	admin := &entity.User{ID: 1, Username: "admin", Role: entity.UsersRoleAdmin}
+	t.Run("when the trash is listed", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("ListDeletedUsers", mock.Anything, 0, 20).Return([]*entity.User{userBar}, int64(1), nil)
+		users, n, err := s.FindTrashedUsers(context.Background(), 1, 0, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, []*entity.User{userBar}, users)
+		assert.Equal(t, int64(1), n)
+	})
+	t.Run("when the caller is not an admin", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(2)).Return(userBar, nil)
+		_, _, err := s.FindTrashedUsers(context.Background(), 2, 0, 20)
+		assert.Equal(t, domain.KindForbidden, domain.KindOf(err))
+	})
+}
+
+func TestUser_RestoreUserByUserName(t *testing.T) {
+	admin := &entity.User{ID: 1, Username: "admin", Role: entity.UsersRoleAdmin}
+	t.Run("when the user is restored", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindDeletedUserByUserName", mock.Anything, "bar").Return(userBar, nil)
+		userMock.On("RestoreUser", mock.Anything, userBar).Return(nil)
+		u, err := s.RestoreUserByUserName(context.Background(), 1, "bar")
+		assert.NoError(t, err)
+		assert.Equal(t, userBar, u)
+	})
+	t.Run("when the user is not in the trash", func(t *testing.T) {
+		userMock := NewIRepoUser(t)
+		s := NewUserService(userMock)
+		userMock.On("FindUserByID", mock.Anything, uint(1)).Return(admin, nil)
+		userMock.On("FindDeletedUserByUserName", mock.Anything, "bar").Return(nil, sql.ErrNoRows)
+		_, err := s.RestoreUserByUserName(context.Background(), 1, "bar")
+		assert.Equal(t, domain.KindNotFound, domain.KindOf(err))
 	})
 }
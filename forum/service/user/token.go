@@ -0,0 +1,33 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"schema/entity"
+	"time"
+)
+
+// RefreshTokenTTL is the lifetime of a refresh token, every use trades it for a new one
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// newRefreshToken draw a refresh token for the user uid, only the hash of the raw token returned is stored
+func newRefreshToken(uid uint64) (*entity.RefreshToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+	return &entity.RefreshToken{
+		UserID:    uid,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}, raw, nil
+}
+
+// hashToken return the hex sha256 of the raw refresh token, the form it is stored and looked up in
+func hashToken(raw string) string {
+	h := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,5 @@
+drop table if exists article_slug_redirects;
+
+drop index idx_articles_slug on articles;
+alter table articles
+    modify slug longtext not null;
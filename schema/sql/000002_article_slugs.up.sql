@@ -0,0 +1,22 @@
+-- make the slugs fit the new column, then suffix the duplicates with the id of their article
+update articles
+set slug = left(slug, 255)
+where char_length(slug) > 255;
+update articles a
+    join (select slug, min(id) as id from articles group by slug having count(*) > 1) kept
+    on a.slug = kept.slug and a.id <> kept.id
+set a.slug = concat(left(a.slug, 254 - char_length(a.id)), '-', a.id);
+
+alter table articles
+    modify slug varchar(255) not null;
+create unique index idx_articles_slug on articles (slug);
+
+create table if not exists article_slug_redirects
+(
+    old_slug   varchar(255)    not null primary key,
+    article_id bigint unsigned not null,
+    created_at datetime(3)     null,
+    constraint fk_article_slug_redirects_article
+        foreign key (article_id) references articles (id)
+            on delete cascade
+);
@@ -0,0 +1,3 @@
+drop index idx_tags_tag on tags;
+alter table tags
+    modify tag longtext null;
@@ -0,0 +1,24 @@
+-- make the tags fit the new column, then keep one tag per name, a live one if any, moving the articles
+-- of the others onto it
+update tags
+set tag = left(tag, 64)
+where char_length(tag) > 64;
+insert ignore into article_tags (tag_id, article_id)
+select kept.id, links.article_id
+from article_tags links
+    join tags t on t.id = links.tag_id
+    join (select tag, coalesce(min(case when deleted_at is null then id end), min(id)) as id from tags group by tag) kept
+    on kept.tag = t.tag and kept.id <> t.id;
+delete links
+from article_tags links
+    join tags t on t.id = links.tag_id
+    join (select tag, coalesce(min(case when deleted_at is null then id end), min(id)) as id from tags group by tag) kept
+    on kept.tag = t.tag and kept.id <> t.id;
+delete t
+from tags t
+    join (select tag, coalesce(min(case when deleted_at is null then id end), min(id)) as id from tags group by tag) kept
+    on kept.tag = t.tag and kept.id <> t.id;
+
+alter table tags
+    modify tag varchar(64) null;
+create unique index idx_tags_tag on tags (tag);
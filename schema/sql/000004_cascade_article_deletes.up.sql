@@ -0,0 +1,26 @@
+alter table comments
+    drop foreign key fk_articles_comments;
+alter table comments
+    add constraint fk_articles_comments
+        foreign key (article_id) references articles (id)
+            on delete cascade;
+
+alter table favorites
+    drop foreign key fk_favorites_article;
+alter table favorites
+    add constraint fk_favorites_article
+        foreign key (article_id) references articles (id)
+            on delete cascade;
+
+alter table article_tags
+    drop foreign key fk_article_tags_article;
+alter table article_tags
+    add constraint fk_article_tags_article
+        foreign key (article_id) references articles (id)
+            on delete cascade;
+alter table article_tags
+    drop foreign key fk_article_tags_tag;
+alter table article_tags
+    add constraint fk_article_tags_tag
+        foreign key (tag_id) references tags (id)
+            on delete cascade;
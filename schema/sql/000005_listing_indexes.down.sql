@@ -0,0 +1,2 @@
+drop index idx_comments_article_created_at on comments;
+drop index idx_articles_created_at on articles;
@@ -0,0 +1,2 @@
+create index idx_articles_created_at on articles (created_at, id);
+create index idx_comments_article_created_at on comments (article_id, created_at, id);
@@ -0,0 +1 @@
+drop index idx_articles_search on articles;
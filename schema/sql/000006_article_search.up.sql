@@ -0,0 +1 @@
+create fulltext index idx_articles_search on articles (title, description, body);
@@ -0,0 +1,7 @@
+drop index idx_comments_root_created_at on comments;
+alter table comments
+    drop foreign key fk_comments_parent;
+alter table comments
+    drop column depth,
+    drop column root_id,
+    drop column parent_id;
@@ -0,0 +1,9 @@
+alter table comments
+    add column parent_id bigint unsigned null,
+    add column root_id   bigint unsigned null,
+    add column depth     int unsigned    not null;
+alter table comments
+    add constraint fk_comments_parent
+        foreign key (parent_id) references comments (id)
+            on delete cascade;
+create index idx_comments_root_created_at on comments (root_id, created_at, id);
@@ -0,0 +1,13 @@
+alter table comments
+    add column edited_at datetime(3) null;
+
+create table if not exists comment_revisions
+(
+    id         bigint unsigned auto_increment primary key,
+    created_at datetime(3)     null,
+    comment_id bigint unsigned not null,
+    body       longtext        null,
+    constraint fk_comment_revisions_comment
+        foreign key (comment_id) references comments (id)
+            on delete cascade
+);
@@ -0,0 +1,2 @@
+alter table articles
+    drop column favorites_count;
@@ -0,0 +1,4 @@
+alter table articles
+    add column favorites_count int unsigned not null default 0;
+update articles
+set favorites_count = (select count(*) from favorites where favorites.article_id = articles.id);
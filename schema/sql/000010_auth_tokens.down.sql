@@ -0,0 +1,3 @@
+drop table if exists revoked_tokens;
+
+drop table if exists refresh_tokens;
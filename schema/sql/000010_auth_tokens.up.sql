@@ -0,0 +1,22 @@
+create table if not exists refresh_tokens
+(
+    id         bigint unsigned auto_increment primary key,
+    created_at datetime(3)     null,
+    user_id    bigint unsigned not null,
+    token_hash char(64)        not null,
+    expires_at datetime(3)     not null,
+    revoked_at datetime(3)     null,
+    constraint uni_refresh_tokens_token_hash
+        unique (token_hash),
+    constraint fk_refresh_tokens_user
+        foreign key (user_id) references users (id)
+            on delete cascade
+);
+
+create table if not exists revoked_tokens
+(
+    jti        varchar(64) not null primary key,
+    expires_at datetime(3) not null
+);
+
+create index idx_revoked_tokens_expires_at on revoked_tokens (expires_at);
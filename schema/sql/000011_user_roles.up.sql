@@ -0,0 +1,2 @@
+alter table users
+    add column role enum ('user', 'moderator', 'admin') not null;
@@ -0,0 +1,5 @@
+drop index idx_users_email on users;
+drop index idx_users_username on users;
+alter table users
+    modify username longtext not null,
+    modify email longtext not null;
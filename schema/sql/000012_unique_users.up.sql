@@ -0,0 +1,22 @@
+-- make the usernames and emails fit the new columns, then suffix the duplicates with the id of their user so
+-- that the first user keeps the original
+update users
+set username = left(username, 255)
+where char_length(username) > 255;
+update users
+set email = left(email, 255)
+where char_length(email) > 255;
+update users u
+    join (select username, min(id) as id from users group by username having count(*) > 1) kept
+    on u.username = kept.username and u.id <> kept.id
+set u.username = concat(left(u.username, 254 - char_length(u.id)), '-', u.id);
+update users u
+    join (select email, min(id) as id from users group by email having count(*) > 1) kept
+    on u.email = kept.email and u.id <> kept.id
+set u.email = concat(u.id, '+', left(u.email, 254 - char_length(u.id)));
+
+alter table users
+    modify username varchar(255) not null,
+    modify email varchar(255) not null;
+create unique index idx_users_username on users (username);
+create unique index idx_users_email on users (email);
@@ -1,11 +1,16 @@
 package error
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
 )
 
 type JsonError struct {
-	Errors map[string]interface{} `json:"error"`
+	Errors map[string]interface{} `json:"errors"`
 }
 
 func NewError(err error) JsonError {
@@ -13,9 +18,9 @@ func NewError(err error) JsonError {
 	e.Errors = make(map[string]interface{})
 	switch v := err.(type) {
 	case *echo.HTTPError:
-		e.Errors["body"] = v.Message
+		e.Errors["body"] = messages(v.Message)
 	default:
-		e.Errors["body"] = v.Error()
+		e.Errors["body"] = []string{v.Error()}
 	}
 	return e
 }
@@ -23,13 +28,71 @@ func NewError(err error) JsonError {
 func AccessForbidden() JsonError {
 	e := JsonError{}
 	e.Errors = make(map[string]interface{})
-	e.Errors["body"] = "access forbidden"
+	e.Errors["body"] = []string{"access forbidden"}
 	return e
 }
 
 func NotFound() JsonError {
 	e := JsonError{}
 	e.Errors = make(map[string]interface{})
-	e.Errors["body"] = "resource not found"
+	e.Errors["body"] = []string{"resource not found"}
 	return e
 }
+
+// ErrorMapper resolve the http status and the public message of an error that is not an *echo.HTTPError
+type ErrorMapper func(err error) (int, string)
+
+// NewHTTPErrorHandler build an echo error handler rendering every error in the {"errors":{"body":[...]}} shape,
+// server errors are logged and their detail never reaches the client
+func NewHTTPErrorHandler(mapper ErrorMapper) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+		var code int
+		var body []string
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			if je, ok := he.Message.(JsonError); ok {
+				writeError(c, he.Code, je)
+				return
+			}
+			code = he.Code
+			body = messages(he.Message)
+		} else {
+			var msg string
+			code, msg = mapper(err)
+			body = []string{msg}
+		}
+		if code >= http.StatusInternalServerError {
+			log.Error().Err(err).Str("path", c.Path()).Msg("request failed")
+			body = []string{http.StatusText(code)}
+		}
+		writeError(c, code, JsonError{Errors: map[string]interface{}{"body": body}})
+	}
+}
+
+func writeError(c echo.Context, code int, body JsonError) {
+	var err error
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = c.JSON(code, body)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to write error response")
+	}
+}
+
+func messages(m interface{}) []string {
+	switch v := m.(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	case error:
+		return []string{v.Error()}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,42 @@
+package error
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPErrorHandler(t *testing.T) {
+	mapper := func(err error) (int, string) {
+		if err.Error() == "missing" {
+			return http.StatusNotFound, "article not found"
+		}
+		return http.StatusInternalServerError, ""
+	}
+	tests := []struct {
+		name string
+		err  error
+		code int
+		body string
+	}{
+		{"echo http error", echo.NewHTTPError(http.StatusBadRequest, "bad request"), http.StatusBadRequest, `{"errors":{"body":["bad request"]}}`},
+		{"echo http error with field errors", echo.NewHTTPError(http.StatusUnprocessableEntity, JsonError{Errors: map[string]interface{}{"title": []string{"can't be blank"}}}), http.StatusUnprocessableEntity, `{"errors":{"title":["can't be blank"]}}`},
+		{"mapped error", errors.New("missing"), http.StatusNotFound, `{"errors":{"body":["article not found"]}}`},
+		{"server error detail is hidden", errors.New("sql: connection refused"), http.StatusInternalServerError, `{"errors":{"body":["Internal Server Error"]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			NewHTTPErrorHandler(mapper)(tt.err, c)
+			assert.Equal(t, tt.code, rec.Code)
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+}
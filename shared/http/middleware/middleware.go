@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"os"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
@@ -32,3 +35,27 @@ func SetupZeroLog(e *echo.Echo) {
 	}
 	e.Use(logs.ZeroLogWithConfig(logConfig))
 }
+
+// DefaultRequestTimeout bound the requests when REQUEST_TIMEOUT is not set
+const DefaultRequestTimeout = 10 * time.Second
+
+// RequestTimeoutFromEnv read the REQUEST_TIMEOUT duration (e.g. "5s"), DefaultRequestTimeout when it is
+// unset or invalid
+func RequestTimeoutFromEnv() time.Duration {
+	d, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT"))
+	if err != nil || d <= 0 {
+		return DefaultRequestTimeout
+	}
+	return d
+}
+
+// SetupRequestTimeout cancel the context of the requests running longer than timeout, so are their
+// queries. The handler errors are passed through untouched for the HTTPErrorHandler to pick the status.
+func SetupRequestTimeout(e *echo.Echo, timeout time.Duration) {
+	e.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Timeout: timeout,
+		ErrorHandler: func(err error, c echo.Context) error {
+			return err
+		},
+	}))
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeoutFromEnv(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "250ms")
+	assert.Equal(t, 250*time.Millisecond, RequestTimeoutFromEnv())
+	t.Setenv("REQUEST_TIMEOUT", "soon")
+	assert.Equal(t, DefaultRequestTimeout, RequestTimeoutFromEnv())
+	t.Setenv("REQUEST_TIMEOUT", "")
+	assert.Equal(t, DefaultRequestTimeout, RequestTimeoutFromEnv())
+}
+
+func TestSetupRequestTimeout(t *testing.T) {
+	e := echo.New()
+	SetupRequestTimeout(e, 10*time.Millisecond)
+	var got error
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		got = err
+		_ = c.NoContent(http.StatusGatewayTimeout)
+	}
+	e.GET("/", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, got, context.DeadlineExceeded)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Claims are the claims of the access tokens, a token missing the user id or the expiry is rejected
+type Claims struct {
+	UserID    uint     `json:"id"`
+	Role      string   `json:"role,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+// Audience is the aud claim, which is a single string or an array of them
+type Audience []string
+
+// ClaimsValidation is what the claims of a token are checked against
+type ClaimsValidation struct {
+	// Issuer has to be the iss claim when it is not empty
+	Issuer string
+	// Audience has to be in the aud claim when it is not empty
+	Audience string
+	// Leeway is the clock skew tolerated on the exp, nbf and iat claims
+	Leeway time.Duration
+}
+
+var (
+	ErrClaimsUser     = errors.New("token has no user id")
+	ErrClaimsExpiry   = errors.New("token has no expiry")
+	ErrClaimsExpired  = errors.New("token is expired")
+	ErrClaimsNotValid = errors.New("token is not valid yet")
+	ErrClaimsIssuer   = errors.New("token has another issuer")
+	ErrClaimsAudience = errors.New("token is meant for another audience")
+)
+
+// JWTClaimsValidation is the ClaimsValidation of GenerateJWT and of the middlewares configured without one
+var JWTClaimsValidation = ClaimsValidation{Issuer: "forum", Audience: "forum", Leeway: 30 * time.Second}
+
+// Valid check c against JWTClaimsValidation
+func (c *Claims) Valid() error {
+	return c.Validate(JWTClaimsValidation)
+}
+
+// Validate check c against v at the current time
+func (c *Claims) Validate(v ClaimsValidation) error {
+	now := time.Now()
+	switch {
+	case c.UserID == 0:
+		return ErrClaimsUser
+	case c.ExpiresAt == 0:
+		return ErrClaimsExpiry
+	case now.After(time.Unix(c.ExpiresAt, 0).Add(v.Leeway)):
+		return ErrClaimsExpired
+	case c.NotBefore != 0 && now.Add(v.Leeway).Before(time.Unix(c.NotBefore, 0)),
+		c.IssuedAt != 0 && now.Add(v.Leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return ErrClaimsNotValid
+	case v.Issuer != "" && c.Issuer != v.Issuer:
+		return ErrClaimsIssuer
+	case v.Audience != "" && !c.Audience.Contains(v.Audience):
+		return ErrClaimsAudience
+	}
+	return nil
+}
+
+// Contains tell whether aud is one of the audiences of a
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
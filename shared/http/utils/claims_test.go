@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudience_JSON(t *testing.T) {
+	var c Claims
+	require.NoError(t, json.Unmarshal([]byte(`{"aud":"forum"}`), &c))
+	assert.Equal(t, Audience{"forum"}, c.Audience)
+	require.NoError(t, json.Unmarshal([]byte(`{"aud":["forum","search"]}`), &c))
+	assert.Equal(t, Audience{"forum", "search"}, c.Audience)
+	assert.Error(t, json.Unmarshal([]byte(`{"aud":7}`), &c))
+
+	b, err := json.Marshal(Audience{"forum"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `"forum"`, string(b))
+	b, err = json.Marshal(Audience{"forum", "search"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["forum","search"]`, string(b))
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,56 +12,141 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
 )
 
 type (
 	JWTConfig struct {
-		Skipper    Skipper
-		SigningKey interface{}
+		// Skipper makes the authentication optional: the requests it matches go on without user when their
+		// token is missing or rejected, or cannot be checked against the denylist, and with the user of their
+		// token when it is valid
+		Skipper Skipper
+		// Keys check the tokens, JWTKeys is used when it is nil
+		Keys *KeySet
+		// Denylist reject the tokens it reports revoked, JWTDenylist is used when it is nil
+		Denylist Denylist
+		// Validation check the claims of the tokens, JWTClaimsValidation is used when it is nil
+		Validation *ClaimsValidation
 	}
-	Skipper      func(echo.Context) bool
+	Skipper func(echo.Context) bool
+	// Denylist tell whether the token whose jti claim is jti was revoked before it expired
+	Denylist     func(ctx context.Context, jti string) (bool, error)
 	jwtExtractor func(echo.Context) (string, error)
 )
 
 var (
 	ErrJWTMissing = echo.NewHTTPError(http.StatusUnauthorized, "missing or malformed jwt")
-	ErrJWTInvalid = echo.NewHTTPError(http.StatusForbidden, "invalid or expired jwt")
+	ErrJWTInvalid = echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	ErrJWTRevoked = echo.NewHTTPError(http.StatusUnauthorized, "revoked jwt")
 )
 
-func JWT(key interface{}) echo.MiddlewareFunc {
+// JWTDenylist is the Denylist of the middlewares configured without one, no token is checked when it is nil
+var JWTDenylist Denylist
+
+func JWT(keys *KeySet) echo.MiddlewareFunc {
 	c := JWTConfig{}
-	c.SigningKey = key
+	c.Keys = keys
 	return JWTWithConfig(c)
 }
 
+// JWTWithConfig authenticate the requests by their token, which is set as "token" in the context along with its
+// user, role, jti and expiry as "user", "role", "jti" and "exp". The requests whose token is missing, malformed,
+// invalid or revoked are answered with a 401 and those whose token cannot be checked against the denylist fail,
+// unless the Skipper matches them
 func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
 	extractor := jwtFromHeader("Authorization", "Token")
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			auth, err := extractor(c)
+			token, claims, err := config.authenticate(c, extractor)
 			if err != nil {
-				if config.Skipper != nil {
-					if config.Skipper(c) {
-						return next(c)
+				var he *echo.HTTPError
+				isRejected := errors.As(err, &he)
+				if config.Skipper != nil && config.Skipper(c) {
+					if !isRejected {
+						log.Error().Err(err).Msg("failed to check the jwt, going on without user")
 					}
+					return next(c)
 				}
-				return c.JSON(http.StatusUnauthorized, http_error.NewError(err))
-			}
-			token, err := jwt.Parse(auth, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+				if isRejected {
+					return c.JSON(he.Code, http_error.NewError(he))
 				}
-				return config.SigningKey, nil
-			})
-			if err != nil {
-				return c.JSON(http.StatusForbidden, http_error.NewError(ErrJWTInvalid))
+				return err
+			}
+			c.Set("token", token)
+			c.Set("user", claims.UserID)
+			c.Set("role", claims.Role)
+			c.Set("exp", time.Unix(claims.ExpiresAt, 0))
+			if claims.ID != "" {
+				c.Set("jti", claims.ID)
+			}
+			return next(c)
+		}
+	}
+}
+
+// authenticate return the token of the request and its claims, the token is rejected with an *echo.HTTPError
+func (config JWTConfig) authenticate(c echo.Context, extractor jwtExtractor) (string, *Claims, error) {
+	auth, err := extractor(c)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := config.Keys
+	if keys == nil {
+		keys = JWTKeys
+	}
+	validation := config.Validation
+	if validation == nil {
+		validation = &JWTClaimsValidation
+	}
+	claims := new(Claims)
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	if _, err = parser.ParseWithClaims(auth, claims, keys.Keyfunc); err != nil {
+		log.Debug().Err(err).Msg("malformed jwt")
+		return "", nil, ErrJWTMissing
+	}
+	if err = claims.Validate(*validation); err != nil {
+		log.Debug().Err(err).Msg("invalid jwt")
+		return "", nil, ErrJWTInvalid
+	}
+	if claims.ID != "" {
+		revoked, err := config.revoked(c.Request().Context(), claims.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		if revoked {
+			return "", nil, ErrJWTRevoked
+		}
+	}
+	return auth, claims, nil
+}
+
+// revoked ask the Denylist of the config, or JWTDenylist, whether jti was revoked
+func (config JWTConfig) revoked(ctx context.Context, jti string) (bool, error) {
+	denylist := config.Denylist
+	if denylist == nil {
+		denylist = JWTDenylist
+	}
+	if denylist == nil {
+		return false, nil
+	}
+	return denylist(ctx, jti)
+}
+
+// RequireRole only let through the requests whose token carries one of roles, it follows a JWT middleware.
+// The anonymous requests get a 401 and the others a 403
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := c.Get("user").(uint); !ok {
+				return c.JSON(http.StatusUnauthorized, http_error.NewError(ErrJWTMissing))
 			}
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				userID := uint(claims["id"].(float64))
-				c.Set("user", userID)
-				return next(c)
+			role, _ := c.Get("role").(string)
+			for _, r := range roles {
+				if r == role {
+					return next(c)
+				}
 			}
-			return c.JSON(http.StatusForbidden, http_error.NewError(ErrJWTInvalid))
+			return c.JSON(http.StatusForbidden, http_error.AccessForbidden())
 		}
 	}
 }
@@ -75,13 +163,31 @@ func jwtFromHeader(header string, authScheme string) jwtExtractor {
 	}
 }
 
-var JWTSecret = []byte("!!SECRET!!")
+// AccessTokenTTL is the lifetime of the tokens GenerateJWT signs, clients get the next ones with their refresh token
+var AccessTokenTTL = 15 * time.Minute
+
+// GenerateJWT sign with JWTKeys an access token for the user id having role, issued by and meant for the issuer
+// and the audience of JWTClaimsValidation. Each token has its own jti so that it can be revoked alone
+func GenerateJWT(id uint, role string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:    id,
+		Role:      role,
+		ID:        newJTI(),
+		Issuer:    JWTClaimsValidation.Issuer,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(AccessTokenTTL).Unix(),
+	}
+	if JWTClaimsValidation.Audience != "" {
+		claims.Audience = Audience{JWTClaimsValidation.Audience}
+	}
+	return JWTKeys.Sign(claims)
+}
 
-func GenerateJWT(id uint) string {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["id"] = id
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
-	t, _ := token.SignedString(JWTSecret)
-	return t
+// newJTI return 128 random bits in hex
+func newJTI() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
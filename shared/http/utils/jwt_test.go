@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useKeys make keys the JWTKeys of the test
+func useKeys(t *testing.T, keys *KeySet) {
+	saved := JWTKeys
+	JWTKeys = keys
+	t.Cleanup(func() { JWTKeys = saved })
+}
+
+func hmacKeys(t *testing.T) *KeySet {
+	keys, err := NewKeySet(HMACKey("test", []byte("secret")))
+	require.NoError(t, err)
+	return keys
+}
+
+func TestGenerateJWT(t *testing.T) {
+	useKeys(t, hmacKeys(t))
+	parse := func() jwt.MapClaims {
+		s, err := GenerateJWT(7, "moderator")
+		require.NoError(t, err)
+		token, err := jwt.Parse(s, JWTKeys.Keyfunc)
+		require.NoError(t, err)
+		assert.Equal(t, "test", token.Header["kid"])
+		return token.Claims.(jwt.MapClaims)
+	}
+	first, second := parse(), parse()
+	assert.Equal(t, float64(7), first["id"])
+	assert.Equal(t, "moderator", first["role"])
+	assert.Len(t, first["jti"], 32)
+	assert.NotEqual(t, first["jti"], second["jti"])
+	exp := time.Unix(int64(first["exp"].(float64)), 0)
+	assert.WithinDuration(t, time.Now().Add(AccessTokenTTL), exp, 5*time.Second)
+	assert.Equal(t, "forum", first["iss"])
+	assert.Equal(t, "forum", first["aud"])
+	t.Run("when no key is configured", func(t *testing.T) {
+		useKeys(t, nil)
+		_, err := GenerateJWT(7, "moderator")
+		assert.ErrorIs(t, err, ErrJWTKeysMissing)
+	})
+}
+
+func TestJWTWithConfig_Denylist(t *testing.T) {
+	useKeys(t, hmacKeys(t))
+	serve := func(config JWTConfig) (*httptest.ResponseRecorder, echo.Context) {
+		e := echo.New()
+		token, err := GenerateJWT(7, "moderator")
+		require.NoError(t, err)
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set("Authorization", "Token "+token)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		h := JWTWithConfig(config)(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		if err := h(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec, c
+	}
+	failing := func(context.Context, string) (bool, error) { return false, fmt.Errorf("some error") }
+	t.Run("when the token is not revoked", func(t *testing.T) {
+		var asked string
+		rec, c := serve(JWTConfig{Denylist: func(_ context.Context, jti string) (bool, error) {
+			asked = jti
+			return false, nil
+		}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, uint(7), c.Get("user"))
+		assert.Equal(t, "moderator", c.Get("role"))
+		assert.Equal(t, c.Request().Header.Get("Authorization")[len("Token "):], c.Get("token"))
+		assert.Equal(t, asked, c.Get("jti"))
+		assert.IsType(t, time.Time{}, c.Get("exp"))
+	})
+	t.Run("when the token is revoked", func(t *testing.T) {
+		rec, c := serve(JWTConfig{Denylist: func(context.Context, string) (bool, error) { return true, nil }})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Nil(t, c.Get("user"))
+	})
+	t.Run("when the denylist fails", func(t *testing.T) {
+		rec, _ := serve(JWTConfig{Denylist: failing})
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("when the auth is optional and the denylist fails", func(t *testing.T) {
+		rec, c := serve(JWTConfig{Denylist: failing, Skipper: func(echo.Context) bool { return true }})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, c.Get("user"))
+	})
+	t.Run("when only the default denylist is set", func(t *testing.T) {
+		JWTDenylist = func(context.Context, string) (bool, error) { return true, nil }
+		defer func() { JWTDenylist = nil }()
+		rec, _ := serve(JWTConfig{})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestJWTWithConfig_Claims(t *testing.T) {
+	keys := hmacKeys(t)
+	useKeys(t, keys)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"id": 7, "iss": "forum", "aud": "forum", "exp": now.Add(time.Minute).Unix()}
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	sign := func(claims jwt.MapClaims) string {
+		s, err := keys.Sign(claims)
+		require.NoError(t, err)
+		return s
+	}
+	serve := func(auth string, skipper Skipper) (*httptest.ResponseRecorder, echo.Context) {
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Token "+auth)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		h := JWTWithConfig(JWTConfig{Skipper: skipper})(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		require.NoError(t, h(c))
+		return rec, c
+	}
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"valid", sign(valid()), http.StatusOK},
+		{"audience among several", sign(with("aud", []string{"other", "forum"})), http.StatusOK},
+		{"expired within the leeway", sign(with("exp", now.Add(-10*time.Second).Unix())), http.StatusOK},
+		{"expired", sign(with("exp", now.Add(-time.Hour).Unix())), http.StatusUnauthorized},
+		{"without expiry", sign(with("exp", nil)), http.StatusUnauthorized},
+		{"not valid yet", sign(with("nbf", now.Add(time.Hour).Unix())), http.StatusUnauthorized},
+		{"issued in the future", sign(with("iat", now.Add(time.Hour).Unix())), http.StatusUnauthorized},
+		{"another issuer", sign(with("iss", "other")), http.StatusUnauthorized},
+		{"another audience", sign(with("aud", "other")), http.StatusUnauthorized},
+		{"without user", sign(with("id", nil)), http.StatusUnauthorized},
+		{"user as a string", sign(with("id", "7")), http.StatusUnauthorized},
+		{"not a jwt", "garbage", http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, c := serve(tt.token, nil)
+			assert.Equal(t, tt.code, rec.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, uint(7), c.Get("user"))
+			} else {
+				assert.Nil(t, c.Get("user"))
+			}
+		})
+	}
+	optional := func(echo.Context) bool { return true }
+	t.Run("when the auth is optional and the token valid", func(t *testing.T) {
+		rec, c := serve(sign(valid()), optional)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, uint(7), c.Get("user"))
+	})
+	t.Run("when the auth is optional and the token rejected", func(t *testing.T) {
+		rec, c := serve(sign(with("id", "7")), optional)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, c.Get("user"))
+	})
+	t.Run("when the auth is optional and the token missing", func(t *testing.T) {
+		rec, c := serve("", optional)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, c.Get("user"))
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+	serve := func(user interface{}, role string) int {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+		if user != nil {
+			c.Set("user", user)
+			c.Set("role", role)
+		}
+		h := RequireRole("moderator", "admin")(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		require.NoError(t, h(c))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, serve(uint(1), "moderator"))
+	assert.Equal(t, http.StatusOK, serve(uint(1), "admin"))
+	assert.Equal(t, http.StatusForbidden, serve(uint(1), "user"))
+	assert.Equal(t, http.StatusForbidden, serve(uint(1), ""))
+	assert.Equal(t, http.StatusUnauthorized, serve(nil, ""))
+}
@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+// JWTKey is a key the tokens are signed with or checked against, ID is the kid naming it in their header
+type JWTKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Private signs the tokens, it is nil for a key which only checks them
+	Private interface{}
+	// Public checks the tokens, it is the secret itself for HMAC
+	Public interface{}
+}
+
+// KeySet is the key the tokens are signed with and every key they are accepted from. Keeping the previous
+// keys in the set while a new one signs lets the tokens they signed live out their lifetime
+type KeySet struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+}
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the JSON Web Key Set other services verify our tokens with
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var ErrJWTKeysMissing = errors.New("no jwt signing key configured")
+
+// JWTKeys is the KeySet of GenerateJWT and of the middlewares configured without one
+var JWTKeys *KeySet
+
+// NewKeySet build the KeySet signing with signing and accepting the tokens of signing and of verify
+func NewKeySet(signing *JWTKey, verify ...*JWTKey) (*KeySet, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, ErrJWTKeysMissing
+	}
+	s := &KeySet{signing: signing, keys: make(map[string]*JWTKey)}
+	for _, k := range append([]*JWTKey{signing}, verify...) {
+		if _, ok := s.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.ID)
+		}
+		s.keys[k.ID] = k
+	}
+	return s, nil
+}
+
+// HMACKey is the HS256 key of secret
+func HMACKey(id string, secret []byte) *JWTKey {
+	return &JWTKey{ID: id, Method: jwt.SigningMethodHS256, Private: secret, Public: secret}
+}
+
+// ParsePrivateKeyPEM read a PKCS#8, PKCS#1 or SEC 1 private key, its type chooses the algorithm: RS256 for
+// RSA, ES256, ES384 or ES512 for ECDSA after its curve, EdDSA for Ed25519
+func ParsePrivateKeyPEM(id string, data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+	k, err := publicKey(id, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.Private = signer
+	return k, nil
+}
+
+// ParsePublicKeyPEM read a PKIX public key, which only checks the tokens, the algorithm is chosen as in
+// ParsePrivateKeyPEM
+func ParsePublicKeyPEM(id string, data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey(id, key)
+}
+
+func publicKey(id string, key crypto.PublicKey) (*JWTKey, error) {
+	k := &JWTKey{ID: id, Public: key}
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		k.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			k.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			k.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			k.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key %T", key)
+	}
+	return k, nil
+}
+
+// KeySetFromEnv build the KeySet from the environment. The tokens are signed with the PEM private key of the
+// file JWT_KEY_FILE, or when it is unset with the HS256 secret JWT_SECRET. JWT_KEY_ID names the signing key
+// (default "default"). JWT_VERIFY_KEYS lists, comma separated, the kid=file PEM public keys still accepted, and
+// JWT_VERIFY_SECRETS the kid=secret HS256 secrets still accepted
+func KeySetFromEnv() (*KeySet, error) {
+	id := os.Getenv("JWT_KEY_ID")
+	if id == "" {
+		id = "default"
+	}
+	var signing *JWTKey
+	if file := os.Getenv("JWT_KEY_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if signing, err = ParsePrivateKeyPEM(id, data); err != nil {
+			return nil, fmt.Errorf("$JWT_KEY_FILE: %w", err)
+		}
+	} else {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("$JWT_SECRET is not set")
+		}
+		signing = HMACKey(id, []byte(secret))
+	}
+	var verify []*JWTKey
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, file, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("$JWT_VERIFY_KEYS: %q is not kid=file", entry)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParsePublicKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("$JWT_VERIFY_KEYS %s: %w", kid, err)
+		}
+		verify = append(verify, k)
+	}
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFY_SECRETS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("$JWT_VERIFY_SECRETS: an entry is not kid=secret")
+		}
+		verify = append(verify, HMACKey(kid, []byte(secret)))
+	}
+	return NewKeySet(signing, verify...)
+}
+
+// Sign sign claims with the signing key, which the kid header of the token names
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s == nil {
+		return "", ErrJWTKeysMissing
+	}
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.Private)
+}
+
+// Keyfunc pick the key checking token after its kid header, the signing key when it has none. The token has to
+// use the algorithm of that key, so that a public key is never taken for an HMAC secret
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if s == nil {
+		return nil, ErrJWTKeysMissing
+	}
+	k := s.signing
+	if kid, ok := token.Header["kid"].(string); ok {
+		if k, ok = s.keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return k.Public, nil
+}
+
+// JWKS list the public keys of the set, the signing key first, the HMAC secrets are never part of it
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0)}
+	if s == nil {
+		return set
+	}
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		if id != s.signing.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	ids = append([]string{s.signing.ID}, ids...)
+	for _, id := range ids {
+		k := s.keys[id]
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = b64(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// JWKSHandler serve the JWKS of keys, at /.well-known/jwks.json by convention
+func JWKSHandler(keys *KeySet) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(http.StatusOK, keys.JWKS())
+	}
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func privatePEM(t *testing.T, key crypto.Signer) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, key crypto.Signer) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"rsa", rsaKey, "RS256"},
+		{"ecdsa", ecKey, "ES256"},
+		{"ed25519", edKey, "EdDSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParsePrivateKeyPEM("k1", privatePEM(t, tt.key))
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, k.Method.Alg())
+			keys, err := NewKeySet(k)
+			require.NoError(t, err)
+			signed, err := keys.Sign(jwt.MapClaims{"id": 1})
+			require.NoError(t, err)
+			token, err := jwt.Parse(signed, keys.Keyfunc)
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, "k1", token.Header["kid"])
+			public, err := ParsePublicKeyPEM("k1", publicPEM(t, tt.key))
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, public.Method.Alg())
+		})
+	}
+	t.Run("when the data is not PEM", func(t *testing.T) {
+		_, err := ParsePrivateKeyPEM("k1", []byte("secret"))
+		assert.Error(t, err)
+	})
+}
+
+func TestKeySet_Keyfunc(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	oldSigning, err := ParsePrivateKeyPEM("old", privatePEM(t, oldKey))
+	require.NoError(t, err)
+	newSigning, err := ParsePrivateKeyPEM("new", privatePEM(t, newKey))
+	require.NoError(t, err)
+	oldPublic, err := ParsePublicKeyPEM("old", publicPEM(t, oldKey))
+	require.NoError(t, err)
+	before, err := NewKeySet(oldSigning)
+	require.NoError(t, err)
+	after, err := NewKeySet(newSigning, oldPublic)
+	require.NoError(t, err)
+
+	t.Run("when the token was signed by a previous key", func(t *testing.T) {
+		signed, err := before.Sign(jwt.MapClaims{"id": 1})
+		require.NoError(t, err)
+		_, err = jwt.Parse(signed, after.Keyfunc)
+		assert.NoError(t, err)
+	})
+	t.Run("when the key of the token is unknown", func(t *testing.T) {
+		signed, err := after.Sign(jwt.MapClaims{"id": 1})
+		require.NoError(t, err)
+		_, err = jwt.Parse(signed, before.Keyfunc)
+		assert.ErrorContains(t, err, `unknown key id "new"`)
+	})
+	t.Run("when the token uses another algorithm than its key", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": 1})
+		token.Header["kid"] = "old"
+		signed, err := token.SignedString(publicPEM(t, oldKey))
+		require.NoError(t, err)
+		_, err = jwt.Parse(signed, after.Keyfunc)
+		assert.ErrorContains(t, err, "unexpected signing method")
+	})
+	t.Run("when two keys share an id", func(t *testing.T) {
+		_, err := NewKeySet(oldSigning, oldPublic)
+		assert.ErrorContains(t, err, "duplicate jwt key id")
+	})
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signing, err := ParsePrivateKeyPEM("rsa", privatePEM(t, rsaKey))
+	require.NoError(t, err)
+	previous, err := ParsePublicKeyPEM("ed", publicPEM(t, edKey))
+	require.NoError(t, err)
+	keys, err := NewKeySet(signing, previous, HMACKey("hmac", []byte("secret")))
+	require.NoError(t, err)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(echo.GET, "/.well-known/jwks.json", nil), rec)
+	require.NoError(t, JWKSHandler(keys)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var set JWKSet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "rsa", set.Keys[0].Kid)
+	assert.Equal(t, "RSA", set.Keys[0].Kty)
+	assert.Equal(t, "RS256", set.Keys[0].Alg)
+	assert.Equal(t, "AQAB", set.Keys[0].E)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: b64(edKey.Public().(ed25519.PublicKey))}, set.Keys[1])
+	assert.NotContains(t, rec.Body.String(), "hmac")
+}
+
+func TestKeySetFromEnv(t *testing.T) {
+	t.Run("when JWT_SECRET is set", func(t *testing.T) {
+		t.Setenv("JWT_KEY_FILE", "")
+		t.Setenv("JWT_SECRET", "secret")
+		t.Setenv("JWT_KEY_ID", "")
+		keys, err := KeySetFromEnv()
+		require.NoError(t, err)
+		signed, err := keys.Sign(jwt.MapClaims{"id": 1})
+		require.NoError(t, err)
+		token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "default", token.Header["kid"])
+	})
+	t.Run("when no key is set", func(t *testing.T) {
+		t.Setenv("JWT_KEY_FILE", "")
+		t.Setenv("JWT_SECRET", "")
+		_, err := KeySetFromEnv()
+		assert.ErrorContains(t, err, "$JWT_SECRET is not set")
+	})
+	t.Run("when the key files are set", func(t *testing.T) {
+		dir := t.TempDir()
+		current, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		previous, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "current.pem"), privatePEM(t, current), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "previous.pem"), publicPEM(t, previous), 0o600))
+		t.Setenv("JWT_KEY_FILE", filepath.Join(dir, "current.pem"))
+		t.Setenv("JWT_KEY_ID", "2024-02")
+		t.Setenv("JWT_VERIFY_KEYS", "2024-01="+filepath.Join(dir, "previous.pem"))
+		keys, err := KeySetFromEnv()
+		require.NoError(t, err)
+		set := keys.JWKS()
+		require.Len(t, set.Keys, 2)
+		assert.Equal(t, "2024-02", set.Keys[0].Kid)
+		assert.Equal(t, "ES256", set.Keys[0].Alg)
+		assert.Equal(t, "2024-01", set.Keys[1].Kid)
+		assert.Equal(t, "RS256", set.Keys[1].Alg)
+	})
+	t.Run("when a previous secret is set", func(t *testing.T) {
+		t.Setenv("JWT_KEY_FILE", "")
+		t.Setenv("JWT_SECRET", "old")
+		t.Setenv("JWT_KEY_ID", "2024-01")
+		t.Setenv("JWT_VERIFY_KEYS", "")
+		t.Setenv("JWT_VERIFY_SECRETS", "")
+		before, err := KeySetFromEnv()
+		require.NoError(t, err)
+		signed, err := before.Sign(jwt.MapClaims{"id": 1})
+		require.NoError(t, err)
+		t.Setenv("JWT_SECRET", "new")
+		t.Setenv("JWT_KEY_ID", "2024-02")
+		t.Setenv("JWT_VERIFY_SECRETS", "2024-01=old")
+		after, err := KeySetFromEnv()
+		require.NoError(t, err)
+		_, err = jwt.Parse(signed, after.Keyfunc)
+		assert.NoError(t, err)
+		assert.Empty(t, after.JWKS().Keys)
+	})
+	t.Run("when a previous secret is not kid=secret", func(t *testing.T) {
+		t.Setenv("JWT_KEY_FILE", "")
+		t.Setenv("JWT_SECRET", "secret")
+		t.Setenv("JWT_VERIFY_KEYS", "")
+		t.Setenv("JWT_VERIFY_SECRETS", "old")
+		_, err := KeySetFromEnv()
+		assert.ErrorContains(t, err, "is not kid=secret")
+	})
+	t.Run("when a verification key is not kid=file", func(t *testing.T) {
+		t.Setenv("JWT_KEY_FILE", "")
+		t.Setenv("JWT_SECRET", "secret")
+		t.Setenv("JWT_VERIFY_KEYS", "previous.pem")
+		_, err := KeySetFromEnv()
+		assert.ErrorContains(t, err, "is not kid=file")
+	})
+}
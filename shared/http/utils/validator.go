@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	http_error "http/error"
 
@@ -9,8 +12,20 @@ import (
 )
 
 func NewValidator() *Validator {
+	v := validator.New()
+	// report fields under their json name, the one the client sent
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
 	return &Validator{
-		validator: validator.New(),
+		validator: v,
 	}
 }
 
@@ -22,12 +37,45 @@ func (v *Validator) Validate(i interface{}) error {
 	return v.validator.Struct(i)
 }
 
+// NewValidatorError render validation failures as a list of messages per field,
+// any other error is reported under body
 func NewValidatorError(err error) http_error.JsonError {
 	e := http_error.JsonError{}
 	e.Errors = make(map[string]interface{})
-	errs := err.(validator.ValidationErrors)
+	if err == nil {
+		return e
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		e.Errors["body"] = []string{err.Error()}
+		return e
+	}
 	for _, v := range errs {
-		e.Errors[v.Field()] = fmt.Sprintf("%v", v.Tag())
+		msgs, _ := e.Errors[v.Field()].([]string)
+		e.Errors[v.Field()] = append(msgs, validationMessage(v))
 	}
 	return e
 }
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "can't be blank"
+	case "email", "url":
+		return "is invalid"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("is too short (minimum is %s characters)", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("is too long (maximum is %s characters)", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("is not one of %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	default:
+		return fmt.Sprintf("failed on the %s rule", fe.Tag())
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signUp struct {
+	Username string   `json:"username" validate:"required,min=3"`
+	Email    string   `json:"email" validate:"required,email"`
+	Tags     []string `json:"tagList" validate:"omitempty,dive,max=4"`
+	Role     string   `json:"role" validate:"omitempty,oneof=user admin"`
+}
+
+func TestNewValidatorError(t *testing.T) {
+	v := NewValidator()
+	t.Run("when fields are invalid", func(t *testing.T) {
+		err := v.Validate(&signUp{Username: "al", Email: "alice", Tags: []string{"golang"}, Role: "root"})
+		e := NewValidatorError(err)
+		assert.Equal(t, []string{"is too short (minimum is 3 characters)"}, e.Errors["username"])
+		assert.Equal(t, []string{"is invalid"}, e.Errors["email"])
+		assert.Equal(t, []string{"is too long (maximum is 4 characters)"}, e.Errors["tagList[0]"])
+		assert.Equal(t, []string{"is not one of user, admin"}, e.Errors["role"])
+	})
+	t.Run("when field is missing", func(t *testing.T) {
+		e := NewValidatorError(v.Validate(&signUp{Username: "alice"}))
+		assert.Equal(t, []string{"can't be blank"}, e.Errors["email"])
+		assert.Len(t, e.Errors, 1)
+	})
+	t.Run("when error is not a validation error", func(t *testing.T) {
+		e := NewValidatorError(errors.New("validator not registered"))
+		assert.Equal(t, []string{"validator not registered"}, e.Errors["body"])
+	})
+	t.Run("when error is nil", func(t *testing.T) {
+		assert.Empty(t, NewValidatorError(nil).Errors)
+	})
+}